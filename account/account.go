@@ -12,6 +12,9 @@ type Account struct {
 	PrivateKey  []byte
 	PublicKey   *crypto.PubKey
 	ProgramHash Uint160
+
+	// The HD derivation path of the private key, empty for a random key
+	DerivationPath string
 }
 
 func NewAccount() (*Account, error) {
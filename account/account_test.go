@@ -1,26 +1,54 @@
 package account
 
 import (
-	"DNA_POW/crypto"
-	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+
+	"DNA_POW/crypto"
 )
 
 func TestClient(t *testing.T) {
-	t.Log("created client start!")
-	crypto.SetAlg(crypto.P256R1)
-	dir := "./data/"
-	err := os.MkdirAll(dir, 0777)
+	crypto.SetAlg("P256R1")
+	dir, err := ioutil.TempDir("", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := path.Join(dir, "wallet.dat")
+	client, err := Create(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, err := client.GetDefaultAccount()
 	if err != nil {
-		t.Log("create dir ", dir, " error: ", err)
-	} else {
-		t.Log("create dir ", dir, " success!")
-	}
-	for i := 0; i < 10000; i++ {
-		p := path.Join(dir, fmt.Sprintf("wallet%d.txt", i))
-		fmt.Println("client path", p)
-		CreateClient(p, []byte(DefaultPin))
+		t.Fatal(err)
+	}
+	if _, err := Open(name, []byte("wrong")); err == nil {
+		t.Error("opened with a wrong password")
+	}
+	opened, err := Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account, _ := opened.GetDefaultAccount(); account == nil || account.ProgramHash != main.ProgramHash {
+		t.Error("main account is not loaded")
+	}
+
+	// A signal waits for the open wallets only
+	registered := func(c *ClientImpl) bool {
+		openClients.Lock()
+		defer openClients.Unlock()
+		_, ok := openClients.m[c]
+		return ok
+	}
+	if !registered(client) || !registered(opened) {
+		t.Error("open wallet is not registered for the signals")
+	}
+	opened.Close()
+	if registered(opened) || !registered(client) {
+		t.Error("closed wallet is registered for the signals")
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"DNA_POW/core/transaction"
 	"DNA_POW/crypto"
 	. "DNA_POW/errors"
+	"DNA_POW/events"
 	"DNA_POW/events/signalset"
 	"DNA_POW/net/protocol"
 )
@@ -54,6 +56,32 @@ type Client interface {
 
 	GetCoins() map[*transaction.UTXOTxInput]*Coin
 	DeleteCoinsData(programHash Uint160) error
+
+	AddWatchOnly(programHash Uint160, contract *ct.Contract, birthday uint32) error
+	DeleteWatchOnly(programHash Uint160) error
+	GetWatchOnly() []*WatchOnlyAccount
+
+	GetHistory() []*TxRecord
+	SetAddressLabel(programHash Uint160, label string) error
+	SetTransactionLabel(txID Uint256, label string) error
+	GetAddressLabels() map[Uint160]string
+
+	AddContact(name string, programHash Uint160) error
+	DeleteContact(name string) error
+	GetContacts() map[string]Uint160
+	CreatePaymentRequest(assetID Uint256, amount Fixed64, label string) (*PaymentRequest, error)
+	GetPaymentRequests() []*PaymentRequest
+
+	Rescan(startHeight, stopHeight uint32) (uint32, error)
+	AbortRescan() bool
+	GetRescanProgress() *RescanProgress
+
+	ExportPrivateKey(programHash Uint160, password []byte) (string, error)
+	ImportPrivateKey(privateKey []byte, birthday uint32) (*Account, error)
+	DumpWallet(path string, password []byte) error
+	ImportDump(dump *WalletDump, birthday uint32) (int, error)
+
+	Close()
 }
 
 type ClientImpl struct {
@@ -68,11 +96,29 @@ type ClientImpl struct {
 	contracts   map[Uint160]*ct.Contract
 	coins       map[*transaction.UTXOTxInput]*Coin
 
-	watchOnly     []Uint160
+	watchOnly     map[Uint160]*WatchOnlyAccount
 	currentHeight int32
+	birthday      uint32
+
+	history       []*TxRecord
+	addressLabels map[Uint160]string
+	txLabels      map[Uint256]string
+	tipChanged    events.Subscriber
+
+	contacts map[string]Uint160
+	requests []*PaymentRequest
+
+	rescanMu    sync.Mutex
+	rescan      *RescanProgress
+	rescanAbort bool
+
+	hdChain  *crypto.ExtendedKey
+	hdNext   uint32
+	gapLimit uint32
 
 	FileStore
-	isRunning bool
+	isRunning int32 // 1 while the wallet syncs, accessed atomically
+	running   sync.WaitGroup
 }
 
 func Create(path string, passwordKey []byte) (*ClientImpl, error) {
@@ -97,32 +143,55 @@ func Open(path string, passwordKey []byte) (*ClientImpl, error) {
 	if client == nil {
 		return nil, errors.New("client nil")
 	}
+	if err := client.loadHDSeed(); err != nil {
+		return nil, errors.New("Load HD seed failure")
+	}
 	if err := client.LoadAccounts(); err != nil {
 		return nil, errors.New("Load accounts failure")
 	}
 	if err := client.LoadContracts(); err != nil {
 		return nil, errors.New("Load contracts failure")
 	}
+	if err := client.LoadWatchOnly(); err != nil {
+		return nil, errors.New("Load watch-only accounts failure")
+	}
 	if err := client.LoadCoins(); err != nil {
 		return nil, errors.New("Load coins failure")
 	}
+	if err := client.LoadHistory(); err != nil {
+		return nil, errors.New("Load history failure")
+	}
+	if err := client.LoadLabels(); err != nil {
+		return nil, errors.New("Load labels failure")
+	}
+	if err := client.LoadAddressBook(); err != nil {
+		return nil, errors.New("Load address book failure")
+	}
+	if err := client.LoadPaymentRequests(); err != nil {
+		return nil, errors.New("Load payment requests failure")
+	}
 
 	return client, nil
 }
 
-func Recover(path string, password []byte, privateKeyHex string) (*ClientImpl, error) {
+// Recover creates a wallet of the private key, encoded or in hex, which syncs
+// from the birthday, the height the key received its first coin at or below.
+func Recover(path string, password []byte, privateKey string, birthday uint32) (*ClientImpl, error) {
+	privateKeyBytes, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
 	client := NewClient(path, password, true)
 	if client == nil {
 		return nil, errors.New("client nil")
 	}
 
-	privateKeyBytes, err := HexStringToBytes(privateKeyHex)
-	if err != nil {
+	if err := client.resetBirthday(birthday); err != nil {
 		return nil, err
 	}
 
 	// recover Account
-	account, err := client.CreateAccountByPrivateKey(privateKeyBytes)
+	account, err := client.CreateAccountByPrivateKey(privateKeyBytes, birthday)
 	if err != nil {
 		return nil, err
 	}
@@ -136,9 +205,10 @@ func Recover(path string, password []byte, privateKeyHex string) (*ClientImpl, e
 }
 
 func (client *ClientImpl) ProcessBlocks() {
+	defer client.running.Done()
 	time.Sleep(time.Second)
-	for client.isRunning {
-		for true {
+	for client.syncing() {
+		for client.syncing() {
 			blockHeight := ledger.DefaultLedger.GetLocalBlockChainHeight()
 			if client.currentHeight >= int32(blockHeight) {
 				break
@@ -148,7 +218,7 @@ func (client *ClientImpl) ProcessBlocks() {
 				fmt.Fprintf(os.Stderr, "fatal error: syncing failed, block missing, height %d\n", client.currentHeight)
 				break
 			}
-			client.ProcessOneBlock(block)
+			client.syncBlock(block)
 		}
 		time.Sleep(1 * time.Second)
 	}
@@ -158,27 +228,66 @@ func (client *ClientImpl) ProcessOneBlock(block *ledger.Block) {
 	client.mu.Lock()
 	defer client.mu.Unlock()
 
+	client.processOneBlock(block)
+}
+
+// syncBlock processes the block unless the wallet height has been lowered by
+// an import of keys since the block is read.
+func (client *ClientImpl) syncBlock(block *ledger.Block) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if int32(block.Blockdata.Height) != client.currentHeight+1 {
+		return
+	}
+	// the block is detached from the best chain since it is read
+	if hash, err := ledger.DefaultLedger.Store.GetBlockHash(block.Blockdata.Height); err != nil || hash != block.Hash() {
+		return
+	}
+	client.processOneBlock(block)
+}
+
+func (client *ClientImpl) processOneBlock(block *ledger.Block) {
+	client.discoverHDAccounts(block)
+
+	// update wallet store
+	if client.processBlockCoins(block, false) {
+		if err := client.SaveCoins(); err != nil {
+			fmt.Fprintf(os.Stderr, "saving coins error: %v\n", err)
+		}
+	}
+
+	// update height
+	client.currentHeight++
+	client.saveHeight()
+}
+
+// processBlockCoins adds the coins the block pays to the wallet and removes
+// those it spends, records the transactions of the wallet and the payment
+// requests they pay, and reports whether the coins changed. A block rescanned
+// below the wallet height adds only the coins unspent on the blockchain. The
+// caller holds the client lock.
+func (client *ClientImpl) processBlockCoins(block *ledger.Block, rescan bool) bool {
 	var needUpdate bool
 	// received coins
 	for _, tx := range block.Transactions {
 		for index, output := range tx.Outputs {
-			if contract, ok := client.contracts[output.ProgramHash]; ok {
+			if addressType, ok := client.addressType(output.ProgramHash); ok {
 				input := &transaction.UTXOTxInput{ReferTxID: tx.Hash(), ReferTxOutputIndex: uint16(index)}
-				if _, ok := client.coins[input]; !ok {
+				if !client.containsCoin(input) {
+					if rescan {
+						if unspent, _ := ledger.DefaultLedger.Store.ContainsUnspent(input.ReferTxID, input.ReferTxOutputIndex); !unspent {
+							continue
+						}
+					}
 					// If it's not Coinbase transaction, the new created utxos could be spent in next block height.
 					// Otherwise, could be spent when block height reaches to current height + SpendCoinbaseSpan
 					h := uint32(0)
 					if tx.IsCoinBaseTx() {
 						h = block.Blockdata.Height + config.Parameters.ChainParam.SpendCoinbaseSpan
 					}
-					var newCoin Coin
-					switch {
-					case contract.IsStandard():
-						newCoin = Coin{Output: output, AddressType: SingleSign, Height: h}
-					case contract.IsMultiSigContract():
-						newCoin = Coin{Output: output, AddressType: MultiSign, Height: h}
-					}
-					client.coins[input] = &newCoin
+					client.coins[input] = &Coin{Output: output, AddressType: addressType, Height: h,
+						ReceivedHeight: block.Blockdata.Height}
 					needUpdate = true
 				}
 			}
@@ -186,56 +295,110 @@ func (client *ClientImpl) ProcessOneBlock(block *ledger.Block) {
 	}
 
 	// spent coins
+	var historyChanged bool
 	for _, tx := range block.Transactions {
+		spent := map[*transaction.UTXOTxInput]*Coin{}
 		for _, input := range tx.UTXOInputs {
-			for k := range client.coins {
-				if k.ReferTxOutputIndex == input.ReferTxOutputIndex && k.ReferTxID == input.ReferTxID {
-					delete(client.coins, k)
-					needUpdate = true
-				}
+			k, coin := client.spentCoin(input, rescan)
+			if coin == nil {
+				continue
 			}
+			spent[&transaction.UTXOTxInput{ReferTxID: input.ReferTxID, ReferTxOutputIndex: input.ReferTxOutputIndex}] = coin
+			if k != nil {
+				delete(client.coins, k)
+				needUpdate = true
+			}
+		}
+		if client.recordTransaction(block, tx, spent) {
+			historyChanged = true
 		}
 	}
+	if historyChanged {
+		if err := client.SaveHistory(); err != nil {
+			fmt.Fprintf(os.Stderr, "saving history error: %v\n", err)
+		}
+	}
+	client.processBlockRequests(block)
+	return needUpdate
+}
 
-	// update wallet store
-	if needUpdate {
-		if err := client.SaveCoins(); err != nil {
-			fmt.Fprintf(os.Stderr, "saving coins error: %v\n", err)
+// containsCoin reports whether the wallet has the coin of the outpoint, the
+// coins are keyed by pointer. The caller holds the client lock.
+func (client *ClientImpl) containsCoin(input *transaction.UTXOTxInput) bool {
+	for k := range client.coins {
+		if k.Equals(input) {
+			return true
 		}
 	}
+	return false
+}
 
-	// update height
-	client.currentHeight++
-	bytesBuffer := bytes.NewBuffer([]byte{})
-	binary.Write(bytesBuffer, binary.LittleEndian, &client.currentHeight)
-	client.SaveStoredData("Height", bytesBuffer.Bytes())
+// openClients are the wallets the node has open. The node handles the
+// interrupt and termination signals once for all of them.
+var openClients = struct {
+	sync.Mutex
+	m map[*ClientImpl]struct{}
+}{m: make(map[*ClientImpl]struct{})}
+
+var handleSignals sync.Once
+
+func registerClient(client *ClientImpl) {
+	handleSignals.Do(func() { go processSignals() })
+	openClients.Lock()
+	openClients.m[client] = struct{}{}
+	openClients.Unlock()
+}
+
+func unregisterClient(client *ClientImpl) {
+	openClients.Lock()
+	delete(openClients.m, client)
+	openClients.Unlock()
 }
 
-func (client *ClientImpl) ProcessSignals() {
-	clientSignalHandler := func(signal os.Signal, v interface{}) {
+// processSignals exits the node on an interrupt or termination signal. A
+// wallet writes every change to its file when it is made, so the node waits
+// for the changes being written and holds the locks of all the open wallets
+// until it exits, leaving no wallet file half written.
+func processSignals() {
+	signalHandler := func(signal os.Signal, v interface{}) {
 		switch signal {
 		case syscall.SIGINT:
 			log.Trace("Caught interrupt signal, program exits.")
 		case syscall.SIGTERM:
 			log.Trace("Caught termination signal, program exits.")
 		}
-		// hold the mutex lock to prevent any wallet db changes
-		client.FileStore.Lock()
+		openClients.Lock()
+		for client := range openClients.m {
+			client.mu.Lock()
+			client.FileStore.Lock()
+		}
 		os.Exit(0)
 	}
 	signalSet := signalset.New()
-	signalSet.Register(syscall.SIGINT, clientSignalHandler)
-	signalSet.Register(syscall.SIGTERM, clientSignalHandler)
+	signalSet.Register(syscall.SIGINT, signalHandler)
+	signalSet.Register(syscall.SIGTERM, signalHandler)
 	sigChan := make(chan os.Signal, MaxSignalQueueLen)
-	signal.Notify(sigChan)
-	for {
-		select {
-		case sig := <-sigChan:
-			signalSet.Handle(sig, nil)
-		default:
-			time.Sleep(time.Second)
-		}
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	for sig := range sigChan {
+		signalSet.Handle(sig, nil)
+	}
+}
+
+// syncing reports whether the wallet syncs with the blockchain
+func (client *ClientImpl) syncing() bool {
+	return atomic.LoadInt32(&client.isRunning) == 1
+}
+
+// Close stops syncing the wallet with the blockchain and waits until the block
+// being processed is saved, a signal does not wait for the wallet from then on.
+func (client *ClientImpl) Close() {
+	atomic.StoreInt32(&client.isRunning, 0)
+	client.running.Wait()
+	if client.tipChanged != nil {
+		ledger.DefaultLedger.Blockchain.BCEvents.UnSubscribe(events.EventChainTipChanged, client.tipChanged)
+		client.tipChanged = nil
 	}
+	unregisterClient(client)
 }
 
 func NewClient(path string, password []byte, create bool) *ClientImpl {
@@ -244,19 +407,28 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 		accounts:      map[Uint160]*Account{},
 		contracts:     map[Uint160]*ct.Contract{},
 		coins:         map[*transaction.UTXOTxInput]*Coin{},
+		watchOnly:     map[Uint160]*WatchOnlyAccount{},
+		addressLabels: map[Uint160]string{},
+		txLabels:      map[Uint256]string{},
+		contacts:      map[string]Uint160{},
 		currentHeight: -1,
 		FileStore:     FileStore{path: path},
-		isRunning:     true,
+		isRunning:     1,
 	}
 
-	go client.ProcessSignals()
+	// a signal waits for the wallet being created or opened
+	registerClient(client)
+	opened := false
+	defer func() {
+		if !opened {
+			unregisterClient(client)
+		}
+	}()
 
-	passwordKey := crypto.ToAesKey(password)
 	if create {
 		//create new client
 		client.iv = make([]byte, 16)
 		client.masterKey = make([]byte, 32)
-		client.watchOnly = []Uint160{}
 
 		//generate random number for iv/masterkey
 		r := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -270,27 +442,12 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 		//new client store (build DB)
 		client.BuildDatabase(path)
 
-		if err := client.SaveStoredData("Version", []byte(WalletStoreVersion)); err != nil {
-			log.Error(err)
-			return nil
-		}
-
-		pwdhash := sha256.Sum256(passwordKey)
-		if err := client.SaveStoredData("PasswordHash", pwdhash[:]); err != nil {
-			log.Error(err)
-			return nil
-		}
 		if err := client.SaveStoredData("IV", client.iv[:]); err != nil {
 			log.Error(err)
 			return nil
 		}
-
-		aesmk, err := crypto.AesEncrypt(client.masterKey[:], passwordKey, client.iv)
-		if err != nil {
-			log.Error(err)
-			return nil
-		}
-		if err := client.SaveStoredData("MasterKey", aesmk); err != nil {
+		// save the version, the password hash and the encrypted master key
+		if err := client.setPassword(password); err != nil {
 			log.Error(err)
 			return nil
 		}
@@ -299,30 +456,18 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 		if ledger.DefaultLedger != nil && ledger.DefaultLedger.Blockchain != nil {
 			client.currentHeight = int32(ledger.DefaultLedger.GetLocalBlockChainHeight())
 		}
-		bytesBuffer := bytes.NewBuffer([]byte{})
-		binary.Write(bytesBuffer, binary.LittleEndian, &client.currentHeight)
-		if err := client.SaveStoredData("Height", bytesBuffer.Bytes()); err != nil {
+		if err := client.saveHeight(); err != nil {
 			return nil
 		}
-
-	} else {
-		if ok := client.verifyPasswordKey(passwordKey); !ok {
-			return nil
-		}
-		var err error
-		client.iv, err = client.LoadStoredData("IV")
-		if err != nil {
-			fmt.Println("error: failed to load iv")
+		// the keys of a new wallet have no coins in the blocks synced
+		client.birthday = uint32(client.currentHeight + 1)
+		if err := client.saveBirthday(); err != nil {
 			return nil
 		}
-		encryptedMasterKey, err := client.LoadStoredData("MasterKey")
-		if err != nil {
-			fmt.Println("error: failed to load master key")
-			return nil
-		}
-		client.masterKey, err = crypto.AesDecrypt(encryptedMasterKey, passwordKey, client.iv)
-		if err != nil {
-			fmt.Println("error: failed to decrypt master key")
+
+	} else {
+		if err := client.unlock(password); err != nil {
+			fmt.Println("error:", err)
 			return nil
 		}
 		tmp, err := client.LoadStoredData("Height")
@@ -333,14 +478,21 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 		var height int32
 		binary.Read(bytesBuffer, binary.LittleEndian, &height)
 		client.currentHeight = height
+		tmp, err = client.LoadStoredData("Birthday")
+		if err != nil {
+			return nil
+		}
+		binary.Read(bytes.NewBuffer(tmp), binary.LittleEndian, &client.birthday)
 	}
-	ClearBytes(passwordKey, len(passwordKey))
 
 	// if has local blockchain database and running flag is set, then sync wallet data
-	if ledger.DefaultLedger != nil && ledger.DefaultLedger.Blockchain != nil && client.isRunning {
+	if ledger.DefaultLedger != nil && ledger.DefaultLedger.Blockchain != nil && client.syncing() {
+		client.tipChanged = ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventChainTipChanged, client.onChainTipChanged)
+		client.running.Add(1)
 		go client.ProcessBlocks()
 	}
 
+	opened = true
 	return client
 }
 
@@ -380,32 +532,19 @@ func (cl *ClientImpl) GetContract(programHash Uint160) *ct.Contract {
 
 func (cl *ClientImpl) ChangePassword(oldPassword []byte, newPassword []byte) bool {
 	// check password
-	oldPasswordKey := crypto.ToAesKey(oldPassword)
-	if !cl.verifyPasswordKey(oldPasswordKey) {
+	oldPasswordKey, err := cl.checkPassword(oldPassword)
+	if err != nil {
 		fmt.Println("error: password verification failed")
 		return false
 	}
+	ClearBytes(oldPasswordKey, len(oldPasswordKey))
 
-	// encrypt master key with new password
-	newPasswordKey := crypto.ToAesKey(newPassword)
-	newMasterKey, err := crypto.AesEncrypt(cl.masterKey, newPasswordKey, cl.iv)
-	if err != nil {
+	// encrypt master key with new password and update wallet file
+	if err := cl.setPassword(newPassword); err != nil {
 		fmt.Println("error: set new password failed")
 		return false
 	}
 
-	// update wallet file
-	newPasswordHash := sha256.Sum256(newPasswordKey)
-	if err := cl.SaveStoredData("PasswordHash", newPasswordHash[:]); err != nil {
-		fmt.Println("error: wallet update failed(password hash)")
-		return false
-	}
-	if err := cl.SaveStoredData("MasterKey", newMasterKey); err != nil {
-		fmt.Println("error: wallet update failed (encrypted master key)")
-		return false
-	}
-	ClearBytes(newPasswordKey, len(newPasswordKey))
-
 	return true
 }
 
@@ -513,8 +652,12 @@ func (cl *ClientImpl) DecryptPrivateKey(prikey []byte) ([]byte, error) {
 	return dec, nil
 }
 
-// CreateAccount create a new Account then save it
+// CreateAccount create a new Account then save it, the next HD account when
+// the wallet has an HD seed
 func (cl *ClientImpl) CreateAccount() (*Account, error) {
+	if cl.hdChain != nil {
+		return cl.createHDAccount()
+	}
 	account, err := NewAccount()
 	if err != nil {
 		return nil, err
@@ -533,7 +676,10 @@ func (cl *ClientImpl) DeleteAccount(programHash Uint160) error {
 	return cl.DeleteAccountData(BytesToHexString(programHash.ToArray()))
 }
 
-func (cl *ClientImpl) CreateAccountByPrivateKey(privateKey []byte) (*Account, error) {
+// CreateAccountByPrivateKey imports the private key, whose coins are received
+// since the birthday. The wallet syncs again from the birthday if it is below
+// the wallet height.
+func (cl *ClientImpl) CreateAccountByPrivateKey(privateKey []byte, birthday uint32) (*Account, error) {
 	account, err := NewAccountWithPrivatekey(privateKey)
 	if err != nil {
 		return nil, err
@@ -542,6 +688,9 @@ func (cl *ClientImpl) CreateAccountByPrivateKey(privateKey []byte) (*Account, er
 	if err := cl.SaveAccount(account); err != nil {
 		return nil, err
 	}
+	if err := cl.lowerBirthday(birthday); err != nil {
+		return nil, err
+	}
 
 	return account, nil
 }
@@ -551,6 +700,10 @@ func (cl *ClientImpl) SaveAccount(ac *Account) error {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
 
+	return cl.saveAccount(ac)
+}
+
+func (cl *ClientImpl) saveAccount(ac *Account) error {
 	// save Account to memory
 	programHash := ac.ProgramHash
 	cl.accounts[programHash] = ac
@@ -573,7 +726,7 @@ func (cl *ClientImpl) SaveAccount(ac *Account) error {
 	ClearBytes(decryptedPrivateKey, 96)
 
 	// save Account keys to db
-	err = cl.SaveAccountData(programHash.ToArray(), encryptedPrivateKey)
+	err = cl.SaveAccountData(programHash.ToArray(), encryptedPrivateKey, ac.DerivationPath)
 	if err != nil {
 		return err
 	}
@@ -602,6 +755,12 @@ func (cl *ClientImpl) LoadAccounts() error {
 		}
 		privateKey := keyPair[64:96]
 		ac, err := NewAccountWithPrivatekey(privateKey)
+		if a.DerivationPath != "" {
+			ac.DerivationPath = a.DerivationPath
+			if index, err := hdIndex(a.DerivationPath); err == nil && index >= cl.hdNext {
+				cl.hdNext = index + 1
+			}
+		}
 		accounts[ac.ProgramHash] = ac
 	}
 
@@ -643,6 +802,10 @@ func (cl *ClientImpl) SaveContract(ct *contract.Contract) error {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
 
+	return cl.saveContract(ct)
+}
+
+func (cl *ClientImpl) saveContract(ct *contract.Contract) error {
 	// save contract to memory
 	cl.contracts[ct.ProgramHash] = ct
 
@@ -692,6 +855,12 @@ func (client *ClientImpl) LoadCoins() error {
 		return err
 	}
 	for input, coin := range loadedCoin {
+		// the coins saved before the received heights are looked up once
+		if coin.ReceivedHeight == 0 && ledger.DefaultLedger != nil && ledger.DefaultLedger.Store != nil {
+			if _, height, err := ledger.DefaultLedger.Store.GetTransaction(input.ReferTxID); err == nil {
+				coin.ReceivedHeight = height
+			}
+		}
 		client.coins[input] = coin
 	}
 
@@ -761,12 +930,9 @@ func (client *ClientImpl) GetAccounts() []*Account {
 }
 
 func (client *ClientImpl) Rebuild() error {
-	// reset wallet block height
-	client.currentHeight = -1
-	var height int32 = -1
-	bytesBuffer := bytes.NewBuffer([]byte{})
-	binary.Write(bytesBuffer, binary.LittleEndian, &height)
-	if err := client.SaveStoredData("Height", bytesBuffer.Bytes()); err != nil {
+	// reset wallet block height, the keys have no coins below the birthday
+	client.currentHeight = int32(client.birthday) - 1
+	if err := client.saveHeight(); err != nil {
 		return err
 	}
 
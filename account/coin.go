@@ -1,46 +1,102 @@
 package account
 
 import (
+	"bytes"
 	"io"
 
-	"DNA_POW/core/transaction"
+	. "DNA_POW/common"
 	"DNA_POW/common/serialization"
+	"DNA_POW/core/transaction"
 )
+
 type AddressType byte
 
 const (
 	SingleSign AddressType = 0
 	MultiSign  AddressType = 1
+	WatchOnly  AddressType = 2
 )
 
+// CoinDataVersion is the version of the coins saved with the height each is
+// received at. The coins saved before have no version.
+const CoinDataVersion = "1"
+
 type Coin struct {
 	Output      *transaction.TxOutput
 	AddressType AddressType
-	Height		uint32
+	// the height a coinbase coin can be spent from, 0 for the others
+	Height uint32
+	// the height of the block the coin is received in
+	ReceivedHeight uint32
 }
 
 func (coin *Coin) Serialize(w io.Writer, version string) error {
 	coin.Output.Serialize(w)
 	w.Write([]byte{byte(coin.AddressType)})
 	serialization.WriteUint32(w, coin.Height)
+	if version != "" {
+		serialization.WriteUint32(w, coin.ReceivedHeight)
+	}
 
 	return nil
 }
 
 func (coin *Coin) Deserialize(r io.Reader, version string) error {
 	coin.Output = new(transaction.TxOutput)
-	coin.Output.Deserialize(r)
+	if err := coin.Output.Deserialize(r); err != nil {
+		return err
+	}
 	addrType, err := serialization.ReadUint8(r)
 	if err != nil {
 		return err
 	}
 	coin.AddressType = AddressType(addrType)
 
-	height,err := serialization.ReadUint32(r)
+	height, err := serialization.ReadUint32(r)
 	if err != nil {
 		return err
 	}
 	coin.Height = height
 
+	if version != "" {
+		if coin.ReceivedHeight, err = serialization.ReadUint32(r); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// encodeCoins serializes the coins with their outpoints in hex
+func encodeCoins(coins map[*transaction.UTXOTxInput]*Coin) CoinData {
+	if len(coins) == 0 {
+		return ""
+	}
+	w := new(bytes.Buffer)
+	serialization.WriteUint32(w, uint32(len(coins)))
+	for k, v := range coins {
+		k.Serialize(w)
+		v.Serialize(w, CoinDataVersion)
+	}
+	return CoinData(BytesToHexString(w.Bytes()))
+}
+
+// decodeCoins deserializes the coins encoded in the version
+func decodeCoins(data CoinData, version string) (map[*transaction.UTXOTxInput]*Coin, error) {
+	coins := make(map[*transaction.UTXOTxInput]*Coin)
+	rawCoins, _ := HexStringToBytes(string(data))
+	r := bytes.NewReader(rawCoins)
+	num, _ := serialization.ReadUint32(r)
+	for i := 0; i < int(num); i++ {
+		input := new(transaction.UTXOTxInput)
+		if err := input.Deserialize(r); err != nil {
+			return nil, err
+		}
+		coin := new(Coin)
+		if err := coin.Deserialize(r, version); err != nil {
+			return nil, err
+		}
+		coins[input] = coin
+	}
+	return coins, nil
+}
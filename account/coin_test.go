@@ -0,0 +1,40 @@
+package account
+
+import (
+	"bytes"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/core/transaction"
+)
+
+func TestCoinData(t *testing.T) {
+	coin := &Coin{
+		Output:         &transaction.TxOutput{Value: 10, ProgramHash: Uint160{1, 2, 3}},
+		AddressType:    MultiSign,
+		Height:         120,
+		ReceivedHeight: 20,
+	}
+	for _, version := range []string{"", CoinDataVersion} {
+		var buffer bytes.Buffer
+		if err := coin.Serialize(&buffer, version); err != nil {
+			t.Fatal(err)
+		}
+		loaded := new(Coin)
+		if err := loaded.Deserialize(&buffer, version); err != nil {
+			t.Fatalf("version %q: %v", version, err)
+		}
+		if buffer.Len() != 0 {
+			t.Errorf("version %q: %d bytes left", version, buffer.Len())
+		}
+		received := coin.ReceivedHeight
+		// the coins saved without a version have no received height
+		if version == "" {
+			received = 0
+		}
+		if *loaded.Output != *coin.Output || loaded.AddressType != coin.AddressType ||
+			loaded.Height != coin.Height || loaded.ReceivedHeight != received {
+			t.Errorf("version %q: coin loaded as %+v", version, loaded)
+		}
+	}
+}
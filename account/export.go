@@ -0,0 +1,445 @@
+package account
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	ct "DNA_POW/core/contract"
+	"DNA_POW/crypto"
+
+	"github.com/itchyny/base58-go"
+)
+
+const (
+	// the byte following the key in the encoding, as the public keys of the
+	// wallet accounts are compressed
+	privateKeyCompressed = 0x01
+
+	privateKeyLen        = 32
+	encodedPrivateKeyLen = 1 + privateKeyLen + 1 + 4
+
+	// the version of the wallet dump files
+	walletDumpVersion = 1
+)
+
+// EncodePrivateKey encodes the private key with the version byte of the
+// network and a checksum, in base58 as the addresses.
+func EncodePrivateKey(privateKey []byte) (string, error) {
+	if config.Parameters.ChainParam == nil {
+		return "", errors.New("no network to tag the private key with")
+	}
+	if len(privateKey) == 0 || len(privateKey) > privateKeyLen {
+		return "", errors.New("invalid private key length")
+	}
+	data := make([]byte, encodedPrivateKeyLen-4)
+	data[0] = config.Parameters.ChainParam.PrivateKeyID
+	copy(data[1+privateKeyLen-len(privateKey):], privateKey)
+	data[1+privateKeyLen] = privateKeyCompressed
+	temp := sha256.Sum256(data)
+	temps := sha256.Sum256(temp[:])
+	data = append(data, temps[0:4]...)
+	defer ClearBytes(data, len(data))
+
+	bi := new(big.Int).SetBytes(data).String()
+	encoded, err := base58.BitcoinEncoding.Encode([]byte(bi))
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// DecodePrivateKey decodes a private key encoded by EncodePrivateKey, which
+// fails for a key of another network or a mistyped one.
+func DecodePrivateKey(s string) ([]byte, error) {
+	decoded, err := base58.BitcoinEncoding.Decode([]byte(s))
+	if err != nil {
+		return nil, errors.New("invalid private key encoding")
+	}
+	x, ok := new(big.Int).SetString(string(decoded), 10)
+	if !ok || len(x.Bytes()) != encodedPrivateKeyLen {
+		return nil, errors.New("invalid private key length")
+	}
+	data := x.Bytes()
+	defer ClearBytes(data, len(data))
+	temp := sha256.Sum256(data[:encodedPrivateKeyLen-4])
+	temps := sha256.Sum256(temp[:])
+	if !bytes.Equal(temps[0:4], data[encodedPrivateKeyLen-4:]) {
+		return nil, errors.New("private key checksum mismatch")
+	}
+	if config.Parameters.ChainParam == nil || data[0] != config.Parameters.ChainParam.PrivateKeyID {
+		return nil, errors.New("the private key is of another network")
+	}
+	if data[1+privateKeyLen] != privateKeyCompressed {
+		return nil, errors.New("unsupported private key format")
+	}
+	privateKey := make([]byte, privateKeyLen)
+	copy(privateKey, data[1:1+privateKeyLen])
+	return privateKey, nil
+}
+
+// ParsePrivateKey parses a private key encoded by EncodePrivateKey, or in hex
+// as the older wallets took them.
+func ParsePrivateKey(s string) ([]byte, error) {
+	if privateKey, err := HexStringToBytes(s); err == nil && len(privateKey) > 0 {
+		return privateKey, nil
+	}
+	return DecodePrivateKey(s)
+}
+
+// ExportPrivateKey returns the encoded private key of the wallet account once
+// the wallet password is verified
+func (cl *ClientImpl) ExportPrivateKey(programHash Uint160, password []byte) (string, error) {
+	passwordKey, err := cl.checkPassword(password)
+	if err != nil {
+		return "", err
+	}
+	ClearBytes(passwordKey, len(passwordKey))
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	account, ok := cl.accounts[programHash]
+	if !ok {
+		return "", errors.New("the address is not an account of the wallet")
+	}
+	return EncodePrivateKey(account.PrivateKey)
+}
+
+// ImportPrivateKey adds the account of the private key to the wallet, which
+// syncs again from the birthday of the key if it is below the wallet height.
+func (cl *ClientImpl) ImportPrivateKey(privateKey []byte, birthday uint32) (*Account, error) {
+	account, err := NewAccountWithPrivatekey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cl.addAccount(account); err != nil {
+		return nil, err
+	}
+	if err := cl.lowerBirthday(birthday); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// addAccount imports the account unless the wallet has it
+func (cl *ClientImpl) addAccount(account *Account) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if _, ok := cl.accounts[account.ProgramHash]; ok {
+		return errors.New("the key is in the wallet already")
+	}
+	return cl.importAccount(account)
+}
+
+// importAccount saves the account and its signature contract, in place of the
+// address if it was watched. The caller holds the client lock.
+func (cl *ClientImpl) importAccount(account *Account) error {
+	contract, err := ct.CreateSignatureContract(account.PubKey())
+	if err != nil {
+		return err
+	}
+	if err := cl.saveAccount(account); err != nil {
+		return err
+	}
+	if err := cl.saveContract(contract); err != nil {
+		return err
+	}
+	if _, ok := cl.watchOnly[account.ProgramHash]; !ok {
+		return nil
+	}
+	delete(cl.watchOnly, account.ProgramHash)
+	if err := cl.DeleteWatchOnlyData(BytesToHexString(account.ProgramHash.ToArray())); err != nil {
+		return err
+	}
+	for _, coin := range cl.coins {
+		if coin.Output.ProgramHash == account.ProgramHash {
+			coin.AddressType = SingleSign
+		}
+	}
+	return cl.SaveCoins()
+}
+
+// WalletDump is the content of a wallet moved to another node: the encoded
+// private keys, the multisig contracts, the watch-only addresses or scripts,
+// the labels and the address book. The accounts of an HD wallet are dumped as
+// their keys.
+type WalletDump struct {
+	Network     string
+	Birthday    uint32
+	Keys        []string
+	MultiSig    []string
+	WatchOnly   []string
+	Labels      []LabelData
+	AddressBook []ContactData
+}
+
+// walletDumpFile is the dump encrypted with a key derived from the password
+// and authenticated by a MAC of the IV and the encrypted data.
+type walletDumpFile struct {
+	Version int
+	KDF     *KDFData
+	IV      string
+	Data    string
+	MAC     string
+}
+
+// walletDump returns the content of the wallet to dump
+func (cl *ClientImpl) walletDump() (*WalletDump, error) {
+	if config.Parameters.ChainParam == nil {
+		return nil, errors.New("no network to tag the wallet dump with")
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	dump := &WalletDump{
+		Network:  config.Parameters.ChainParam.Name,
+		Birthday: cl.birthday,
+	}
+	for _, account := range cl.accounts {
+		key, err := EncodePrivateKey(account.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		dump.Keys = append(dump.Keys, key)
+	}
+	for programHash, contract := range cl.contracts {
+		if _, ok := cl.accounts[programHash]; !ok {
+			dump.MultiSig = append(dump.MultiSig, BytesToHexString(contract.ToArray()))
+		}
+	}
+	for _, w := range cl.watchOnly {
+		if w.Contract != nil {
+			dump.WatchOnly = append(dump.WatchOnly, BytesToHexString(w.Contract.Code))
+			continue
+		}
+		address, err := w.ProgramHash.ToAddress()
+		if err != nil {
+			return nil, err
+		}
+		dump.WatchOnly = append(dump.WatchOnly, address)
+	}
+	var err error
+	if dump.Labels, err = cl.LoadLabelsData(); err != nil {
+		return nil, err
+	}
+	if dump.AddressBook, err = cl.LoadAddressBookData(); err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+// DumpWallet writes the wallet to a file encrypted with the password, which
+// ImportDump adds to another wallet. An existing file is not overwritten.
+func (cl *ClientImpl) DumpWallet(path string, password []byte) error {
+	dump, err := cl.walletDump()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(dump)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(data, len(data))
+
+	salt := make([]byte, kdfSaltLen)
+	iv := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+	kdf := DefaultKDF
+	kdf.Salt = BytesToHexString(salt)
+	passwordKey, macKey, err := deriveKeys(password, &kdf)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+
+	encrypted, err := crypto.AesEncrypt(crypto.PKCS5Padding(data, 16), passwordKey, iv)
+	if err != nil {
+		return err
+	}
+	file := walletDumpFile{
+		Version: walletDumpVersion,
+		KDF:     &kdf,
+		IV:      BytesToHexString(iv),
+		Data:    BytesToHexString(encrypted),
+		MAC:     BytesToHexString(fileMAC(macKey, append(iv, encrypted...))),
+	}
+	blob, err := json.Marshal(&file)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(blob); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadWalletDump decrypts a wallet dump file with its password
+func ReadWalletDump(path string, password []byte) (*WalletDump, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file walletDumpFile
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return nil, errors.New("not a wallet dump file")
+	}
+	if file.Version != walletDumpVersion || file.KDF == nil {
+		return nil, errors.New("unsupported wallet dump version")
+	}
+	iv, err := HexStringToBytes(file.IV)
+	if err != nil || len(iv) != 16 {
+		return nil, errors.New("invalid wallet dump IV")
+	}
+	encrypted, err := HexStringToBytes(file.Data)
+	if err != nil {
+		return nil, errors.New("invalid wallet dump data")
+	}
+	mac, err := HexStringToBytes(file.MAC)
+	if err != nil {
+		return nil, errors.New("invalid wallet dump MAC")
+	}
+	passwordKey, macKey, err := deriveKeys(password, file.KDF)
+	if err != nil {
+		return nil, err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+	if !hmac.Equal(mac, fileMAC(macKey, append(iv, encrypted...))) {
+		return nil, errors.New("wrong password or corrupted wallet dump")
+	}
+
+	data, err := crypto.AesDecrypt(encrypted, passwordKey, iv)
+	if err != nil {
+		return nil, err
+	}
+	defer ClearBytes(data, len(data))
+	if padding := int(data[len(data)-1]); padding == 0 || padding > 16 {
+		return nil, errors.New("invalid wallet dump padding")
+	}
+	var dump WalletDump
+	if err := json.Unmarshal(crypto.PKCS5UnPadding(data), &dump); err != nil {
+		return nil, errors.New("invalid wallet dump data")
+	}
+	if config.Parameters.ChainParam == nil || dump.Network != config.Parameters.ChainParam.Name {
+		return nil, errors.New("the wallet dump is of another network")
+	}
+	return &dump, nil
+}
+
+// ImportDump adds the keys, the contracts, the watch-only addresses, the
+// labels and the contacts of the dump missing from the wallet, which syncs
+// again from the birthday. It returns the number of keys added.
+func (cl *ClientImpl) ImportDump(dump *WalletDump, birthday uint32) (int, error) {
+	added, err := cl.importDump(dump)
+	if err != nil {
+		return added, err
+	}
+	return added, cl.lowerBirthday(birthday)
+}
+
+// importDump adds the content of the dump missing from the wallet
+func (cl *ClientImpl) importDump(dump *WalletDump) (int, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	added := 0
+	for _, key := range dump.Keys {
+		privateKey, err := DecodePrivateKey(key)
+		if err != nil {
+			return added, err
+		}
+		account, err := NewAccountWithPrivatekey(privateKey)
+		if err != nil {
+			return added, err
+		}
+		if _, ok := cl.accounts[account.ProgramHash]; ok {
+			continue
+		}
+		if err := cl.importAccount(account); err != nil {
+			return added, err
+		}
+		added++
+	}
+	for _, raw := range dump.MultiSig {
+		rawdata, err := HexStringToBytes(raw)
+		if err != nil {
+			return added, err
+		}
+		contract := new(ct.Contract)
+		if err := contract.Deserialize(bytes.NewReader(rawdata)); err != nil {
+			return added, err
+		}
+		if contract.ProgramHash, err = ToCodeHash(contract.Code); err != nil {
+			return added, err
+		}
+		if _, ok := cl.contracts[contract.ProgramHash]; ok {
+			continue
+		}
+		if err := cl.saveContract(contract); err != nil {
+			return added, err
+		}
+	}
+	for _, w := range dump.WatchOnly {
+		programHash, contract, err := ParseWatchOnly(w)
+		if err != nil {
+			return added, err
+		}
+		if _, ok := cl.addressType(programHash); ok {
+			continue
+		}
+		if err := cl.SaveWatchOnlyData(programHash, contract); err != nil {
+			return added, err
+		}
+		cl.watchOnly[programHash] = &WatchOnlyAccount{ProgramHash: programHash, Contract: contract}
+	}
+	for _, l := range dump.Labels {
+		if l.TxID != "" {
+			if txID, err := parseHash(l.TxID); err == nil {
+				cl.txLabels[txID] = l.Label
+			}
+			continue
+		}
+		b, _ := HexStringToBytes(l.ProgramHash)
+		if programHash, err := Uint160ParseFromBytes(b); err == nil {
+			if _, ok := cl.addressType(programHash); ok {
+				cl.addressLabels[programHash] = l.Label
+			}
+		}
+	}
+	if err := cl.saveLabels(); err != nil {
+		return added, err
+	}
+	for _, contact := range dump.AddressBook {
+		if _, ok := cl.contacts[contact.Name]; ok {
+			continue
+		}
+		if programHash, err := ToScriptHash(contact.Address); err == nil {
+			cl.contacts[contact.Name] = programHash
+		}
+	}
+	if err := cl.saveAddressBook(); err != nil {
+		return added, err
+	}
+	return added, nil
+}
@@ -0,0 +1,142 @@
+package account
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+)
+
+func TestPrivateKeyEncoding(t *testing.T) {
+	key := testKey("exported")
+	encoded, err := EncodePrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePrivateKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, key) {
+		t.Fatal("decoded private key differs")
+	}
+	if parsed, err := ParsePrivateKey(BytesToHexString(key)); err != nil || !bytes.Equal(parsed, key) {
+		t.Error("private key in hex not parsed")
+	}
+
+	// A mistyped key fails the checksum
+	mistyped := []byte(encoded)
+	if mistyped[10] == '2' {
+		mistyped[10] = '3'
+	} else {
+		mistyped[10] = '2'
+	}
+	if _, err := DecodePrivateKey(string(mistyped)); err == nil {
+		t.Error("mistyped private key decoded")
+	}
+
+	// A key of another network is rejected
+	params := *config.Parameters.ChainParam
+	other := params
+	other.PrivateKeyID++
+	config.Parameters.ChainParam = &other
+	defer func() { config.Parameters.ChainParam = &params }()
+	if _, err := DecodePrivateKey(encoded); err == nil {
+		t.Error("private key of another network decoded")
+	}
+}
+
+func TestWalletDump(t *testing.T) {
+	source, name, cleanup := newTestWallet(t)
+	defer cleanup()
+	source.birthday = 50
+	source.currentHeight = 60
+	imported, err := source.ImportPrivateKey(testKey("imported"), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.ImportPrivateKey(testKey("imported"), 30); err == nil {
+		t.Error("imported a key of the wallet again")
+	}
+	watched := Uint160{4, 5, 6}
+	if err := source.AddWatchOnly(watched, nil, uint32(source.currentHeight+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.AddContact("shop", Uint160{7, 8, 9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.SetAddressLabel(imported.ProgramHash, "cold"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.ExportPrivateKey(imported.ProgramHash, []byte("wrong")); err == nil {
+		t.Error("exported a key with a wrong password")
+	}
+	key, err := source.ExportPrivateKey(imported.ProgramHash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := DecodePrivateKey(key); !bytes.Equal(decoded, testKey("imported")) {
+		t.Error("exported key differs from the one imported")
+	}
+
+	file := path.Join(path.Dir(name), "wallet.dump")
+	if err := source.DumpWallet(file, []byte("dump password")); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.DumpWallet(file, []byte("dump password")); err == nil {
+		t.Error("dumped over an existing file")
+	}
+	if _, err := ReadWalletDump(file, []byte("wrong")); err == nil {
+		t.Error("wallet dump read with a wrong password")
+	}
+	dump, err := ReadWalletDump(file, []byte("dump password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Birthday != 30 || len(dump.Keys) != 2 {
+		t.Fatalf("dump of birthday %d with %d keys", dump.Birthday, len(dump.Keys))
+	}
+
+	target, _, cleanupTarget := newTestWallet(t)
+	defer cleanupTarget()
+	target.birthday = 100
+	target.currentHeight = 100
+	added, err := target.ImportDump(dump, dump.Birthday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("%d keys added", added)
+	}
+	if added, _ := target.ImportDump(dump, dump.Birthday); added != 0 {
+		t.Errorf("%d keys added again", added)
+	}
+	if target.birthday != 30 || target.currentHeight != 29 {
+		t.Errorf("wallet syncs from height %d, birthday %d after import", target.currentHeight+1, target.birthday)
+	}
+
+	// The keys born between the birthday and the height sync the blocks since
+	target.currentHeight = 120
+	if _, err := target.ImportPrivateKey(testKey("late"), 110); err != nil {
+		t.Fatal(err)
+	}
+	if target.birthday != 30 || target.currentHeight != 109 {
+		t.Errorf("wallet syncs from height %d, birthday %d after importing a key", target.currentHeight+1, target.birthday)
+	}
+	target.currentHeight = 120
+	if _, err := target.ImportDump(dump, 90); err != nil {
+		t.Fatal(err)
+	}
+	if target.birthday != 30 || target.currentHeight != 89 {
+		t.Errorf("wallet syncs from height %d, birthday %d after importing a dump", target.currentHeight+1, target.birthday)
+	}
+	if target.GetAccountByProgramHash(imported.ProgramHash) == nil || target.GetContract(imported.ProgramHash) == nil {
+		t.Error("imported account or contract missing")
+	}
+	if len(target.GetWatchOnly()) != 1 || target.GetContacts()["shop"] != (Uint160{7, 8, 9}) ||
+		target.GetAddressLabels()[imported.ProgramHash] != "cold" {
+		t.Error("watch-only address, contact or label missing")
+	}
+}
@@ -2,6 +2,8 @@ package account
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -11,14 +13,15 @@ import (
 	"sync"
 
 	. "DNA_POW/common"
-	"DNA_POW/common/serialization"
 	ct "DNA_POW/core/contract"
 	"DNA_POW/core/transaction"
 	. "DNA_POW/errors"
 )
 
 const (
-       WalletStoreVersion = "1.0.0"
+	// WalletStoreVersionV1 keeps the password key of two sha256 and no MAC
+	WalletStoreVersionV1 = "1.0.0"
+	WalletStoreVersion   = "2.0.0"
 )
 
 type WalletData struct {
@@ -26,7 +29,22 @@ type WalletData struct {
 	IV           string
 	MasterKey    string
 	Height       int32
+	Birthday     uint32
 	Version      string
+	HDSeed       string
+	GapLimit     uint32
+	KDF          *KDFData
+	MAC          string
+}
+
+// KDFData is the key derivation function the password key and the MAC key of
+// the wallet are derived with, nil in the wallets of version 1.
+type KDFData struct {
+	Name string
+	Salt string
+	N    int
+	R    int
+	P    int
 }
 
 type AccountData struct {
@@ -34,6 +52,7 @@ type AccountData struct {
 	ProgramHash         string
 	PrivateKeyEncrypted string
 	Type                string
+	DerivationPath      string
 }
 
 type ContractData struct {
@@ -41,6 +60,58 @@ type ContractData struct {
 	RawData     string
 }
 
+// WatchOnlyData is a watch-only address, the raw contract is empty when only
+// the program hash is known
+type WatchOnlyData struct {
+	Address     string
+	ProgramHash string
+	RawData     string
+}
+
+// TransactionData is a transaction of the wallet, the block hash is empty
+// while it is unconfirmed. The wallet coins it spends are encoded like Coins.
+type TransactionData struct {
+	TxID          string
+	Category      string
+	Amounts       []AmountData
+	Fee           Fixed64
+	ProgramHashes []string
+	Height        uint32
+	BlockHash     string
+	Time          uint32
+	Spent         CoinData `json:",omitempty"`
+}
+
+type AmountData struct {
+	AssetID string
+	Value   Fixed64
+}
+
+// LabelData is the label of a wallet address or of a transaction
+type LabelData struct {
+	ProgramHash string
+	TxID        string
+	Label       string
+}
+
+// ContactData is a named recipient of the address book
+type ContactData struct {
+	Name    string
+	Address string
+}
+
+// PaymentRequestData is a payment requested to a wallet address, the paying
+// transaction is empty until it is confirmed
+type PaymentRequestData struct {
+	ID          string
+	ProgramHash string
+	AssetID     string
+	Amount      Fixed64
+	Label       string
+	Time        int64
+	PaidTxID    string
+}
+
 type FileStore struct {
 	// this lock could be hold by readDB, writeDB and interrupt signals.
 	sync.Mutex
@@ -48,18 +119,32 @@ type FileStore struct {
 	data FileData
 	file *os.File
 	path string
+
+	// the key of the MAC of the wallet file, nil for the wallets of version 1
+	macKey []byte
 }
 
 type CoinData string
 
 type FileData struct {
 	WalletData
-	Account  []AccountData
-	Contract []ContractData
-	Coins    CoinData
+	Account   []AccountData
+	Contract  []ContractData
+	WatchOnly []WatchOnlyData
+	Coins     CoinData
+	History   []TransactionData
+	Labels    []LabelData
+
+	AddressBook     []ContactData
+	PaymentRequests []PaymentRequestData
+
+	// the version of the coins, empty for those saved without it
+	CoinsVersion string `json:",omitempty"`
 }
 
-// Caller holds the lock and reads bytes from DB, then close the DB and release the lock
+// Caller holds the lock and reads bytes from DB, then close the DB and release the lock.
+// Once the wallet file is verified the bytes are checked against the MAC, so
+// a Save* keeps nothing of a file changed since.
 func (cs *FileStore) readDB() ([]byte, error) {
 	cs.Lock()
 	defer cs.Unlock()
@@ -76,6 +161,11 @@ func (cs *FileStore) readDB() ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		if cs.macKey != nil {
+			if err := verifyMAC(data, cs.macKey); err != nil {
+				return nil, err
+			}
+		}
 		return data, nil
 	} else {
 		return nil, NewDetailErr(errors.New("[readDB] file handle is nil"), ErrNoCode, "")
@@ -108,9 +198,107 @@ func (cs *FileStore) closeDB() {
 	}
 }
 
+// marshalDB returns the JSON of the wallet data with the MAC of it when the
+// key of the MAC is known. The MAC is of the JSON with the MAC empty.
+func (cs *FileStore) marshalDB() ([]byte, error) {
+	cs.data.MAC = ""
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil || cs.macKey == nil {
+		return JSONBlob, err
+	}
+	cs.data.MAC = BytesToHexString(fileMAC(cs.macKey, JSONBlob))
+	return bytes.Replace(JSONBlob, macField(""), macField(cs.data.MAC), 1), nil
+}
+
+// macField returns the bytes of the MAC field in the JSON of the wallet data
+func macField(mac string) []byte {
+	return []byte(`"MAC":"` + mac + `"`)
+}
+
+func fileMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// VerifyDB checks the wallet file against its MAC, which none but the holder
+// of the password can make. A wallet of version 1 has none. The MAC is
+// checked over the bytes of the file with the MAC emptied, so the wallets
+// written with other fields than this version knows are verified as well.
+func (cs *FileStore) VerifyDB(macKey []byte) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := verifyMAC(JSONData, macKey); err != nil {
+		return err
+	}
+	cs.macKey = macKey
+
+	return nil
+}
+
+// verifyMAC checks the bytes of the wallet file against their MAC
+func verifyMAC(JSONData []byte, macKey []byte) error {
+	var data FileData
+	if err := json.Unmarshal(JSONData, &data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	saved, err := HexStringToBytes(data.MAC)
+	if err != nil || len(saved) == 0 {
+		return errors.New("the wallet file has no MAC")
+	}
+	// the quotes in the JSON strings are escaped, only the key matches
+	if bytes.Count(JSONData, []byte(`"MAC":`)) != 1 {
+		return errors.New("the wallet file is tampered")
+	}
+	JSONBlob := bytes.Replace(JSONData, macField(data.MAC), macField(""), 1)
+	if !hmac.Equal(saved, fileMAC(macKey, JSONBlob)) {
+		return errors.New("the wallet file is tampered")
+	}
+	return nil
+}
+
+// SaveKDFData saves the key derivation function, the password hash and the
+// encrypted master key of a password at once, and authenticates the wallet
+// file with the MAC key from then on. The wallet becomes the current version.
+func (cs *FileStore) SaveKDFData(kdf *KDFData, passwordHash []byte, masterKey []byte, macKey []byte) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	cs.data.Version = WalletStoreVersion
+	cs.data.KDF = kdf
+	cs.data.PasswordHash = BytesToHexString(passwordHash)
+	cs.data.MasterKey = BytesToHexString(masterKey)
+	cs.macKey = macKey
+
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadKDFData() (*KDFData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+	return cs.data.KDF, nil
+}
+
 func (cs *FileStore) BuildDatabase(path string) {
 	os.Remove(path)
-	jsonBlob, err := json.Marshal(cs.data)
+	jsonBlob, err := cs.marshalDB()
 	if err != nil {
 		fmt.Println("Build DataBase Error")
 		os.Exit(1)
@@ -118,7 +306,7 @@ func (cs *FileStore) BuildDatabase(path string) {
 	cs.writeDB(jsonBlob)
 }
 
-func (cs *FileStore) SaveAccountData(programHash []byte, encryptedPrivateKey []byte) error {
+func (cs *FileStore) SaveAccountData(programHash []byte, encryptedPrivateKey []byte, derivationPath string) error {
 	JSONData, err := cs.readDB()
 	if err != nil {
 		return errors.New("error: reading db")
@@ -147,10 +335,11 @@ func (cs *FileStore) SaveAccountData(programHash []byte, encryptedPrivateKey []b
 		ProgramHash:         BytesToHexString(programHash),
 		PrivateKeyEncrypted: BytesToHexString(encryptedPrivateKey),
 		Type:                accountType,
+		DerivationPath:      derivationPath,
 	}
 	cs.data.Account = append(cs.data.Account, a)
 
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -177,7 +366,7 @@ func (cs *FileStore) DeleteAccountData(programHash string) error {
 		}
 	}
 
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -211,7 +400,7 @@ func (cs *FileStore) SaveContractData(ct *ct.Contract) error {
 	}
 	cs.data.Contract = append(cs.data.Contract, c)
 
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -235,7 +424,7 @@ func (cs *FileStore) DeleteContractData(programHash string) error {
 		}
 	}
 
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -256,7 +445,7 @@ func (cs *FileStore) LoadContractData() ([]ContractData, error) {
 	return cs.data.Contract, nil
 }
 
-func (cs *FileStore) SaveCoinsData(coins map[*transaction.UTXOTxInput]*Coin) error {
+func (cs *FileStore) SaveWatchOnlyData(programHash Uint160, ct *ct.Contract) error {
 	JSONData, err := cs.readDB()
 	if err != nil {
 		return errors.New("error: reading db")
@@ -264,21 +453,78 @@ func (cs *FileStore) SaveCoinsData(coins map[*transaction.UTXOTxInput]*Coin) err
 	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
 		return errors.New("error: unmarshal db")
 	}
+	addr, err := programHash.ToAddress()
+	if err != nil {
+		return errors.New("invalid address")
+	}
+	w := WatchOnlyData{
+		Address:     addr,
+		ProgramHash: BytesToHexString(programHash.ToArray()),
+	}
+	if ct != nil {
+		w.RawData = BytesToHexString(ct.ToArray())
+	}
+	cs.data.WatchOnly = append(cs.data.WatchOnly, w)
 
-	length := uint32(len(coins))
-	if length == 0 {
-		cs.data.Coins = ""
-	} else {
-		w := new(bytes.Buffer)
-		serialization.WriteUint32(w, uint32(len(coins)))
-		for k, v := range coins {
-			k.Serialize(w)
-			v.Serialize(w, cs.data.Version)
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) DeleteWatchOnlyData(programHash string) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	for i, v := range cs.data.WatchOnly {
+		if programHash == v.ProgramHash {
+			cs.data.WatchOnly = append(cs.data.WatchOnly[:i], cs.data.WatchOnly[i+1:]...)
+			break
 		}
-		cs.data.Coins = CoinData(BytesToHexString(w.Bytes()))
 	}
 
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadWatchOnlyData() ([]WatchOnlyData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+
+	return cs.data.WatchOnly, nil
+}
+
+func (cs *FileStore) SaveCoinsData(coins map[*transaction.UTXOTxInput]*Coin) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	cs.data.Coins = encodeCoins(coins)
+	cs.data.CoinsVersion = CoinDataVersion
+
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -299,21 +545,13 @@ func (cs *FileStore) DeleteCoinsData(programHash Uint160) error {
 		return nil
 	}
 
-	coins := make(map[*transaction.UTXOTxInput]*Coin)
-	rawCoins, _ := HexStringToBytes(string(cs.data.Coins))
-	r := bytes.NewReader(rawCoins)
-	num, _ := serialization.ReadUint32(r)
-	for i := 0; i < int(num); i++ {
-		input := new(transaction.UTXOTxInput)
-		if err := input.Deserialize(r); err != nil {
-			return err
-		}
-		coin := new(Coin)
-		if err := coin.Deserialize(r, cs.data.Version); err != nil {
-			return err
-		}
-		if coin.Output.ProgramHash != programHash {
-			coins[input] = coin
+	coins, err := decodeCoins(cs.data.Coins, cs.data.CoinsVersion)
+	if err != nil {
+		return err
+	}
+	for input, coin := range coins {
+		if coin.Output.ProgramHash == programHash {
+			delete(coins, input)
 		}
 	}
 	if err := cs.SaveCoinsData(coins); err != nil {
@@ -331,23 +569,131 @@ func (cs *FileStore) LoadCoinsData() (map[*transaction.UTXOTxInput]*Coin, error)
 	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
 		return nil, errors.New("error: unmarshal db")
 	}
-	coins := make(map[*transaction.UTXOTxInput]*Coin)
-	rawCoins, _ := HexStringToBytes(string(cs.data.Coins))
-	r := bytes.NewReader(rawCoins)
-	num, _ := serialization.ReadUint32(r)
-	for i := 0; i < int(num); i++ {
-		input := new(transaction.UTXOTxInput)
-		if err := input.Deserialize(r); err != nil {
-			return nil, err
-		}
-		coin := new(Coin)
-		if err := coin.Deserialize(r, cs.data.Version); err != nil {
-			return nil, err
-		}
-		coins[input] = coin
+	return decodeCoins(cs.data.Coins, cs.data.CoinsVersion)
+}
+
+func (cs *FileStore) SaveHistoryData(history []TransactionData) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	cs.data.History = history
+
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadHistoryData() ([]TransactionData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
 	}
 
-	return coins, nil
+	return cs.data.History, nil
+}
+
+func (cs *FileStore) SaveLabelsData(labels []LabelData) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	cs.data.Labels = labels
+
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadLabelsData() ([]LabelData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+
+	return cs.data.Labels, nil
+}
+
+func (cs *FileStore) SaveAddressBookData(contacts []ContactData) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	cs.data.AddressBook = contacts
+
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadAddressBookData() ([]ContactData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+
+	return cs.data.AddressBook, nil
+}
+
+func (cs *FileStore) SavePaymentRequestsData(requests []PaymentRequestData) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+	cs.data.PaymentRequests = requests
+
+	JSONBlob, err := cs.marshalDB()
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadPaymentRequestsData() ([]PaymentRequestData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+
+	return cs.data.PaymentRequests, nil
 }
 
 func (cs *FileStore) SaveStoredData(name string, value []byte) error {
@@ -369,14 +715,26 @@ func (cs *FileStore) SaveStoredData(name string, value []byte) error {
 		cs.data.MasterKey = hexValue
 	case "PasswordHash":
 		cs.data.PasswordHash = hexValue
+	case "HDSeed":
+		cs.data.HDSeed = hexValue
+	case "GapLimit":
+		var gapLimit uint32
+		bytesBuffer := bytes.NewBuffer(value)
+		binary.Read(bytesBuffer, binary.LittleEndian, &gapLimit)
+		cs.data.GapLimit = gapLimit
 	case "Height":
 		var height int32
 		bytesBuffer := bytes.NewBuffer(value)
 		binary.Read(bytesBuffer, binary.LittleEndian, &height)
 		cs.data.Height = height
+	case "Birthday":
+		var birthday uint32
+		bytesBuffer := bytes.NewBuffer(value)
+		binary.Read(bytesBuffer, binary.LittleEndian, &birthday)
+		cs.data.Birthday = birthday
 
 	}
-	JSONBlob, err := json.Marshal(cs.data)
+	JSONBlob, err := cs.marshalDB()
 	if err != nil {
 		return errors.New("error: marshal db")
 	}
@@ -402,10 +760,20 @@ func (cs *FileStore) LoadStoredData(name string) ([]byte, error) {
 		return HexStringToBytes(cs.data.MasterKey)
 	case "PasswordHash":
 		return HexStringToBytes(cs.data.PasswordHash)
+	case "HDSeed":
+		return HexStringToBytes(cs.data.HDSeed)
+	case "GapLimit":
+		bytesBuffer := bytes.NewBuffer([]byte{})
+		binary.Write(bytesBuffer, binary.LittleEndian, cs.data.GapLimit)
+		return bytesBuffer.Bytes(), nil
 	case "Height":
 		bytesBuffer := bytes.NewBuffer([]byte{})
 		binary.Write(bytesBuffer, binary.LittleEndian, cs.data.Height)
 		return bytesBuffer.Bytes(), nil
+	case "Birthday":
+		bytesBuffer := bytes.NewBuffer([]byte{})
+		binary.Write(bytesBuffer, binary.LittleEndian, cs.data.Birthday)
+		return bytesBuffer.Bytes(), nil
 	}
 
 	return nil, errors.New("Can't find the key: " + name)
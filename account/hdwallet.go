@@ -0,0 +1,232 @@
+package account
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"DNA_POW/core/contract"
+	"DNA_POW/core/ledger"
+	"DNA_POW/crypto"
+)
+
+const (
+	// DefaultDerivationPath is the BIP-0044 external chain of the first
+	// account with the coin type 2305 registered for ELA in SLIP-0044, the
+	// HD accounts are its children.
+	DefaultDerivationPath = "m/44'/2305'/0'/0"
+	// DefaultGapLimit is the number of unused HD accounts derived ahead of
+	// the last used one while recovering a wallet.
+	DefaultGapLimit = 20
+)
+
+// CreateHD creates a wallet whose accounts are derived from the mnemonic
+// sentence, which is the only backup the wallet needs.
+func CreateHD(path string, passwordKey []byte, mnemonic string) (*ClientImpl, error) {
+	seed, err := crypto.MnemonicToSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(path, passwordKey, true)
+	if client == nil {
+		return nil, errors.New("client nil")
+	}
+	if err := client.setHDSeed(seed); err != nil {
+		return nil, err
+	}
+	account, err := client.CreateAccount()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.CreateContract(account); err != nil {
+		return nil, err
+	}
+	client.mainAccount = account.ProgramHash
+
+	return client, nil
+}
+
+// RecoverHD recreates the wallet of the mnemonic sentence. The first gapLimit
+// accounts are derived at once and the wallet is synced from the genesis
+// block, every account found in use extends the derived accounts so that
+// gapLimit unused ones follow the last used account.
+func RecoverHD(path string, password []byte, mnemonic string, gapLimit uint32) (*ClientImpl, error) {
+	if gapLimit == 0 {
+		return nil, errors.New("gap limit must be positive")
+	}
+	client, err := CreateHD(path, password, mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.gapLimit = gapLimit
+	bytesBuffer := bytes.NewBuffer([]byte{})
+	binary.Write(bytesBuffer, binary.LittleEndian, gapLimit)
+	if err := client.SaveStoredData("GapLimit", bytesBuffer.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := client.deriveHDAccounts(gapLimit); err != nil {
+		return nil, err
+	}
+
+	// the coins of the accounts are found by syncing from the genesis block
+	client.currentHeight = -1
+	var height int32 = -1
+	bytesBuffer = bytes.NewBuffer([]byte{})
+	binary.Write(bytesBuffer, binary.LittleEndian, &height)
+	if err := client.SaveStoredData("Height", bytesBuffer.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// setHDSeed saves the seed encrypted with the master key and sets the chain
+// key the accounts are derived from.
+func (cl *ClientImpl) setHDSeed(seed []byte) error {
+	master, err := crypto.NewMasterKey(seed)
+	if err != nil {
+		return err
+	}
+	chain, err := master.Derive(DefaultDerivationPath)
+	if err != nil {
+		return err
+	}
+	encryptedSeed, err := crypto.AesEncrypt(seed, cl.masterKey, cl.iv)
+	if err != nil {
+		return err
+	}
+	if err := cl.SaveStoredData("HDSeed", encryptedSeed); err != nil {
+		return err
+	}
+	cl.hdChain = chain
+
+	return nil
+}
+
+// loadHDSeed loads the HD seed and gap limit of the wallet, a wallet without
+// seed creates random accounts.
+func (cl *ClientImpl) loadHDSeed() error {
+	encryptedSeed, err := cl.LoadStoredData("HDSeed")
+	if err != nil {
+		return err
+	}
+	if len(encryptedSeed) == 0 {
+		return nil
+	}
+	seed, err := crypto.AesDecrypt(encryptedSeed, cl.masterKey, cl.iv)
+	if err != nil {
+		return err
+	}
+	master, err := crypto.NewMasterKey(seed)
+	if err != nil {
+		return err
+	}
+	if cl.hdChain, err = master.Derive(DefaultDerivationPath); err != nil {
+		return err
+	}
+
+	tmp, err := cl.LoadStoredData("GapLimit")
+	if err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewBuffer(tmp), binary.LittleEndian, &cl.gapLimit)
+}
+
+// hdIndex returns the account index, the last element of the derivation path
+func hdIndex(path string) (uint32, error) {
+	indexes, err := crypto.ParseDerivationPath(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(indexes) == 0 {
+		return 0, fmt.Errorf("derivation path %q has no account index", path)
+	}
+	return indexes[len(indexes)-1], nil
+}
+
+func (cl *ClientImpl) deriveHDAccount(index uint32) (*Account, error) {
+	key, err := cl.hdChain.Child(index)
+	if err != nil {
+		return nil, err
+	}
+	account, err := NewAccountWithPrivatekey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	account.DerivationPath = fmt.Sprintf("%s/%d", DefaultDerivationPath, index)
+
+	return account, nil
+}
+
+func (cl *ClientImpl) createHDAccount() (*Account, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	account, err := cl.deriveHDAccount(cl.hdNext)
+	if err != nil {
+		return nil, err
+	}
+	if err := cl.saveAccount(account); err != nil {
+		return nil, err
+	}
+	cl.hdNext++
+
+	return account, nil
+}
+
+// deriveHDAccounts derives and saves the HD accounts with their contracts up
+// to the index count, the caller holds the client lock.
+func (cl *ClientImpl) deriveHDAccounts(count uint32) error {
+	for cl.hdNext < count {
+		account, err := cl.deriveHDAccount(cl.hdNext)
+		if err != nil {
+			return err
+		}
+		ct, err := contract.CreateSignatureContract(account.PubKey())
+		if err != nil {
+			return err
+		}
+		if err := cl.saveAccount(account); err != nil {
+			return err
+		}
+		if err := cl.saveContract(ct); err != nil {
+			return err
+		}
+		cl.hdNext++
+	}
+	return nil
+}
+
+// discoverHDAccounts keeps gapLimit derived accounts after the last HD
+// account the block pays to, the caller holds the client lock.
+func (cl *ClientImpl) discoverHDAccounts(block *ledger.Block) {
+	if cl.hdChain == nil || cl.gapLimit == 0 {
+		return
+	}
+	for {
+		count := cl.hdNext
+		for _, tx := range block.Transactions {
+			for _, output := range tx.Outputs {
+				account, ok := cl.accounts[output.ProgramHash]
+				if !ok || account.DerivationPath == "" {
+					continue
+				}
+				index, err := hdIndex(account.DerivationPath)
+				if err == nil && index+1+cl.gapLimit > count {
+					count = index + 1 + cl.gapLimit
+				}
+			}
+		}
+		if count == cl.hdNext {
+			return
+		}
+		if err := cl.deriveHDAccounts(count); err != nil {
+			fmt.Println("error: failed to derive HD accounts:", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package account
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"DNA_POW/crypto"
+)
+
+func TestHDWallet(t *testing.T) {
+	crypto.SetAlg("P256R1")
+	dir, err := ioutil.TempDir("", "hdwallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mnemonic, err := crypto.NewMnemonic(crypto.MnemonicEntropyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := path.Join(dir, "created.dat")
+	client, err := CreateHD(created, []byte("password"), mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, _ := client.GetDefaultAccount()
+	if main.DerivationPath != DefaultDerivationPath+"/0" {
+		t.Fatalf("unexpected main account path %q", main.DerivationPath)
+	}
+	second, err := client.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.DerivationPath != DefaultDerivationPath+"/1" {
+		t.Fatalf("unexpected account path %q", second.DerivationPath)
+	}
+
+	// The next index continues after the saved accounts
+	client, err = Open(created, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetAccountByProgramHash(second.ProgramHash).DerivationPath != second.DerivationPath {
+		t.Error("derivation path is not loaded")
+	}
+	third, err := client.CreateAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.DerivationPath != DefaultDerivationPath+"/2" {
+		t.Errorf("unexpected account path %q after reopening", third.DerivationPath)
+	}
+
+	// The recovered wallet derives the same accounts up to the gap limit
+	recovered, err := RecoverHD(path.Join(dir, "recovered.dat"), []byte("password"), mnemonic, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered.GetAccounts()) != 5 || len(recovered.GetContracts()) != 5 {
+		t.Fatalf("unexpected %d accounts recovered", len(recovered.GetAccounts()))
+	}
+	for _, ac := range []*Account{main, second, third} {
+		if recovered.GetAccountByProgramHash(ac.ProgramHash) == nil {
+			t.Errorf("account %s is not recovered", ac.DerivationPath)
+		}
+	}
+	if recovered.mainAccount != main.ProgramHash {
+		t.Error("main account is not recovered")
+	}
+}
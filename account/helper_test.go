@@ -0,0 +1,27 @@
+package account
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"DNA_POW/crypto"
+)
+
+// newTestWallet creates a wallet of the password "password" in a temporary
+// directory, which the cleanup removes.
+func newTestWallet(t *testing.T) (client *ClientImpl, name string, cleanup func()) {
+	crypto.SetAlg("P256R1")
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name = path.Join(dir, "wallet.dat")
+	client, err = Create(name, []byte("password"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return client, name, func() { os.RemoveAll(dir) }
+}
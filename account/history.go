@@ -0,0 +1,425 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+)
+
+// The categories of the transactions of the wallet
+const (
+	TxReceive  = "receive"
+	TxSend     = "send"
+	TxSelf     = "self"
+	TxGenerate = "generate"
+)
+
+// TxRecord is a transaction paying to or spending from the wallet addresses.
+// The amounts are the values received by asset, negative when sent, and the
+// fee is known when the wallet spends all the inputs.
+type TxRecord struct {
+	TxID          Uint256
+	Category      string
+	Amounts       map[Uint256]Fixed64
+	Fee           Fixed64
+	ProgramHashes []Uint160
+	Height        uint32
+	BlockHash     Uint256 // zero while unconfirmed
+	Time          uint32
+
+	// the coins of the wallet it spends, restored when it is unconfirmed
+	spent map[*transaction.UTXOTxInput]*Coin
+
+	// filled in by GetHistory
+	Confirmations uint32
+	Label         string
+}
+
+// Confirmed reports whether the transaction is in a block of the best chain
+func (record *TxRecord) Confirmed() bool {
+	return record.BlockHash != Uint256{}
+}
+
+func hashString(hash Uint256) string {
+	return BytesToHexString(hash.ToArrayReverse())
+}
+
+func parseHash(s string) (Uint256, error) {
+	b, err := HexStringToBytes(s)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return Uint256ParseFromBytes(BytesReverse(b))
+}
+
+func (record *TxRecord) toData() TransactionData {
+	data := TransactionData{
+		TxID:     hashString(record.TxID),
+		Category: record.Category,
+		Fee:      record.Fee,
+		Height:   record.Height,
+		Time:     record.Time,
+	}
+	for assetID, value := range record.Amounts {
+		data.Amounts = append(data.Amounts, AmountData{AssetID: hashString(assetID), Value: value})
+	}
+	for _, programHash := range record.ProgramHashes {
+		data.ProgramHashes = append(data.ProgramHashes, BytesToHexString(programHash.ToArray()))
+	}
+	if record.Confirmed() {
+		data.BlockHash = hashString(record.BlockHash)
+	}
+	data.Spent = encodeCoins(record.spent)
+	return data
+}
+
+func recordFromData(data *TransactionData) (*TxRecord, error) {
+	txID, err := parseHash(data.TxID)
+	if err != nil {
+		return nil, err
+	}
+	record := &TxRecord{
+		TxID:     txID,
+		Category: data.Category,
+		Amounts:  map[Uint256]Fixed64{},
+		Fee:      data.Fee,
+		Height:   data.Height,
+		Time:     data.Time,
+	}
+	for _, amount := range data.Amounts {
+		assetID, err := parseHash(amount.AssetID)
+		if err != nil {
+			return nil, err
+		}
+		record.Amounts[assetID] = amount.Value
+	}
+	for _, p := range data.ProgramHashes {
+		b, _ := HexStringToBytes(p)
+		programHash, err := Uint160ParseFromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		record.ProgramHashes = append(record.ProgramHashes, programHash)
+	}
+	if data.BlockHash != "" {
+		if record.BlockHash, err = parseHash(data.BlockHash); err != nil {
+			return nil, err
+		}
+	}
+	if record.spent, err = decodeCoins(data.Spent, CoinDataVersion); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// findRecord returns the transaction of the wallet history. The caller holds
+// the client lock.
+func (client *ClientImpl) findRecord(txID Uint256) *TxRecord {
+	for i := len(client.history) - 1; i >= 0; i-- {
+		if client.history[i].TxID == txID {
+			return client.history[i]
+		}
+	}
+	return nil
+}
+
+// spentCoin returns the coin of the wallet the input spends, which is looked
+// up in the blockchain while rescanning as the coins spent later are not
+// added. The caller holds the client lock.
+func (client *ClientImpl) spentCoin(input *transaction.UTXOTxInput, rescan bool) (*transaction.UTXOTxInput, *Coin) {
+	for k, coin := range client.coins {
+		if k.ReferTxOutputIndex == input.ReferTxOutputIndex && k.ReferTxID == input.ReferTxID {
+			return k, coin
+		}
+	}
+	if !rescan {
+		return nil, nil
+	}
+	txn, height, err := ledger.DefaultLedger.Store.GetTransaction(input.ReferTxID)
+	if err != nil || int(input.ReferTxOutputIndex) >= len(txn.Outputs) {
+		return nil, nil
+	}
+	output := txn.Outputs[input.ReferTxOutputIndex]
+	addressType, ok := client.addressType(output.ProgramHash)
+	if !ok {
+		return nil, nil
+	}
+	coin := &Coin{Output: output, AddressType: addressType, ReceivedHeight: height}
+	if txn.IsCoinBaseTx() {
+		coin.Height = height + config.Parameters.ChainParam.SpendCoinbaseSpan
+	}
+	return nil, coin
+}
+
+// recordTransaction adds the transaction to the wallet history if it pays to
+// or spends from the wallet, or confirms it again in the block. It reports
+// whether the history changed. The caller holds the client lock.
+func (client *ClientImpl) recordTransaction(block *ledger.Block, tx *transaction.Transaction,
+	spent map[*transaction.UTXOTxInput]*Coin) bool {
+	txID := tx.Hash()
+	if record := client.findRecord(txID); record != nil {
+		if record.BlockHash == block.Hash() {
+			return false
+		}
+		record.Height = block.Blockdata.Height
+		record.BlockHash = block.Hash()
+		record.Time = block.Blockdata.Timestamp
+		if len(spent) > 0 {
+			record.spent = spent
+		}
+		return true
+	}
+
+	record := &TxRecord{
+		TxID:      txID,
+		Amounts:   map[Uint256]Fixed64{},
+		Height:    block.Blockdata.Height,
+		BlockHash: block.Hash(),
+		Time:      block.Blockdata.Timestamp,
+		spent:     spent,
+	}
+	addresses := map[Uint160]bool{}
+	addAddress := func(programHash Uint160) {
+		if !addresses[programHash] {
+			addresses[programHash] = true
+			record.ProgramHashes = append(record.ProgramHashes, programHash)
+		}
+	}
+	received := 0
+	for _, output := range tx.Outputs {
+		if _, ok := client.addressType(output.ProgramHash); ok {
+			record.Amounts[output.AssetID] += output.Value
+			addAddress(output.ProgramHash)
+			received++
+		}
+	}
+	if received == 0 && len(spent) == 0 {
+		return false
+	}
+	for _, coin := range spent {
+		record.Amounts[coin.Output.AssetID] -= coin.Output.Value
+		addAddress(coin.Output.ProgramHash)
+	}
+
+	switch {
+	case tx.IsCoinBaseTx():
+		record.Category = TxGenerate
+	case len(spent) == 0:
+		record.Category = TxReceive
+	case received == len(tx.Outputs):
+		record.Category = TxSelf
+	default:
+		record.Category = TxSend
+	}
+	// the inputs exceeding the outputs are the fee
+	if len(spent) > 0 && len(spent) == len(tx.UTXOInputs) {
+		values := map[Uint256]Fixed64{}
+		for _, coin := range spent {
+			values[coin.Output.AssetID] += coin.Output.Value
+		}
+		for _, output := range tx.Outputs {
+			values[output.AssetID] -= output.Value
+		}
+		for _, value := range values {
+			if value > 0 {
+				record.Fee += value
+			}
+		}
+	}
+	client.history = append(client.history, record)
+
+	return true
+}
+
+// unconfirm marks the transactions of the blocks detached from the best chain
+// unconfirmed and the payment requests they pay not paid, and lowers the
+// wallet height to the fork to sync the blocks attached. The coins received
+// above the fork are removed and those the unconfirmed transactions spend are
+// restored. The caller holds the client lock.
+func (client *ClientImpl) unconfirm(change *ledger.ChainTipChange) {
+	detached := make(map[Uint256]bool, len(change.Detached))
+	for _, hash := range change.Detached {
+		detached[hash] = true
+	}
+	unconfirmed := map[Uint256]bool{}
+	var coinsChanged bool
+	for _, record := range client.history {
+		if record.Confirmed() && detached[record.BlockHash] {
+			record.Height = 0
+			record.BlockHash = Uint256{}
+			unconfirmed[record.TxID] = true
+			for input, coin := range record.spent {
+				if coin.ReceivedHeight <= change.ForkHeight && !client.containsCoin(input) {
+					client.coins[input] = coin
+					coinsChanged = true
+				}
+			}
+		}
+	}
+	for input, coin := range client.coins {
+		if coin.ReceivedHeight > change.ForkHeight {
+			delete(client.coins, input)
+			coinsChanged = true
+		}
+	}
+	if coinsChanged {
+		if err := client.SaveCoins(); err != nil {
+			fmt.Fprintf(os.Stderr, "saving coins error: %v\n", err)
+		}
+	}
+	if len(unconfirmed) > 0 {
+		if err := client.SaveHistory(); err != nil {
+			return
+		}
+		client.unpayRequests(unconfirmed)
+	}
+	if client.currentHeight > int32(change.ForkHeight) {
+		client.currentHeight = int32(change.ForkHeight)
+		client.saveHeight()
+	}
+}
+
+func (client *ClientImpl) onChainTipChanged(v interface{}) {
+	change, ok := v.(*ledger.ChainTipChange)
+	if !ok || len(change.Detached) == 0 {
+		return
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.unconfirm(change)
+}
+
+// GetHistory returns the transactions of the wallet from the oldest, with
+// their confirmations at the wallet height and their labels.
+func (client *ClientImpl) GetHistory() []*TxRecord {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	history := make([]*TxRecord, 0, len(client.history))
+	for _, r := range client.history {
+		record := *r
+		if record.Confirmed() && client.currentHeight >= int32(record.Height) {
+			record.Confirmations = uint32(client.currentHeight) - record.Height + 1
+		}
+		record.Label = client.txLabels[record.TxID]
+		history = append(history, &record)
+	}
+	return history
+}
+
+// SaveHistory saves the wallet history. The caller holds the client lock.
+func (client *ClientImpl) SaveHistory() error {
+	history := make([]TransactionData, 0, len(client.history))
+	for _, record := range client.history {
+		history = append(history, record.toData())
+	}
+	return client.SaveHistoryData(history)
+}
+
+// LoadHistory loads the wallet history from db to memory
+func (client *ClientImpl) LoadHistory() error {
+	data, err := client.LoadHistoryData()
+	if err != nil {
+		return err
+	}
+	history := make([]*TxRecord, 0, len(data))
+	for i := range data {
+		record, err := recordFromData(&data[i])
+		if err != nil {
+			return err
+		}
+		history = append(history, record)
+	}
+	client.history = history
+	return nil
+}
+
+// SetAddressLabel labels an address of the wallet, an empty label removes it
+func (client *ClientImpl) SetAddressLabel(programHash Uint160, label string) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if _, ok := client.addressType(programHash); !ok {
+		return errors.New("the address does not belong to the wallet")
+	}
+	if label == "" {
+		delete(client.addressLabels, programHash)
+	} else {
+		client.addressLabels[programHash] = label
+	}
+	return client.saveLabels()
+}
+
+// SetTransactionLabel labels a transaction, which may be not yet in the
+// wallet history, an empty label removes it
+func (client *ClientImpl) SetTransactionLabel(txID Uint256, label string) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if label == "" {
+		delete(client.txLabels, txID)
+	} else {
+		client.txLabels[txID] = label
+	}
+	return client.saveLabels()
+}
+
+// GetAddressLabels returns the labels of the wallet addresses
+func (client *ClientImpl) GetAddressLabels() map[Uint160]string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	labels := make(map[Uint160]string, len(client.addressLabels))
+	for programHash, label := range client.addressLabels {
+		labels[programHash] = label
+	}
+	return labels
+}
+
+// saveLabels saves the labels. The caller holds the client lock.
+func (client *ClientImpl) saveLabels() error {
+	labels := make([]LabelData, 0, len(client.addressLabels)+len(client.txLabels))
+	for programHash, label := range client.addressLabels {
+		labels = append(labels, LabelData{ProgramHash: BytesToHexString(programHash.ToArray()), Label: label})
+	}
+	for txID, label := range client.txLabels {
+		labels = append(labels, LabelData{TxID: hashString(txID), Label: label})
+	}
+	return client.SaveLabelsData(labels)
+}
+
+// LoadLabels loads the labels of the addresses and the transactions from db
+// to memory
+func (client *ClientImpl) LoadLabels() error {
+	data, err := client.LoadLabelsData()
+	if err != nil {
+		return err
+	}
+	addressLabels := map[Uint160]string{}
+	txLabels := map[Uint256]string{}
+	for _, l := range data {
+		if l.TxID != "" {
+			txID, err := parseHash(l.TxID)
+			if err != nil {
+				return err
+			}
+			txLabels[txID] = l.Label
+			continue
+		}
+		b, _ := HexStringToBytes(l.ProgramHash)
+		programHash, err := Uint160ParseFromBytes(b)
+		if err != nil {
+			return err
+		}
+		addressLabels[programHash] = l.Label
+	}
+	client.addressLabels = addressLabels
+	client.txLabels = txLabels
+	return nil
+}
@@ -0,0 +1,157 @@
+package account
+
+import (
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+	"DNA_POW/core/transaction/payload"
+)
+
+func TestHistory(t *testing.T) {
+	client, name, cleanup := newTestWallet(t)
+	defer cleanup()
+	main := client.mainAccount
+	external := Uint160{1, 2, 3}
+	asset := Uint256{9}
+
+	received, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{AssetID: asset, Value: 50, ProgramHash: main},
+	})
+	sent, _ := transaction.NewTransferAssetTransaction([]*transaction.UTXOTxInput{
+		{ReferTxID: received.Hash(), ReferTxOutputIndex: 0},
+	}, []*transaction.TxOutput{
+		{AssetID: asset, Value: 30, ProgramHash: external},
+		{AssetID: asset, Value: 19, ProgramHash: main},
+	})
+	other, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{AssetID: asset, Value: 7, ProgramHash: external},
+	})
+	blocks := []*ledger.Block{
+		{Blockdata: &ledger.Blockdata{Height: 0}, Transactions: []*transaction.Transaction{received, other}},
+		{Blockdata: &ledger.Blockdata{Height: 1}, Transactions: []*transaction.Transaction{sent}},
+	}
+	for _, block := range blocks {
+		client.ProcessOneBlock(block)
+	}
+
+	if err := client.SetAddressLabel(main, "savings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetAddressLabel(external, "shop"); err == nil {
+		t.Error("labeled an address not of the wallet")
+	}
+	if err := client.SetTransactionLabel(sent.Hash(), "rent"); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := client.GetHistory()
+	if len(history) != 2 {
+		t.Fatalf("%d transactions in the history", len(history))
+	}
+	if r := history[0]; r.TxID != received.Hash() || r.Category != TxReceive || r.Amounts[asset] != 50 ||
+		r.Confirmations != 2 || r.BlockHash != blocks[0].Hash() {
+		t.Errorf("unexpected received transaction %+v", r)
+	}
+	if r := history[1]; r.TxID != sent.Hash() || r.Category != TxSend || r.Amounts[asset] != -31 ||
+		r.Fee != 1 || r.Confirmations != 1 || r.Label != "rent" {
+		t.Errorf("unexpected sent transaction %+v", r)
+	}
+	if labels := client.GetAddressLabels(); len(labels) != 1 || labels[main] != "savings" {
+		t.Errorf("unexpected address labels %v", labels)
+	}
+
+	// A reorg detaching the block marks its transactions unconfirmed
+	client.unconfirm(&ledger.ChainTipChange{ForkHeight: 0, Detached: []Uint256{blocks[1].Hash()}})
+	history = client.GetHistory()
+	if history[1].Confirmed() || history[1].Confirmations != 0 || !history[0].Confirmed() {
+		t.Fatal("transaction of the detached block is confirmed")
+	}
+	if client.currentHeight != 0 {
+		t.Fatalf("wallet height %d is not lowered to the fork", client.currentHeight)
+	}
+	client.ProcessOneBlock(blocks[1])
+	history = client.GetHistory()
+	if len(history) != 2 || !history[1].Confirmed() || history[1].Amounts[asset] != -31 {
+		t.Error("transaction is not confirmed again")
+	}
+}
+
+func TestUnconfirmCoins(t *testing.T) {
+	client, name, cleanup := newTestWallet(t)
+	defer cleanup()
+	main := client.mainAccount
+	external := Uint160{1, 2, 3}
+	asset := Uint256{9}
+
+	received, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{AssetID: asset, Value: 50, ProgramHash: main},
+	})
+	coinbase, _ := transaction.NewCoinBaseTransaction(&payload.CoinBase{}, 1)
+	coinbase.Outputs = []*transaction.TxOutput{{AssetID: asset, Value: 100, ProgramHash: main}}
+	sent, _ := transaction.NewTransferAssetTransaction([]*transaction.UTXOTxInput{
+		{ReferTxID: received.Hash(), ReferTxOutputIndex: 0},
+	}, []*transaction.TxOutput{
+		{AssetID: asset, Value: 30, ProgramHash: external},
+		{AssetID: asset, Value: 19, ProgramHash: main},
+	})
+	blocks := []*ledger.Block{
+		{Blockdata: &ledger.Blockdata{Height: 0}, Transactions: []*transaction.Transaction{received}},
+		{Blockdata: &ledger.Blockdata{Height: 1}, Transactions: []*transaction.Transaction{coinbase, sent}},
+	}
+	for _, block := range blocks {
+		client.ProcessOneBlock(block)
+	}
+	if coins := client.GetCoins(); len(coins) != 2 {
+		t.Fatalf("%d coins before the reorg", len(coins))
+	}
+
+	// The coins spent by the detached transactions are restored after the
+	// wallet is opened again, those received above the fork are removed
+	client, err := Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.unconfirm(&ledger.ChainTipChange{ForkHeight: 0, Detached: []Uint256{blocks[1].Hash()}})
+	coins := client.GetCoins()
+	if len(coins) != 1 {
+		t.Fatalf("%d coins after the reorg", len(coins))
+	}
+	for input, coin := range coins {
+		if input.ReferTxID != received.Hash() || input.ReferTxOutputIndex != 0 || coin.Output.Value != 50 ||
+			coin.ReceivedHeight != 0 {
+			t.Errorf("unexpected coin %+v %+v", input, coin.Output)
+		}
+	}
+	if client, err = Open(name, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+	if coins := client.GetCoins(); len(coins) != 1 {
+		t.Fatalf("%d coins saved after the reorg", len(coins))
+	}
+
+	// The restored coin is spent again on the new best chain
+	spentAgain, _ := transaction.NewTransferAssetTransaction([]*transaction.UTXOTxInput{
+		{ReferTxID: received.Hash(), ReferTxOutputIndex: 0},
+	}, []*transaction.TxOutput{
+		{AssetID: asset, Value: 49, ProgramHash: external},
+	})
+	client.ProcessOneBlock(&ledger.Block{Blockdata: &ledger.Blockdata{Height: 1, Nonce: 1},
+		Transactions: []*transaction.Transaction{spentAgain}})
+	if coins := client.GetCoins(); len(coins) != 0 {
+		t.Errorf("%d coins after the coin is spent again", len(coins))
+	}
+	history := client.GetHistory()
+	if len(history) != 4 || history[1].Confirmed() || history[2].Confirmed() {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if r := history[3]; r.TxID != spentAgain.Hash() || r.Category != TxSend || r.Amounts[asset] != -50 ||
+		r.Fee != 1 || r.Confirmations != 1 {
+		t.Errorf("unexpected transaction spending again %+v", r)
+	}
+}
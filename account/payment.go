@@ -0,0 +1,260 @@
+package account
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	. "DNA_POW/common"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+)
+
+// the length in bytes of the random IDs of the payment requests
+const paymentRequestIDLen = 8
+
+// PaymentRequest is a payment of an amount of an asset requested to a new
+// address of the wallet. It is paid when a transaction paying at least the
+// amount to the address is confirmed.
+type PaymentRequest struct {
+	ID          string
+	ProgramHash Uint160
+	AssetID     Uint256
+	Amount      Fixed64
+	Label       string
+	Time        int64
+	PaidTxID    Uint256 // zero until paid
+}
+
+// Paid reports whether a confirmed transaction pays the request
+func (request *PaymentRequest) Paid() bool {
+	return request.PaidTxID != Uint256{}
+}
+
+// AddContact names a recipient in the address book
+func (client *ClientImpl) AddContact(name string, programHash Uint160) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if name == "" {
+		return errors.New("the contact has no name")
+	}
+	if _, ok := client.contacts[name]; ok {
+		return errors.New("the name is in the address book already")
+	}
+	client.contacts[name] = programHash
+	return client.saveAddressBook()
+}
+
+// DeleteContact removes a recipient from the address book
+func (client *ClientImpl) DeleteContact(name string) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if _, ok := client.contacts[name]; !ok {
+		return errors.New("the name is not in the address book")
+	}
+	delete(client.contacts, name)
+	return client.saveAddressBook()
+}
+
+// GetContacts returns the address book
+func (client *ClientImpl) GetContacts() map[string]Uint160 {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	contacts := make(map[string]Uint160, len(client.contacts))
+	for name, programHash := range client.contacts {
+		contacts[name] = programHash
+	}
+	return contacts
+}
+
+// saveAddressBook saves the address book. The caller holds the client lock.
+func (client *ClientImpl) saveAddressBook() error {
+	contacts := make([]ContactData, 0, len(client.contacts))
+	for name, programHash := range client.contacts {
+		address, err := programHash.ToAddress()
+		if err != nil {
+			return err
+		}
+		contacts = append(contacts, ContactData{Name: name, Address: address})
+	}
+	return client.SaveAddressBookData(contacts)
+}
+
+// LoadAddressBook loads the address book from db to memory
+func (client *ClientImpl) LoadAddressBook() error {
+	data, err := client.LoadAddressBookData()
+	if err != nil {
+		return err
+	}
+	contacts := map[string]Uint160{}
+	for _, contact := range data {
+		programHash, err := ToScriptHash(contact.Address)
+		if err != nil {
+			return err
+		}
+		contacts[contact.Name] = programHash
+	}
+	client.contacts = contacts
+	return nil
+}
+
+// CreatePaymentRequest requests a payment of the amount of the asset to a new
+// address of the wallet
+func (client *ClientImpl) CreatePaymentRequest(assetID Uint256, amount Fixed64, label string) (*PaymentRequest, error) {
+	if amount <= 0 {
+		return nil, errors.New("invalid amount of payment request")
+	}
+	id := make([]byte, paymentRequestIDLen)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	account, err := client.CreateAccount()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.CreateContract(account); err != nil {
+		return nil, err
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	request := &PaymentRequest{
+		ID:          BytesToHexString(id),
+		ProgramHash: account.ProgramHash,
+		AssetID:     assetID,
+		Amount:      amount,
+		Label:       label,
+		Time:        time.Now().Unix(),
+	}
+	client.requests = append(client.requests, request)
+	if err := client.savePaymentRequests(); err != nil {
+		return nil, err
+	}
+	r := *request
+	return &r, nil
+}
+
+// GetPaymentRequests returns the payment requests from the oldest
+func (client *ClientImpl) GetPaymentRequests() []*PaymentRequest {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	requests := make([]*PaymentRequest, 0, len(client.requests))
+	for _, request := range client.requests {
+		r := *request
+		requests = append(requests, &r)
+	}
+	return requests
+}
+
+// payRequests marks the payment requests the transaction pays as paid, and
+// reports whether any is. The caller holds the client lock.
+func (client *ClientImpl) payRequests(tx *transaction.Transaction) bool {
+	var paid bool
+	for _, request := range client.requests {
+		if request.Paid() {
+			continue
+		}
+		var value Fixed64
+		for _, output := range tx.Outputs {
+			if output.ProgramHash == request.ProgramHash && output.AssetID == request.AssetID {
+				value += output.Value
+			}
+		}
+		if value >= request.Amount {
+			request.PaidTxID = tx.Hash()
+			paid = true
+		}
+	}
+	return paid
+}
+
+// processBlockRequests marks the payment requests the transactions of the
+// block pay as paid. The caller holds the client lock.
+func (client *ClientImpl) processBlockRequests(block *ledger.Block) {
+	var paid bool
+	for _, tx := range block.Transactions {
+		if client.payRequests(tx) {
+			paid = true
+		}
+	}
+	if paid {
+		client.savePaymentRequests()
+	}
+}
+
+// unpayRequests marks the payment requests paid by the transactions
+// unconfirmed by a reorg as not paid. The caller holds the client lock.
+func (client *ClientImpl) unpayRequests(unconfirmed map[Uint256]bool) {
+	var changed bool
+	for _, request := range client.requests {
+		if request.Paid() && unconfirmed[request.PaidTxID] {
+			request.PaidTxID = Uint256{}
+			changed = true
+		}
+	}
+	if changed {
+		client.savePaymentRequests()
+	}
+}
+
+// savePaymentRequests saves the payment requests. The caller holds the
+// client lock.
+func (client *ClientImpl) savePaymentRequests() error {
+	requests := make([]PaymentRequestData, 0, len(client.requests))
+	for _, request := range client.requests {
+		data := PaymentRequestData{
+			ID:          request.ID,
+			ProgramHash: BytesToHexString(request.ProgramHash.ToArray()),
+			AssetID:     hashString(request.AssetID),
+			Amount:      request.Amount,
+			Label:       request.Label,
+			Time:        request.Time,
+		}
+		if request.Paid() {
+			data.PaidTxID = hashString(request.PaidTxID)
+		}
+		requests = append(requests, data)
+	}
+	return client.SavePaymentRequestsData(requests)
+}
+
+// LoadPaymentRequests loads the payment requests from db to memory
+func (client *ClientImpl) LoadPaymentRequests() error {
+	data, err := client.LoadPaymentRequestsData()
+	if err != nil {
+		return err
+	}
+	requests := make([]*PaymentRequest, 0, len(data))
+	for _, d := range data {
+		b, _ := HexStringToBytes(d.ProgramHash)
+		programHash, err := Uint160ParseFromBytes(b)
+		if err != nil {
+			return err
+		}
+		assetID, err := parseHash(d.AssetID)
+		if err != nil {
+			return err
+		}
+		request := &PaymentRequest{
+			ID:          d.ID,
+			ProgramHash: programHash,
+			AssetID:     assetID,
+			Amount:      d.Amount,
+			Label:       d.Label,
+			Time:        d.Time,
+		}
+		if d.PaidTxID != "" {
+			if request.PaidTxID, err = parseHash(d.PaidTxID); err != nil {
+				return err
+			}
+		}
+		requests = append(requests, request)
+	}
+	client.requests = requests
+	return nil
+}
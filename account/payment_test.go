@@ -0,0 +1,74 @@
+package account
+
+import (
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+)
+
+func TestPaymentRequests(t *testing.T) {
+	client, name, cleanup := newTestWallet(t)
+	defer cleanup()
+	shop := Uint160{1, 2, 3}
+	if err := client.AddContact("shop", shop); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddContact("shop", Uint160{4}); err == nil {
+		t.Error("contact named twice")
+	}
+
+	asset := Uint256{9}
+	request, err := client.CreatePaymentRequest(asset, 100, "invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetAccountByProgramHash(request.ProgramHash) == nil {
+		t.Fatal("payment request is not to a wallet address")
+	}
+	client, err = Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contacts := client.GetContacts(); len(contacts) != 1 || contacts["shop"] != shop {
+		t.Fatalf("unexpected address book %v", contacts)
+	}
+	if requests := client.GetPaymentRequests(); len(requests) != 1 || requests[0].ID != request.ID || requests[0].Paid() {
+		t.Fatalf("unexpected payment requests %v", requests)
+	}
+
+	// An output short of the amount does not pay the request
+	short, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{AssetID: asset, Value: 60, ProgramHash: request.ProgramHash},
+	})
+	paying, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{AssetID: asset, Value: 60, ProgramHash: request.ProgramHash},
+		{AssetID: asset, Value: 40, ProgramHash: request.ProgramHash},
+	})
+	blocks := []*ledger.Block{
+		{Blockdata: &ledger.Blockdata{Height: 0}, Transactions: []*transaction.Transaction{short}},
+		{Blockdata: &ledger.Blockdata{Height: 1}, Transactions: []*transaction.Transaction{paying}},
+	}
+	client.ProcessOneBlock(blocks[0])
+	if client.GetPaymentRequests()[0].Paid() {
+		t.Fatal("payment request paid by an output short of the amount")
+	}
+	client.ProcessOneBlock(blocks[1])
+	if r := client.GetPaymentRequests()[0]; !r.Paid() || r.PaidTxID != paying.Hash() {
+		t.Fatal("payment request is not paid")
+	}
+
+	// The request is not paid while the paying transaction is unconfirmed
+	client.unconfirm(&ledger.ChainTipChange{ForkHeight: 0, Detached: []Uint256{blocks[1].Hash()}})
+	if client.GetPaymentRequests()[0].Paid() {
+		t.Error("payment request paid by an unconfirmed transaction")
+	}
+	client.ProcessOneBlock(blocks[1])
+	if client, err = Open(name, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+	if r := client.GetPaymentRequests()[0]; r.PaidTxID != paying.Hash() {
+		t.Error("payment request is not paid again")
+	}
+}
@@ -0,0 +1,160 @@
+package account
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"DNA_POW/common/log"
+	"DNA_POW/core/ledger"
+)
+
+// the number of blocks rescanned between the progress logs
+const rescanLogInterval = 1000
+
+// RescanProgress is the range of blocks being rescanned and the height reached
+type RescanProgress struct {
+	StartHeight uint32
+	StopHeight  uint32
+	Height      uint32
+}
+
+// saveHeight saves the wallet height. The caller holds the client lock.
+func (client *ClientImpl) saveHeight() error {
+	bytesBuffer := bytes.NewBuffer([]byte{})
+	binary.Write(bytesBuffer, binary.LittleEndian, &client.currentHeight)
+	return client.SaveStoredData("Height", bytesBuffer.Bytes())
+}
+
+// saveBirthday saves the height the keys of the wallet have their coins since.
+// The caller holds the client lock.
+func (client *ClientImpl) saveBirthday() error {
+	bytesBuffer := bytes.NewBuffer([]byte{})
+	binary.Write(bytesBuffer, binary.LittleEndian, &client.birthday)
+	return client.SaveStoredData("Birthday", bytesBuffer.Bytes())
+}
+
+// resetBirthday sets the birthday of the wallet and syncs it from there.
+func (client *ClientImpl) resetBirthday(birthday uint32) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.birthday = birthday
+	if err := client.saveBirthday(); err != nil {
+		return err
+	}
+	client.currentHeight = int32(birthday) - 1
+	return client.saveHeight()
+}
+
+// lowerBirthday moves the birthday of the wallet back to that of a key
+// imported, the blocks since are synced again if the wallet passed them. A
+// key born after the wallet keeps the birthday but may still need the blocks
+// synced since.
+func (client *ClientImpl) lowerBirthday(birthday uint32) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if birthday < client.birthday {
+		client.birthday = birthday
+		if err := client.saveBirthday(); err != nil {
+			return err
+		}
+	}
+	if int32(birthday) > client.currentHeight {
+		return nil
+	}
+	client.currentHeight = int32(birthday) - 1
+	return client.saveHeight()
+}
+
+// Rescan finds the coins of the wallet in the blocks from the start height to
+// the stop height, which is lowered to the wallet height. The coins spent by
+// the blocks above are not added. It returns the last height rescanned, or the
+// height it is aborted at with an error.
+func (client *ClientImpl) Rescan(startHeight, stopHeight uint32) (uint32, error) {
+	if ledger.DefaultLedger == nil || ledger.DefaultLedger.Blockchain == nil {
+		return 0, errors.New("no local blockchain to rescan")
+	}
+	client.mu.Lock()
+	if height := client.currentHeight; height < 0 {
+		client.mu.Unlock()
+		return 0, errors.New("wallet has not synced any block")
+	} else if stopHeight > uint32(height) {
+		stopHeight = uint32(height)
+	}
+	client.mu.Unlock()
+	if startHeight > stopHeight {
+		return 0, fmt.Errorf("invalid rescan range from %d to %d", startHeight, stopHeight)
+	}
+
+	client.rescanMu.Lock()
+	if client.rescan != nil {
+		client.rescanMu.Unlock()
+		return 0, errors.New("a rescan is in progress")
+	}
+	client.rescan = &RescanProgress{StartHeight: startHeight, StopHeight: stopHeight, Height: startHeight}
+	client.rescanAbort = false
+	client.rescanMu.Unlock()
+	defer func() {
+		client.rescanMu.Lock()
+		client.rescan = nil
+		client.rescanMu.Unlock()
+	}()
+
+	log.Infof("rescanning the wallet from height %d to %d", startHeight, stopHeight)
+	for height := startHeight; height <= stopHeight; height++ {
+		client.rescanMu.Lock()
+		aborted := client.rescanAbort
+		client.rescan.Height = height
+		client.rescanMu.Unlock()
+		if aborted {
+			return height, fmt.Errorf("rescan aborted at height %d", height)
+		}
+		if (height-startHeight)%rescanLogInterval == 0 && height != startHeight {
+			log.Infof("rescanning the wallet, height %d of %d", height, stopHeight)
+		}
+
+		block, err := ledger.DefaultLedger.GetBlockWithHeight(height)
+		if err != nil {
+			return height, fmt.Errorf("rescan failed, block missing, height %d", height)
+		}
+		client.mu.Lock()
+		client.discoverHDAccounts(block)
+		if client.processBlockCoins(block, true) {
+			if err := client.SaveCoins(); err != nil {
+				client.mu.Unlock()
+				return height, err
+			}
+		}
+		client.mu.Unlock()
+	}
+	log.Infof("rescan of the wallet finished at height %d", stopHeight)
+
+	return stopHeight, nil
+}
+
+// AbortRescan stops the rescan in progress, and reports whether there is one.
+func (client *ClientImpl) AbortRescan() bool {
+	client.rescanMu.Lock()
+	defer client.rescanMu.Unlock()
+
+	if client.rescan == nil {
+		return false
+	}
+	client.rescanAbort = true
+	return true
+}
+
+// GetRescanProgress returns the progress of the rescan, nil if there is none.
+func (client *ClientImpl) GetRescanProgress() *RescanProgress {
+	client.rescanMu.Lock()
+	defer client.rescanMu.Unlock()
+
+	if client.rescan == nil {
+		return nil
+	}
+	progress := *client.rescan
+	return &progress
+}
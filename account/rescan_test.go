@@ -0,0 +1,89 @@
+package account
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+)
+
+// testKey returns a private key of 32 bytes derived from the seed
+func testKey(seed string) []byte {
+	key := sha256.Sum256([]byte(seed))
+	return key[:]
+}
+
+func TestBirthday(t *testing.T) {
+	crypto.SetAlg("P256R1")
+	dir, err := ioutil.TempDir("", "birthday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := NewAccountWithPrivatekey(testKey("recovered"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := path.Join(dir, "wallet.dat")
+	if _, err := Recover(name, []byte("password"), BytesToHexString(key.PrivateKey), 100); err != nil {
+		t.Fatal(err)
+	}
+	client, err := Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.birthday != 100 || client.currentHeight != 99 {
+		t.Fatalf("recovered wallet syncs from height %d, birthday %d", client.currentHeight+1, client.birthday)
+	}
+
+	// An imported key born before the wallet lowers the birthday and the height
+	if _, err := client.CreateAccountByPrivateKey(testKey("older"), 40); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateAccountByPrivateKey(testKey("newer"), 120); err != nil {
+		t.Fatal(err)
+	}
+	if client.birthday != 40 || client.currentHeight != 39 {
+		t.Fatalf("wallet syncs from height %d, birthday %d after import", client.currentHeight+1, client.birthday)
+	}
+
+	// A key born after the wallet but below its height syncs the blocks since
+	client.currentHeight = 80
+	if _, err := client.CreateAccountByPrivateKey(testKey("between"), 70); err != nil {
+		t.Fatal(err)
+	}
+	if client.birthday != 40 || client.currentHeight != 69 {
+		t.Fatalf("wallet syncs from height %d, birthday %d after import", client.currentHeight+1, client.birthday)
+	}
+	client.currentHeight = 60
+	if err := client.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if client, err = Open(name, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+	if client.birthday != 40 || client.currentHeight != 39 {
+		t.Errorf("rebuilt wallet syncs from height %d, birthday %d", client.currentHeight+1, client.birthday)
+	}
+
+	// Syncing a block again does not add its coins twice
+	txn, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{Value: 10, ProgramHash: key.ProgramHash},
+	})
+	block := &ledger.Block{
+		Blockdata:    &ledger.Blockdata{Height: 40},
+		Transactions: []*transaction.Transaction{txn},
+	}
+	client.ProcessOneBlock(block)
+	client.ProcessOneBlock(block)
+	if coins := client.GetCoins(); len(coins) != 1 {
+		t.Errorf("%d coins after syncing a block twice", len(coins))
+	}
+}
@@ -12,7 +12,7 @@ type IClientStore interface {
 	LoadStoredData(name string) []byte
 
 	CreateAccount() (*Account, error)
-	CreateAccountByPrivateKey(privateKey []byte) (*Account, error)
+	CreateAccountByPrivateKey(privateKey []byte, birthday uint32) (*Account, error)
 	LoadAccounts() map[Uint160]*Account
 
 	CreateContract(account *Account) error
@@ -0,0 +1,191 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	. "DNA_POW/common"
+	"DNA_POW/crypto"
+
+	"github.com/golang/crypto/scrypt"
+)
+
+// KDFScrypt is the memory hard key derivation function of the wallets
+const KDFScrypt = "scrypt"
+
+// DefaultKDF is the cost of the key derivation of the wallets created or
+// given a new password, which takes 32MB of memory by default. A wallet keeps
+// the cost it is created with.
+var DefaultKDF = KDFData{
+	Name: KDFScrypt,
+	N:    1 << 15,
+	R:    8,
+	P:    1,
+}
+
+const kdfSaltLen = 32
+
+// The bounds of the scrypt parameters read from a file, so a file can neither
+// weaken the key derivation nor make it take all the memory of the node.
+const (
+	kdfMinN      = 1 << 10
+	kdfMaxN      = 1 << 20
+	kdfMaxR      = 32
+	kdfMaxP      = 16
+	kdfMaxMemory = 1 << 30 // 128 * N * R bytes
+)
+
+// the suffix of the backup of a wallet of version 1 while it is migrated
+const walletBackupV1 = ".v1"
+
+// the suffix of the file marking the wallet of the path as of version 2 or
+// later, a wallet file of version 1 at the path is refused from then on
+const walletVersionMark = ".version"
+
+// checkKDF checks the parameters of the key derivation are in bounds
+func checkKDF(kdf *KDFData) error {
+	if kdf.Name != KDFScrypt {
+		return fmt.Errorf("unsupported key derivation function %q", kdf.Name)
+	}
+	if kdf.N < kdfMinN || kdf.N > kdfMaxN || kdf.N&(kdf.N-1) != 0 {
+		return fmt.Errorf("invalid scrypt N %d", kdf.N)
+	}
+	if kdf.R < 1 || kdf.R > kdfMaxR || 128*int64(kdf.N)*int64(kdf.R) > kdfMaxMemory {
+		return fmt.Errorf("invalid scrypt r %d", kdf.R)
+	}
+	if kdf.P < 1 || kdf.P > kdfMaxP {
+		return fmt.Errorf("invalid scrypt p %d", kdf.P)
+	}
+	return nil
+}
+
+// deriveKeys returns the key encrypting the master key and the key of the MAC
+// of the wallet file derived from the password.
+func deriveKeys(password []byte, kdf *KDFData) ([]byte, []byte, error) {
+	if err := checkKDF(kdf); err != nil {
+		return nil, nil, err
+	}
+	salt, err := HexStringToBytes(kdf.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, nil, errors.New("invalid key derivation salt")
+	}
+	key, err := scrypt.Key(password, salt, kdf.N, kdf.R, kdf.P, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key[:32], key[32:], nil
+}
+
+// setPassword derives the keys of the password with a new salt and saves the
+// master key encrypted with them, which makes the wallet the current version.
+func (cl *ClientImpl) setPassword(password []byte) error {
+	salt := make([]byte, kdfSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kdf := DefaultKDF
+	kdf.Salt = BytesToHexString(salt)
+	passwordKey, macKey, err := deriveKeys(password, &kdf)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+
+	masterKey, err := crypto.AesEncrypt(cl.masterKey, passwordKey, cl.iv)
+	if err != nil {
+		return err
+	}
+	passwordHash := sha256.Sum256(passwordKey)
+
+	if err := cl.SaveKDFData(&kdf, passwordHash[:], masterKey, macKey); err != nil {
+		return err
+	}
+	return cl.markVersion()
+}
+
+// markVersion marks the wallet of the path as of the current version unless
+// it is marked already.
+func (cl *ClientImpl) markVersion() error {
+	if _, err := os.Stat(cl.path + walletVersionMark); err == nil {
+		return nil
+	}
+	return ioutil.WriteFile(cl.path+walletVersionMark, []byte(WalletStoreVersion), 0600)
+}
+
+// checkPassword verifies the password and the MAC of the wallet file, and
+// returns the password key. A wallet of version 1 has its password key of two
+// sha256 and no MAC, it is refused once the wallet is of a later version.
+func (cl *ClientImpl) checkPassword(password []byte) ([]byte, error) {
+	kdf, err := cl.LoadKDFData()
+	if err != nil {
+		return nil, err
+	}
+	if kdf == nil {
+		if _, err := os.Stat(cl.path + walletVersionMark); err == nil {
+			return nil, errors.New("the wallet of version 1 replaces a wallet of a later version")
+		}
+		passwordKey := crypto.ToAesKey(password)
+		if !cl.verifyPasswordKey(passwordKey) {
+			return nil, errors.New("password verification failed")
+		}
+		return passwordKey, nil
+	}
+
+	passwordKey, macKey, err := deriveKeys(password, kdf)
+	if err != nil {
+		return nil, err
+	}
+	if !cl.verifyPasswordKey(passwordKey) {
+		return nil, errors.New("password verification failed")
+	}
+	if err := cl.VerifyDB(macKey); err != nil {
+		return nil, err
+	}
+	return passwordKey, nil
+}
+
+// unlock verifies the password and decrypts the master key. A wallet of
+// version 1 is migrated to the current version, the old file is kept with the
+// suffix .v1 until the migration is done.
+func (cl *ClientImpl) unlock(password []byte) error {
+	passwordKey, err := cl.checkPassword(password)
+	if err != nil {
+		return err
+	}
+	defer ClearBytes(passwordKey, len(passwordKey))
+
+	cl.iv, err = cl.LoadStoredData("IV")
+	if err != nil {
+		return errors.New("failed to load iv")
+	}
+	encryptedMasterKey, err := cl.LoadStoredData("MasterKey")
+	if err != nil {
+		return errors.New("failed to load master key")
+	}
+	cl.masterKey, err = crypto.AesDecrypt(encryptedMasterKey, passwordKey, cl.iv)
+	if err != nil {
+		return errors.New("failed to decrypt master key")
+	}
+
+	if cl.macKey == nil {
+		data, err := cl.readDB()
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(cl.path+walletBackupV1, data, 0600); err != nil {
+			return fmt.Errorf("failed to back up the wallet before migration: %v", err)
+		}
+		if err := cl.setPassword(password); err != nil {
+			return fmt.Errorf("failed to migrate the wallet: %v", err)
+		}
+		// the backup holds the master key under the weaker password key
+		if err := os.Remove(cl.path + walletBackupV1); err != nil {
+			return fmt.Errorf("failed to remove the backup of the migrated wallet: %v", err)
+		}
+	}
+	return cl.markVersion()
+}
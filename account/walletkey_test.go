@@ -0,0 +1,160 @@
+package account
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/crypto"
+)
+
+func TestWalletKey(t *testing.T) {
+	client, name, cleanup := newTestWallet(t)
+	defer cleanup()
+	main, _ := client.GetDefaultAccount()
+	raw, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data FileData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Version != WalletStoreVersion || data.KDF == nil || data.KDF.Name != KDFScrypt || data.MAC == "" {
+		t.Fatalf("unexpected wallet version %s, kdf %v", data.Version, data.KDF)
+	}
+
+	if _, err := Open(name, []byte("wrong")); err == nil {
+		t.Error("opened with a wrong password")
+	}
+	if !client.ChangePassword([]byte("password"), []byte("new password")) {
+		t.Fatal("password is not changed")
+	}
+	if _, err := Open(name, []byte("new password")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wallet file changed by any but the holder of the password is refused
+	raw, _ = ioutil.ReadFile(name)
+	tampered := bytes.Replace(raw, []byte(`"GapLimit":0`), []byte(`"GapLimit":5`), 1)
+	if bytes.Equal(tampered, raw) {
+		t.Fatal("wallet file is not tampered")
+	}
+	if err := ioutil.WriteFile(name, tampered, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(name, []byte("new password")); err == nil {
+		t.Error("opened a tampered wallet")
+	}
+	ioutil.WriteFile(name, raw, 0600)
+
+	// A wallet written by a version without some fields is opened
+	older := bytes.Replace(raw, []byte(`,"PaymentRequests":null`), nil, 1)
+	if bytes.Equal(older, raw) {
+		t.Fatal("wallet file has no field to drop")
+	}
+	json.Unmarshal(raw, &data)
+	older = bytes.Replace(older, macField(data.MAC), macField(""), 1)
+	mac := BytesToHexString(fileMAC(client.macKey, older))
+	older = bytes.Replace(older, macField(""), macField(mac), 1)
+	if err := ioutil.WriteFile(name, older, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(name, []byte("new password")); err != nil {
+		t.Errorf("wallet of an older field set not opened: %v", err)
+	}
+	ioutil.WriteFile(name, raw, 0600)
+
+	// A wallet file changed while the wallet is open is neither saved over
+	// nor loaded
+	opened, err := Open(name, []byte("new password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, tampered, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := opened.SaveStoredData("Height", []byte{1, 0, 0, 0}); err == nil {
+		t.Error("saved over a tampered wallet file")
+	}
+	if _, err := opened.LoadStoredData("Height"); err == nil {
+		t.Error("loaded from a tampered wallet file")
+	}
+	if after, _ := ioutil.ReadFile(name); !bytes.Equal(after, tampered) {
+		t.Error("tampered wallet file is saved over")
+	}
+	ioutil.WriteFile(name, raw, 0600)
+
+	// A wallet of version 1 never upgraded is migrated on opening
+	if err := os.Remove(name + walletVersionMark); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(raw, &data)
+	passwordKey := crypto.ToAesKey([]byte("password"))
+	passwordHash := sha256.Sum256(passwordKey)
+	masterKey, _ := crypto.AesEncrypt(client.masterKey, passwordKey, client.iv)
+	data.Version = WalletStoreVersionV1
+	data.KDF = nil
+	data.MAC = ""
+	data.PasswordHash = BytesToHexString(passwordHash[:])
+	data.MasterKey = BytesToHexString(masterKey)
+	v1, _ := json.Marshal(data)
+	if err := ioutil.WriteFile(name, v1, 0600); err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account, _ := migrated.GetDefaultAccount(); account == nil || account.ProgramHash != main.ProgramHash {
+		t.Fatal("accounts are not loaded from the wallet of version 1")
+	}
+	if _, err := os.Stat(name + walletBackupV1); !os.IsNotExist(err) {
+		t.Errorf("backup of the migrated wallet is kept: %v", err)
+	}
+	raw, _ = ioutil.ReadFile(name)
+	json.Unmarshal(raw, &data)
+	if data.Version != WalletStoreVersion || data.KDF == nil || data.MAC == "" {
+		t.Error("wallet of version 1 is not migrated")
+	}
+	if _, err := Open(name, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once upgraded, a wallet file of version 1 is refused
+	if err := ioutil.WriteFile(name, v1, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(name, []byte("password")); err == nil {
+		t.Error("opened a wallet of version 1 after the upgrade")
+	}
+}
+
+func TestKDFBounds(t *testing.T) {
+	salt := BytesToHexString(make([]byte, kdfSaltLen))
+	tests := []struct {
+		kdf   KDFData
+		valid bool
+	}{
+		{DefaultKDF, true},
+		{KDFData{Name: "pbkdf2", N: 1 << 15, R: 8, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 9, R: 8, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1<<15 + 1, R: 8, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 21, R: 8, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 15, R: 0, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 20, R: 16, P: 1}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 15, R: 8, P: 0}, false},
+		{KDFData{Name: KDFScrypt, N: 1 << 15, R: 8, P: 1 << 30}, false},
+	}
+	for _, test := range tests {
+		kdf := test.kdf
+		kdf.Salt = salt
+		if _, _, err := deriveKeys([]byte("password"), &kdf); (err == nil) != test.valid {
+			t.Errorf("kdf %+v: got error %v", test.kdf, err)
+		}
+	}
+}
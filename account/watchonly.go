@@ -0,0 +1,177 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+
+	. "DNA_POW/common"
+	ct "DNA_POW/core/contract"
+	"DNA_POW/crypto"
+	"DNA_POW/vm"
+)
+
+// WatchOnlyAccount is an address whose coins the wallet tracks without holding
+// its private keys. The contract is nil when only the program hash is known.
+type WatchOnlyAccount struct {
+	ProgramHash Uint160
+	Contract    *ct.Contract
+}
+
+// NewScriptContract creates the contract of a signature or multisig redeem
+// script.
+func NewScriptContract(code []byte) (*ct.Contract, error) {
+	contract := &ct.Contract{Code: code}
+	switch {
+	case contract.IsStandard():
+		contract.Parameters = []ct.ContractParameterType{ct.Signature}
+	case contract.IsMultiSigContract():
+		// the script starts with the number of signatures pushed
+		m := int(code[0]) - int(vm.PUSH1) + 1
+		if code[0] == 1 {
+			m = int(code[1])
+		}
+		contract.Parameters = make([]ct.ContractParameterType, m)
+		for i := range contract.Parameters {
+			contract.Parameters[i] = ct.Signature
+		}
+	default:
+		return nil, errors.New("neither a signature nor a multisig script")
+	}
+	programHash, err := ToCodeHash(code)
+	if err != nil {
+		return nil, err
+	}
+	contract.ProgramHash = programHash
+
+	return contract, nil
+}
+
+// ParseWatchOnly parses an address, a public key or a redeem script in hex to
+// the program hash to watch and its contract if known.
+func ParseWatchOnly(s string) (Uint160, *ct.Contract, error) {
+	if data, err := HexStringToBytes(s); err == nil && len(data) > 0 {
+		if pubKey, err := crypto.DecodePoint(data); err == nil {
+			contract, err := ct.CreateSignatureContract(pubKey)
+			if err != nil {
+				return Uint160{}, nil, err
+			}
+			return contract.ProgramHash, contract, nil
+		}
+		if contract, err := NewScriptContract(data); err == nil {
+			return contract.ProgramHash, contract, nil
+		}
+	}
+	programHash, err := ToScriptHash(s)
+	if err != nil {
+		return Uint160{}, nil, errors.New("neither an address, a public key nor a redeem script")
+	}
+	return programHash, nil, nil
+}
+
+// addressType returns the type of the coins paid to the program hash, false
+// when the wallet doesn't track it. The caller holds the client lock.
+func (cl *ClientImpl) addressType(programHash Uint160) (AddressType, bool) {
+	if contract, ok := cl.contracts[programHash]; ok {
+		switch {
+		case contract.IsStandard():
+			return SingleSign, true
+		case contract.IsMultiSigContract():
+			return MultiSign, true
+		}
+		return 0, false
+	}
+	if _, ok := cl.watchOnly[programHash]; ok {
+		return WatchOnly, true
+	}
+	return 0, false
+}
+
+// AddWatchOnly adds a watch-only address whose coins are received since the
+// birthday. The wallet syncs again from the birthday if it is below the
+// wallet height to find the coins received before.
+func (cl *ClientImpl) AddWatchOnly(programHash Uint160, contract *ct.Contract, birthday uint32) error {
+	if err := cl.addWatchOnly(programHash, contract); err != nil {
+		return err
+	}
+	return cl.lowerBirthday(birthday)
+}
+
+// addWatchOnly saves the watch-only address unless the wallet has it
+func (cl *ClientImpl) addWatchOnly(programHash Uint160, contract *ct.Contract) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if _, ok := cl.contracts[programHash]; ok {
+		return errors.New("the address belongs to the wallet")
+	}
+	if _, ok := cl.watchOnly[programHash]; ok {
+		return errors.New("the address is watched already")
+	}
+	if err := cl.SaveWatchOnlyData(programHash, contract); err != nil {
+		return err
+	}
+	cl.watchOnly[programHash] = &WatchOnlyAccount{ProgramHash: programHash, Contract: contract}
+
+	return nil
+}
+
+// DeleteWatchOnly removes a watch-only address and its coins
+func (cl *ClientImpl) DeleteWatchOnly(programHash Uint160) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if _, ok := cl.watchOnly[programHash]; !ok {
+		return errors.New("the address is not watched")
+	}
+	delete(cl.watchOnly, programHash)
+	if err := cl.DeleteWatchOnlyData(BytesToHexString(programHash.ToArray())); err != nil {
+		return err
+	}
+	for input, coin := range cl.coins {
+		if coin.Output.ProgramHash == programHash {
+			delete(cl.coins, input)
+		}
+	}
+	return cl.DeleteCoinsData(programHash)
+}
+
+// GetWatchOnly returns the watch-only addresses of the wallet
+func (cl *ClientImpl) GetWatchOnly() []*WatchOnlyAccount {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	watchOnly := make([]*WatchOnlyAccount, 0, len(cl.watchOnly))
+	for _, w := range cl.watchOnly {
+		watchOnly = append(watchOnly, w)
+	}
+	return watchOnly
+}
+
+// LoadWatchOnly loads the watch-only addresses from db to memory
+func (cl *ClientImpl) LoadWatchOnly() error {
+	data, err := cl.LoadWatchOnlyData()
+	if err != nil {
+		return err
+	}
+	watchOnly := map[Uint160]*WatchOnlyAccount{}
+	for _, w := range data {
+		p, _ := HexStringToBytes(w.ProgramHash)
+		programHash, err := Uint160ParseFromBytes(p)
+		if err != nil {
+			return err
+		}
+		account := &WatchOnlyAccount{ProgramHash: programHash}
+		if w.RawData != "" {
+			rawdata, _ := HexStringToBytes(w.RawData)
+			account.Contract = new(ct.Contract)
+			if err := account.Contract.Deserialize(bytes.NewReader(rawdata)); err != nil {
+				return err
+			}
+			account.Contract.ProgramHash = programHash
+		}
+		watchOnly[programHash] = account
+	}
+
+	cl.watchOnly = watchOnly
+	return nil
+}
@@ -0,0 +1,91 @@
+package account
+
+import (
+	"testing"
+
+	. "DNA_POW/common"
+	ct "DNA_POW/core/contract"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+)
+
+func TestWatchOnly(t *testing.T) {
+	client, name, cleanup := newTestWallet(t)
+	defer cleanup()
+
+	var keys []*crypto.PubKey
+	for i := 0; i < 3; i++ {
+		_, pubKey, _ := crypto.GenKeyPair()
+		keys = append(keys, &pubKey)
+	}
+	encoded, _ := keys[0].EncodePoint(true)
+	single, contract, err := ParseWatchOnly(BytesToHexString(encoded))
+	if err != nil || contract == nil || !contract.IsStandard() {
+		t.Fatalf("public key not parsed: %v", err)
+	}
+	multisig, err := ct.CreateMultiSigContract(Uint160{}, 2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contract, err = ParseWatchOnly(BytesToHexString(multisig.Code))
+	if err != nil || contract.ProgramHash != multisig.ProgramHash || len(contract.Parameters) != 2 {
+		t.Fatalf("multisig script not parsed: %v", err)
+	}
+	address, _ := multisig.ProgramHash.ToAddress()
+	programHash, contract, err := ParseWatchOnly(address)
+	if err != nil || contract != nil || programHash != multisig.ProgramHash {
+		t.Fatalf("address not parsed: %v", err)
+	}
+
+	if err := client.AddWatchOnly(single, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddWatchOnly(single, nil, 0); err == nil {
+		t.Error("address watched twice")
+	}
+	if err := client.AddWatchOnly(client.mainAccount, nil, 0); err == nil {
+		t.Error("wallet account added as watch-only")
+	}
+
+	// The coins of the watch-only address are tracked apart from the spendable ones
+	txn, _ := transaction.NewTransferAssetTransaction(nil, []*transaction.TxOutput{
+		{Value: 10, ProgramHash: single},
+		{Value: 20, ProgramHash: client.mainAccount},
+	})
+	client.ProcessOneBlock(&ledger.Block{
+		Blockdata:    &ledger.Blockdata{Height: 1},
+		Transactions: []*transaction.Transaction{txn},
+	})
+	types := map[Uint160]AddressType{}
+	for _, coin := range client.GetCoins() {
+		types[coin.Output.ProgramHash] = coin.AddressType
+	}
+	if len(types) != 2 || types[single] != WatchOnly || types[client.mainAccount] != SingleSign {
+		t.Fatalf("unexpected coins %v", types)
+	}
+
+	client, err = Open(name, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watchOnly := client.GetWatchOnly(); len(watchOnly) != 1 || watchOnly[0].ProgramHash != single {
+		t.Fatal("watch-only address is not loaded")
+	}
+	if err := client.DeleteWatchOnly(single); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.GetWatchOnly()) != 0 || len(client.GetCoins()) != 1 {
+		t.Error("watch-only address or its coins are not deleted")
+	}
+
+	// An address watched after the wallet synced past its coins syncs again
+	client.birthday = 10
+	client.currentHeight = 50
+	if err := client.AddWatchOnly(multisig.ProgramHash, multisig, 20); err != nil {
+		t.Fatal(err)
+	}
+	if client.birthday != 10 || client.currentHeight != 19 {
+		t.Errorf("wallet syncs from height %d, birthday %d after watching", client.currentHeight+1, client.birthday)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 
 	. "DNA_POW/cli/common"
+	. "DNA_POW/common"
 	"DNA_POW/net/httpjsonrpc"
+	"DNA_POW/sdk"
 
 	"github.com/urfave/cli"
 )
@@ -19,6 +21,9 @@ func assetAction(c *cli.Context) error {
 		fmt.Println("missing flag [--transfer]")
 		return nil
 	}
+	if uri := c.String("uri"); uri != "" {
+		return payURI(c, uri)
+	}
 	asset := c.String("asset")
 	if asset == "" {
 		fmt.Println("missing flag [--asset]")
@@ -39,7 +44,52 @@ func assetAction(c *cli.Context) error {
 		fmt.Println("transaction fee is required with [--fee]")
 		return nil
 	}
-	resp, err := httpjsonrpc.Call(Address(), "sendtoaddress", 0, []interface{}{asset, address, value,fee})
+	var resp []byte
+	var err error
+	if from := c.String("from"); from != "" {
+		// spending from a watch-only address returns the unsigned transaction
+		resp, err = httpjsonrpc.Call(Address(), "createwatchonlytransaction", 0, []interface{}{asset, from, address, value, fee})
+	} else if selection, pinned, locked := c.String("selection"), c.StringSlice("pin"), c.StringSlice("lock"); selection != "" || len(pinned) > 0 || len(locked) > 0 {
+		resp, err = httpjsonrpc.Call(Address(), "sendtoaddress", 0, []interface{}{asset, address, value, fee, selection, pinned, locked})
+	} else {
+		resp, err = httpjsonrpc.Call(Address(), "sendtoaddress", 0, []interface{}{asset, address, value,fee})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+
+	return nil
+}
+
+// payURI pays a payment URI, the flags give the amount and the asset when the
+// URI has none
+func payURI(c *cli.Context, uri string) error {
+	payment, err := sdk.ParsePaymentURI(uri)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	value := c.String("value")
+	if payment.Amount == 0 && value == "" {
+		fmt.Println("asset amount is required with [--value], the payment URI has none")
+		return nil
+	}
+	asset := c.String("asset")
+	if payment.AssetID == (Uint256{}) && asset == "" {
+		fmt.Println("missing flag [--asset], the payment URI has none")
+		return nil
+	}
+	fee := c.String("fee")
+	if fee == "" {
+		fmt.Println("transaction fee is required with [--fee]")
+		return nil
+	}
+	if payment.Label != "" {
+		fmt.Printf("Paying %s (%s)\n", payment.Address, payment.Label)
+	}
+	resp, err := httpjsonrpc.Call(Address(), "sendtouri", 0, []interface{}{uri, fee, value, asset})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return err
@@ -66,7 +116,15 @@ func NewCommand() *cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "to",
-				Usage: "asset to whom",
+				Usage: "asset to whom, an address or a name of the address book",
+			},
+			cli.StringFlag{
+				Name:  "uri, u",
+				Usage: "payment URI to pay, dna:<address>?asset=<asset id>&amount=<value>&label=<label>&id=<id>",
+			},
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "watch-only address to spend from, returns the unsigned transaction",
 			},
 			cli.StringFlag{
 				Name:  "value, v",
@@ -78,6 +136,18 @@ func NewCommand() *cli.Command {
 				Usage: "transaction fee",
 				Value: "",
 			},
+			cli.StringFlag{
+				Name:  "selection, s",
+				Usage: "coin selection [smallest, largest, exact, oldest]",
+			},
+			cli.StringSliceFlag{
+				Name:  "pin",
+				Usage: "coin txid:index to spend, repeatable",
+			},
+			cli.StringSliceFlag{
+				Name:  "lock",
+				Usage: "coin txid:index never to spend, repeatable",
+			},
 		},
 		Action: assetAction,
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
@@ -0,0 +1,68 @@
+package consolidate
+
+import (
+	"fmt"
+	"os"
+
+	. "DNA_POW/cli/common"
+	"DNA_POW/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+func consolidateAction(c *cli.Context) error {
+	if c.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+	asset := c.String("asset")
+	if asset == "" {
+		fmt.Println("missing flag [--asset]")
+		return nil
+	}
+	feeCap := c.String("feecap")
+	if feeCap == "" {
+		fmt.Println("fee cap is required with [--feecap]")
+		return nil
+	}
+	params := []interface{}{asset, feeCap}
+	if dust := c.String("dust"); dust != "" {
+		params = append(params, dust)
+	}
+	resp, err := httpjsonrpc.Call(Address(), "consolidate", 0, params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+
+	return nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "consolidate",
+		Usage:       "merge small coins of the wallet",
+		Description: "With nodectl consolidate, you merge the dust coins of an asset to the main account, the oldest first.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "asset, a",
+				Usage: "uniq id for asset",
+			},
+			cli.StringFlag{
+				Name:  "dust, d",
+				Usage: "merge the coins worth less than it, all coins if not given",
+			},
+			cli.StringFlag{
+				Name:  "feecap, f",
+				Usage: "the maximum transaction fee",
+			},
+		},
+		Action: consolidateAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "consolidate")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -24,6 +24,7 @@ func infoAction(c *cli.Context) (err error) {
 	neighbor := c.Bool("neighbor")
 	state := c.Bool("state")
 	version := c.Bool("nodeversion")
+	rejects := c.Bool("rejects")
 
 	var resp []byte
 	var output [][]byte
@@ -90,6 +91,15 @@ func infoAction(c *cli.Context) (err error) {
 		output = append(output, resp)
 	}
 
+	if rejects {
+		resp, err := httpjsonrpc.Call(Address(), "getrejects", 0, []interface{}{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return err
+		}
+		output = append(output, resp)
+	}
+
 	if txhash != "" {
 		resp, err = httpjsonrpc.Call(Address(), "getrawtransaction", 0, []interface{}{txhash})
 		if err != nil {
@@ -159,6 +169,10 @@ func NewCommand() *cli.Command {
 				Name:  "nodeversion, v",
 				Usage: "version of connected remote node",
 			},
+			cli.BoolFlag{
+				Name:  "rejects",
+				Usage: "reject messages received from neighbors",
+			},
 		},
 		Action: infoAction,
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
@@ -16,6 +16,12 @@ func miningAction(c *cli.Context) (err error) {
 
 	toggle := c.Bool("toggle")
 	discrete := c.Bool("discrete")
+	if c.Bool("info") {
+		resp, _ := httpjsonrpc.Call(Address(), "getmininginfo", 0, []interface{}{})
+		FormatOutput(resp)
+		return nil
+	}
+
 	if toggle {
 		control := c.String("control")
 		var isMining bool
@@ -67,6 +73,10 @@ func NewCommand() *cli.Command {
 				Name:  "num, n",
 				Usage: "number of blocks to mine",
 			},
+			cli.BoolFlag{
+				Name:  "info, i",
+				Usage: "show mining status and hashes per second",
+			},
 		},
 		Action: miningAction,
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
@@ -0,0 +1,269 @@
+package partial
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"DNA_POW/account"
+	. "DNA_POW/cli/common"
+	"DNA_POW/common"
+	"DNA_POW/common/password"
+	"DNA_POW/net/httpjsonrpc"
+	"DNA_POW/sdk"
+
+	"github.com/urfave/cli"
+)
+
+// readPartialTransaction parses a partial transaction given in hex or as the
+// name of the file it is saved in.
+func readPartialTransaction(data string) (*sdk.PartialTransaction, error) {
+	if common.FileExisted(data) {
+		content, err := ioutil.ReadFile(data)
+		if err != nil {
+			return nil, err
+		}
+		data = string(content)
+	}
+	return sdk.PartialTransactionFromHexString(strings.TrimSpace(data))
+}
+
+// writePartialTransaction prints the partial transaction, or saves it to the
+// file given with [--out].
+func writePartialTransaction(c *cli.Context, pt *sdk.PartialTransaction) error {
+	data, err := pt.ToHexString()
+	if err != nil {
+		return err
+	}
+	if out := c.String("out"); out != "" {
+		if err := ioutil.WriteFile(out, []byte(data), 0600); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(data)
+	}
+	if missing := pt.Missing(); missing > 0 {
+		fmt.Fprintf(os.Stderr, "%d signatures are missing\n", missing)
+	} else {
+		fmt.Fprintln(os.Stderr, "all signatures collected, finalize it with [--finalize]")
+	}
+	return nil
+}
+
+func getData(c *cli.Context) []string {
+	data := c.StringSlice("data")
+	if len(data) == 0 {
+		fmt.Fprintln(os.Stderr, "partial transaction is required with [--data]")
+		os.Exit(1)
+	}
+	return data
+}
+
+func createPartialTransaction(c *cli.Context) error {
+	asset := c.String("asset")
+	from := c.String("from")
+	to := c.String("to")
+	value := c.String("value")
+	fee := c.String("fee")
+	msg := ""
+	switch {
+	case asset == "":
+		msg = "asset id is required with [--asset]"
+	case from == "":
+		msg = "sender address is required with [--from]"
+	case to == "":
+		msg = "receiver address is required with [--to]"
+	case value == "":
+		msg = "asset amount is required with [--value]"
+	case fee == "":
+		msg = "tranfer fee is required with [--fee]"
+	}
+	if msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(1)
+	}
+	resp, err := httpjsonrpc.Call(Address(), "createpartialtransaction", 0, []interface{}{asset, from, to, value, fee})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+
+	return nil
+}
+
+// signPartialTransaction signs with the wallet file only, so that it works on
+// a machine never connected to a node.
+func signPartialTransaction(c *cli.Context) error {
+	pt, err := readPartialTransaction(getData(c)[0])
+	if err != nil {
+		return err
+	}
+	passwd := []byte(c.String("password"))
+	if len(passwd) == 0 {
+		if passwd, err = password.GetPassword(); err != nil {
+			return err
+		}
+	}
+	wallet, err := account.Open(c.String("wallet"), passwd)
+	if err != nil {
+		return err
+	}
+	count, err := pt.Sign(wallet)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d signatures added\n", count)
+
+	return writePartialTransaction(c, pt)
+}
+
+func combinePartialTransactions(c *cli.Context) error {
+	data := getData(c)
+	pt, err := readPartialTransaction(data[0])
+	if err != nil {
+		return err
+	}
+	for _, d := range data[1:] {
+		other, err := readPartialTransaction(d)
+		if err != nil {
+			return err
+		}
+		if err := pt.Combine(other); err != nil {
+			return err
+		}
+	}
+	return writePartialTransaction(c, pt)
+}
+
+func finalizePartialTransaction(c *cli.Context) error {
+	pt, err := readPartialTransaction(getData(c)[0])
+	if err != nil {
+		return err
+	}
+	txn, err := pt.Finalize()
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	txn.Serialize(&buffer)
+	rawtxn := common.BytesToHexString(buffer.Bytes())
+	if !c.Bool("send") {
+		fmt.Println(rawtxn)
+		return nil
+	}
+	resp, err := httpjsonrpc.Call(Address(), "sendrawtransaction", 0, []interface{}{rawtxn})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+
+	return nil
+}
+
+func partialAction(c *cli.Context) error {
+	if c.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+
+	var err error
+	switch {
+	case c.Bool("create"):
+		err = createPartialTransaction(c)
+	case c.Bool("sign"):
+		err = signPartialTransaction(c)
+	case c.Bool("combine"):
+		err = combinePartialTransactions(c)
+	case c.Bool("finalize"):
+		err = finalizePartialTransaction(c)
+	default:
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "partial",
+		Usage:       "partially signed transaction creation, sign, combination and finalization",
+		Description: "With nodectl partial, you sign transactions apart from the node, even offline.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "create, c",
+				Usage: "create a partial transaction with the node wallet",
+			},
+			cli.BoolFlag{
+				Name:  "sign, s",
+				Usage: "sign a partial transaction with the local wallet file",
+			},
+			cli.BoolFlag{
+				Name:  "combine",
+				Usage: "combine the signatures of partial transactions",
+			},
+			cli.BoolFlag{
+				Name:  "finalize",
+				Usage: "finalize a partial transaction to the raw transaction",
+			},
+			cli.BoolFlag{
+				Name:  "send",
+				Usage: "send the finalized transaction to the node",
+			},
+			cli.StringSliceFlag{
+				Name:  "data, d",
+				Usage: "partial transaction in hex or the file of it, repeated to combine",
+			},
+			cli.StringFlag{
+				Name:  "out, o",
+				Usage: "file to save the partial transaction",
+			},
+			cli.StringFlag{
+				Name:  "wallet, w",
+				Usage: "wallet name",
+				Value: account.WalletFileName,
+			},
+			cli.StringFlag{
+				Name:  "password, p",
+				Usage: "wallet password",
+			},
+			cli.StringFlag{
+				Name:  "asset, a",
+				Usage: "uniq id for asset",
+			},
+			cli.StringFlag{
+				Name:  "from, f",
+				Usage: "asset from which address",
+			},
+			cli.StringFlag{
+				Name:  "to, t",
+				Usage: "asset to which address",
+			},
+			cli.StringFlag{
+				Name:  "value, v",
+				Usage: "asset amount",
+				Value: "",
+			},
+			cli.StringFlag{
+				Name:  "fee",
+				Usage: "transfer fee",
+				Value: "",
+			},
+		},
+		Action: partialAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "partial")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -27,8 +27,13 @@ func recoverAction(c *cli.Context) error {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	birthday := c.Int("birthday")
+	if birthday < 0 {
+		fmt.Println("invalid birthday height")
+		os.Exit(1)
+	}
 	newWalletName := fmt.Sprintf("wallet-%s-recovered.dat", time.Now().Format("2006-01-02-15-04-05"))
-	_, err = account.Recover(newWalletName, []byte(newPassword), privateKey)
+	_, err = account.Recover(newWalletName, []byte(newPassword), privateKey, uint32(birthday))
 	if err != nil {
 		fmt.Println("failed to recover wallet from private key")
 		os.Exit(1)
@@ -46,7 +51,11 @@ func NewCommand() *cli.Command {
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "key, k",
-				Usage: "private key",
+				Usage: "private key, encoded or in hex",
+			},
+			cli.IntFlag{
+				Name:  "birthday, b",
+				Usage: "the height the key received its first coin at or below, the wallet syncs from it",
 			},
 		},
 		Action: recoverAction,
@@ -0,0 +1,74 @@
+package rescan
+
+import (
+	"fmt"
+	"os"
+
+	. "DNA_POW/cli/common"
+	"DNA_POW/net/httpjsonrpc"
+
+	"github.com/urfave/cli"
+)
+
+func rescanAction(c *cli.Context) error {
+	if c.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+	var resp []byte
+	var err error
+	switch {
+	case c.Bool("abort"):
+		resp, err = httpjsonrpc.Call(Address(), "abortrescan", 0, []interface{}{})
+	case c.Bool("progress"):
+		resp, err = httpjsonrpc.Call(Address(), "getrescanprogress", 0, []interface{}{})
+	case c.IsSet("start"):
+		params := []interface{}{c.Int("start")}
+		if c.IsSet("stop") {
+			params = append(params, c.Int("stop"))
+		}
+		resp, err = httpjsonrpc.Call(Address(), "rescanblockchain", 0, params)
+	default:
+		fmt.Println("missing flag [--start]")
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	FormatOutput(resp)
+
+	return nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "rescan",
+		Usage:       "rescan the blockchain for the coins of the wallet",
+		Description: "With nodectl rescan, you find the coins of the wallet in a range of blocks, abort the rescan or show its progress.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "start",
+				Usage: "the height the rescan starts at",
+			},
+			cli.IntFlag{
+				Name:  "stop",
+				Usage: "the height the rescan stops at, the wallet height if not given",
+			},
+			cli.BoolFlag{
+				Name:  "abort",
+				Usage: "abort the rescan in progress",
+			},
+			cli.BoolFlag{
+				Name:  "progress",
+				Usage: "show the progress of the rescan",
+			},
+		},
+		Action: rescanAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			PrintError(c, err, "rescan")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -6,10 +6,14 @@ import (
 	. "DNA_POW/common"
 	"DNA_POW/common/password"
 	"DNA_POW/crypto"
+	"DNA_POW/sdk"
+	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli"
 )
@@ -133,6 +137,141 @@ func showVerboseInfo(wallet account.Client) {
 	}
 }
 
+// showUnspentInfo lists the coins of the wallet with the outpoints to pin or
+// lock in a transfer.
+func showUnspentInfo(wallet account.Client) {
+	coins := wallet.GetCoins()
+	if len(coins) == 0 {
+		fmt.Println("no coins")
+		return
+	}
+	fmt.Println(" Height  Outpoint  Asset ID  Amount")
+	fmt.Println("-------  --------  --------  ------")
+	for input, coin := range coins {
+		watchOnly := ""
+		if coin.AddressType == account.WatchOnly {
+			watchOnly = "  (watch-only)"
+		}
+		fmt.Printf("%7d  %s  %s  %v%s\n", coin.ReceivedHeight, sdk.FormatOutpoint(input),
+			BytesToHexString(coin.Output.AssetID.ToArrayReverse()), coin.Output.Value, watchOnly)
+	}
+}
+
+func showHistoryInfo(wallet account.Client) {
+	history := wallet.GetHistory()
+	if len(history) == 0 {
+		fmt.Println("no transactions")
+		return
+	}
+	labels := wallet.GetAddressLabels()
+	for _, record := range history {
+		fmt.Println("-----------------------------------------------------------------------------------")
+		fmt.Printf("TxID: %s  (%s)\n", BytesToHexString(record.TxID.ToArrayReverse()), record.Category)
+		if record.Label != "" {
+			fmt.Printf("Label: %s\n", record.Label)
+		}
+		if record.Confirmed() {
+			fmt.Printf("Block: %s  Height: %d  Confirmations: %d\n",
+				BytesToHexString(record.BlockHash.ToArrayReverse()), record.Height, record.Confirmations)
+		} else {
+			fmt.Println("Block: unconfirmed")
+		}
+		for _, programHash := range record.ProgramHashes {
+			address, _ := programHash.ToAddress()
+			if label := labels[programHash]; label != "" {
+				address += "  (" + label + ")"
+			}
+			fmt.Printf("Address: %s\n", address)
+		}
+		for assetID, value := range record.Amounts {
+			fmt.Printf("Amount: %v  %s\n", value, BytesToHexString(assetID.ToArrayReverse()))
+		}
+		if record.Fee > 0 {
+			fmt.Printf("Fee: %v\n", record.Fee)
+		}
+	}
+}
+
+func showContactsInfo(wallet account.Client) {
+	contacts := wallet.GetContacts()
+	if len(contacts) == 0 {
+		fmt.Println("no contacts")
+		return
+	}
+	names := make([]string, 0, len(contacts))
+	for name := range contacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println(" Address\t\t\t\t Name")
+	fmt.Println(" -------\t\t\t\t ----")
+	for _, name := range names {
+		programHash := contacts[name]
+		address, _ := programHash.ToAddress()
+		fmt.Printf(" %s %s\n", address, name)
+	}
+}
+
+func showPaymentRequestsInfo(wallet account.Client) {
+	requests := wallet.GetPaymentRequests()
+	if len(requests) == 0 {
+		fmt.Println("no payment requests")
+		return
+	}
+	for _, request := range requests {
+		address, _ := request.ProgramHash.ToAddress()
+		uri := sdk.PaymentURI{
+			Address: address,
+			AssetID: request.AssetID,
+			Amount:  request.Amount,
+			Label:   request.Label,
+			ID:      request.ID,
+		}
+		fmt.Println("-----------------------------------------------------------------------------------")
+		fmt.Printf("ID: %s  Created: %s\n", request.ID, time.Unix(request.Time, 0).Format(time.RFC3339))
+		fmt.Printf("URI: %s\n", uri.String())
+		if request.Paid() {
+			fmt.Printf("Paid by: %s\n", BytesToHexString(request.PaidTxID.ToArrayReverse()))
+		} else {
+			fmt.Println("Paid by: unpaid")
+		}
+	}
+}
+
+func showWatchOnlyInfo(wallet account.Client) {
+	watchOnly := wallet.GetWatchOnly()
+	coins := wallet.GetCoins()
+	if len(watchOnly) == 0 {
+		fmt.Println("no watch-only address")
+		return
+	}
+
+	for _, w := range watchOnly {
+		assets := make(map[Uint256]Fixed64)
+		for _, out := range coins {
+			if out.AddressType == account.WatchOnly && out.Output.ProgramHash == w.ProgramHash {
+				assets[out.Output.AssetID] += out.Output.Value
+			}
+		}
+		address, _ := w.ProgramHash.ToAddress()
+		fmt.Println("-----------------------------------------------------------------------------------")
+		fmt.Printf("Address: %s\n", address)
+		if w.Contract != nil {
+			fmt.Printf("Script: %s\n", BytesToHexString(w.Contract.Code))
+		}
+		if len(assets) == 0 {
+			continue
+		}
+		fmt.Println(" ID   Asset ID\t\t\t\t\t\t\t\tAmount")
+		fmt.Println("----  --------\t\t\t\t\t\t\t\t------")
+		i := 0
+		for id, amount := range assets {
+			fmt.Printf("%4s  %s  %v\n", strconv.Itoa(i), BytesToHexString(id.ToArrayReverse()), amount)
+			i++
+		}
+	}
+}
+
 func getPassword(passwd string) []byte {
 	var tmp []byte
 	var err error
@@ -163,6 +302,18 @@ func getConfirmedPassword(passwd string) []byte {
 	return tmp
 }
 
+// readSecret reads a mnemonic sentence or a private key from the standard
+// input rather than the command line, which would keep it in the shell history.
+func readSecret(prompt string) string {
+	fmt.Print(prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return strings.TrimSpace(line)
+}
+
 func walletAction(c *cli.Context) error {
 	if c.NumFlags() == 0 {
 		cli.ShowSubcommandHelp(c)
@@ -174,12 +325,30 @@ func walletAction(c *cli.Context) error {
 		os.Exit(1)
 	}
 	passwd := c.String("password")
+	// the cost of the key derivation of a new wallet or password
+	if n := c.Int("kdfcost"); n > 0 {
+		account.DefaultKDF.N = n
+	}
 
 	// create wallet
 	if c.Bool("create") {
 		if FileExisted(name) {
 			fmt.Printf("CAUTION: '%s' already exists!\n", name)
 			os.Exit(1)
+		} else if c.Bool("mnemonic") {
+			mnemonic, err := crypto.NewMnemonic(crypto.MnemonicEntropyBits)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			wallet, err := account.CreateHD(name, getConfirmedPassword(passwd), mnemonic)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Println("Write down the mnemonic below, it recovers all the accounts of the wallet:")
+			fmt.Printf("\n%s\n\n", mnemonic)
+			showAccountsInfo(wallet)
 		} else {
 			wallet, err := account.Create(name, getConfirmedPassword(passwd))
 			if err != nil {
@@ -191,10 +360,41 @@ func walletAction(c *cli.Context) error {
 		return nil
 	}
 
+	// recover wallet from mnemonic
+	if c.Bool("recover") {
+		if !c.Bool("mnemonic") {
+			fmt.Fprintln(os.Stderr, "--recover requires --mnemonic, use nodectl recover for a private key")
+			os.Exit(1)
+		}
+		if FileExisted(name) {
+			fmt.Printf("CAUTION: '%s' already exists!\n", name)
+			os.Exit(1)
+		}
+		gapLimit := c.Int("gaplimit")
+		if gapLimit <= 0 {
+			fmt.Fprintln(os.Stderr, "--gaplimit must be positive")
+			os.Exit(1)
+		}
+		mnemonic := readSecret("Mnemonic: ")
+		if _, err := crypto.MnemonicToEntropy(mnemonic); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		wallet, err := account.RecoverHD(name, getConfirmedPassword(passwd), mnemonic, uint32(gapLimit))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		showAccountsInfo(wallet)
+		fmt.Println("wallet is recovered, the coins are found while the node syncs the wallet")
+		return nil
+	}
+
 	// list wallet info
 	if item := c.String("list"); item != "" {
-		if item != "account" && item != "balance" && item != "verbose" && item != "multisig" {
-			fmt.Fprintln(os.Stderr, "--list [account | balance | verbose | multisig]")
+		if item != "account" && item != "balance" && item != "verbose" && item != "multisig" && item != "watchonly" && item != "unspent" &&
+			item != "history" && item != "contacts" && item != "requests" {
+			fmt.Fprintln(os.Stderr, "--list [account | balance | verbose | multisig | watchonly | unspent | history | contacts | requests]")
 			os.Exit(1)
 		} else {
 			wallet, err := account.Open(name, getPassword(passwd))
@@ -211,6 +411,16 @@ func walletAction(c *cli.Context) error {
 				showVerboseInfo(wallet)
 			case "multisig":
 				showMultisigInfo(wallet)
+			case "watchonly":
+				showWatchOnlyInfo(wallet)
+			case "unspent":
+				showUnspentInfo(wallet)
+			case "history":
+				showHistoryInfo(wallet)
+			case "contacts":
+				showContactsInfo(wallet)
+			case "requests":
+				showPaymentRequestsInfo(wallet)
 			}
 		}
 		return nil
@@ -259,6 +469,155 @@ func walletAction(c *cli.Context) error {
 		return nil
 	}
 
+	// add watch-only address
+	if watch := c.String("addwatchonly"); watch != "" {
+		height := c.Int("height")
+		if height < 0 {
+			height = 0
+		}
+		programHash, contract, err := account.ParseWatchOnly(watch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid watch-only address:", err)
+			os.Exit(1)
+		}
+		wallet, err := account.Open(name, getPassword(passwd))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := wallet.AddWatchOnly(programHash, contract, uint32(height)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		address, _ := programHash.ToAddress()
+		fmt.Printf("watch-only address %s added, the wallet syncs again from height %d\n", address, height)
+		return nil
+	}
+
+	// delete watch-only address
+	if address := c.String("deletewatchonly"); address != "" {
+		programHash, err := ToScriptHash(address)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid address")
+			os.Exit(1)
+		}
+		wallet, err := account.Open(name, getPassword(passwd))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := wallet.DeleteWatchOnly(programHash); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("watch-only address %s deleted\n", address)
+		return nil
+	}
+
+	// export the private key of an address
+	if address := c.String("exportkey"); address != "" {
+		programHash, err := ToScriptHash(address)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid address")
+			os.Exit(1)
+		}
+		password := getPassword(passwd)
+		wallet, err := account.Open(name, password)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		key, err := wallet.ExportPrivateKey(programHash, password)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(key)
+		return nil
+	}
+
+	// import a private key
+	if c.Bool("importkey") {
+		height := c.Int("height")
+		if height < 0 {
+			height = 0
+		}
+		privateKey, err := account.DecodePrivateKey(readSecret("Private key: "))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		wallet, err := account.Open(name, getPassword(passwd))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		ac, err := wallet.ImportPrivateKey(privateKey, uint32(height))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		address, _ := ac.ProgramHash.ToAddress()
+		fmt.Printf("%s imported, the wallet syncs again from height %d\n", address, height)
+		return nil
+	}
+
+	// dump the wallet to an encrypted file
+	if file := c.String("dumpwallet"); file != "" {
+		if FileExisted(file) {
+			fmt.Printf("CAUTION: '%s' already exists!\n", file)
+			os.Exit(1)
+		}
+		wallet, err := account.Open(name, getPassword(passwd))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("# input dump password #")
+		dumpPassword, err := password.GetConfirmedPassword()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := wallet.DumpWallet(file, dumpPassword); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("wallet dumped to %s\n", file)
+		return nil
+	}
+
+	// import a wallet dump
+	if file := c.String("importwallet"); file != "" {
+		fmt.Println("# input dump password #")
+		dumpPassword, err := password.GetPassword()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		dump, err := account.ReadWalletDump(file, dumpPassword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		wallet, err := account.Open(name, getPassword(passwd))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		birthday := dump.Birthday
+		if c.IsSet("height") && c.Int("height") >= 0 {
+			birthday = uint32(c.Int("height"))
+		}
+		added, err := wallet.ImportDump(dump, birthday)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d keys imported, the wallet syncs again from height %d\n", added, birthday)
+		return nil
+	}
+
 	// change password
 	if c.Bool("changepassword") {
 		fmt.Printf("Wallet File: '%s'\n", name)
@@ -332,9 +691,22 @@ func NewCommand() *cli.Command {
 				Name:  "create, c",
 				Usage: "create wallet",
 			},
+			cli.BoolFlag{
+				Name:  "recover",
+				Usage: "recover wallet from mnemonic",
+			},
+			cli.BoolFlag{
+				Name:  "mnemonic, m",
+				Usage: "derive the accounts from a mnemonic with create or recover",
+			},
+			cli.IntFlag{
+				Name:  "gaplimit",
+				Usage: "number of unused accounts to look ahead with recover",
+				Value: account.DefaultGapLimit,
+			},
 			cli.StringFlag{
 				Name:  "list, l",
-				Usage: "list wallet information [account, balance, verbose]",
+				Usage: "list wallet information [account, balance, verbose, multisig, watchonly, unspent, history, contacts, requests]",
 			},
 			cli.IntFlag{
 				Name:  "addaccount",
@@ -344,11 +716,43 @@ func NewCommand() *cli.Command {
 				Name:  "addmultisigaccount",
 				Usage: "add new multi-sign account address",
 			},
+			cli.StringFlag{
+				Name:  "addwatchonly",
+				Usage: "watch an address, public key or redeem script without its keys",
+			},
+			cli.StringFlag{
+				Name:  "deletewatchonly",
+				Usage: "stop watching an address",
+			},
+			cli.StringFlag{
+				Name:  "exportkey",
+				Usage: "print the encoded private key of an address",
+			},
+			cli.BoolFlag{
+				Name:  "importkey",
+				Usage: "import an encoded private key read from the standard input",
+			},
+			cli.StringFlag{
+				Name:  "dumpwallet",
+				Usage: "dump the keys, contracts, watch-only addresses, labels and contacts to an encrypted file",
+			},
+			cli.StringFlag{
+				Name:  "importwallet",
+				Usage: "import a wallet dump file",
+			},
+			cli.IntFlag{
+				Name:  "height",
+				Usage: "height the wallet syncs again from with importkey, addwatchonly or importwallet, 0 or the birthday of the dump by default",
+			},
 
 			cli.BoolFlag{
 				Name:  "changepassword",
 				Usage: "change wallet password",
 			},
+			cli.IntFlag{
+				Name:  "kdfcost",
+				Usage: "scrypt cost of the password key of a new wallet or password, a power of 2",
+			},
 			cli.BoolFlag{
 				Name:  "reset",
 				Usage: "reset wallet",
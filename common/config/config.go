@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"time"
@@ -29,6 +30,22 @@ var (
 		MaxOrphanBlocks:    10000,
 		MinMemoryNodes:     20160,
 		SpendCoinbaseSpan:  100,
+		MaxReorgDepth:      720, // One day of blocks
+		PrivateKeyID:       0x80,
+
+		LwmaAveragingWindow: 90,
+
+		AuxPowChainID:          1,
+		AuxPowStrictChainID:    true,
+		AuxPowMinParentVersion: 2,
+		AuxPowStartHeight:      0,
+		AuxPowMandatoryHeight:  math.MaxUint32,
+
+		RuleChangeActivationThreshold: 1916, // 95% of MinerConfirmationWindow
+		MinerConfirmationWindow:       2016,
+		Deployments: []ConsensusDeployment{
+			{Name: "csv", BitNumber: 0, StartTime: 1798761600, ExpireTime: 1830297600}, // 2027-01-01 to 2028-01-01
+		},
 	}
 	testNet *ChainParams = &ChainParams{
 		Name:               "TestNet",
@@ -40,6 +57,28 @@ var (
 		MaxOrphanBlocks:    10000,
 		MinMemoryNodes:     20160,
 		SpendCoinbaseSpan:  100,
+		MaxReorgDepth:      720, // One day of blocks
+		PrivateKeyID:       0xef,
+
+		// LWMA is a hard fork of the testnet, it is not scheduled until the
+		// fork height is agreed with the testnet node operators, like the aux
+		// pow mandatory height. The blocks below it keep the retarget rules.
+		DifficultyAlgorithm:        "lwma",
+		DifficultyActivationHeight: math.MaxUint32,
+		LwmaAveragingWindow:        60,
+
+		AuxPowChainID:          1,
+		AuxPowStrictChainID:    true,
+		AuxPowMinParentVersion: 2,
+		AuxPowStartHeight:      0,
+		AuxPowMandatoryHeight:  math.MaxUint32,
+
+		RuleChangeActivationThreshold: 1512, // 75% of MinerConfirmationWindow
+		MinerConfirmationWindow:       2016,
+		Deployments: []ConsensusDeployment{
+			{Name: "testdummy", BitNumber: 28, StartTime: 0, ExpireTime: math.MaxInt64},
+			{Name: "csv", BitNumber: 0, StartTime: 0, ExpireTime: math.MaxInt64},
+		},
 	}
 	regNet *ChainParams = &ChainParams{
 		Name:               "RegNet",
@@ -51,6 +90,24 @@ var (
 		MaxOrphanBlocks:    10000,
 		MinMemoryNodes:     20160,
 		SpendCoinbaseSpan:  100,
+		MaxReorgDepth:      0,
+		PrivateKeyID:       0xf0,
+
+		PowNoRetargeting:    true,
+		LwmaAveragingWindow: 60,
+
+		AuxPowChainID:          1,
+		AuxPowStrictChainID:    false,
+		AuxPowMinParentVersion: 1,
+		AuxPowStartHeight:      0,
+		AuxPowMandatoryHeight:  math.MaxUint32,
+
+		RuleChangeActivationThreshold: 108, // 75% of MinerConfirmationWindow
+		MinerConfirmationWindow:       144,
+		Deployments: []ConsensusDeployment{
+			{Name: "testdummy", BitNumber: 28, StartTime: 0, ExpireTime: math.MaxInt64},
+			{Name: "csv", BitNumber: 0, StartTime: 0, ExpireTime: math.MaxInt64},
+		},
 	}
 )
 
@@ -68,6 +125,7 @@ type PowConfiguration struct {
 	AutoMining       bool   `json:"AutoMining"`
 	MinerInfo        string `json:"MinerInfo"`
 	MinTxFee         int    `json:"MinTxFee"`
+	MinTxFeePerKB    int    `json:"MinTxFeePerKB"`
 	ActiveNet        string `json:"ActiveNet"`
 }
 
@@ -80,9 +138,12 @@ type Configuration struct {
 	RestKeyPath         string           `json:"RestKeyPath"`
 	HttpInfoPort        uint16           `json:"HttpInfoPort"`
 	HttpInfoStart       bool             `json:"HttpInfoStart"`
+	HttpMetricsPort     int              `json:"HttpMetricsPort"`
+	HttpMetricsStart    bool             `json:"HttpMetricsStart"`
 	HttpWsPort          int              `json:"HttpWsPort"`
 	WsHeartbeatInterval time.Duration    `json:"WsHeartbeatInterval"`
 	HttpJsonPort        int              `json:"HttpJsonPort"`
+	HttpJsonHost        string           `json:"HttpJsonHost"` // All interfaces if empty
 	OauthServerUrl      string           `json:"OauthServerUrl"`
 	NoticeServerUrl     string           `json:"NoticeServerUrl"`
 	NodePort            int              `json:"NodePort"`
@@ -105,6 +166,8 @@ type Configuration struct {
 	DefaultMaxPeers     uint             `json:"DefaultMaxPeers"`
 	GetAddrMax          uint             `json:"GetAddrMax"`
 	MaxOutboundCnt      uint             `json:"MaxOutboundCnt"`
+	MaxReorgDepth       uint32           `json:"MaxReorgDepth"`
+	DifficultyAlgorithm string           `json:"DifficultyAlgorithm"` // RegNet only
 	//AddCheckpoints format: "<height>:<hash>"
 	AddCheckpoints []string `json:"AddCheckpoints"`
 }
@@ -123,6 +186,47 @@ type ChainParams struct {
 	MaxOrphanBlocks    int
 	MinMemoryNodes     uint32
 	SpendCoinbaseSpan  uint32
+
+	// MaxReorgDepth is a rolling checkpoint, the blocks forking the best
+	// chain deeper than it are rejected. Zero disables the limit.
+	MaxReorgDepth uint32
+
+	// PrivateKeyID is the version byte of the exported private keys, which
+	// tags them with the network.
+	PrivateKeyID byte
+
+	// The difficulty algorithm used from DifficultyActivationHeight on, the
+	// blocks below it use the Bitcoin style retarget, which keeps the
+	// PowLimitBits with PowNoRetargeting.
+	DifficultyAlgorithm        string
+	DifficultyActivationHeight uint32
+	PowNoRetargeting           bool
+	LwmaAveragingWindow        uint32
+
+	// Merged mining rules, the blocks from AuxPowStartHeight may carry an aux
+	// proof of work and the blocks from AuxPowMandatoryHeight have to.
+	AuxPowChainID          int
+	AuxPowStrictChainID    bool  // Reject the parent blocks claiming AuxPowChainID
+	AuxPowMinParentVersion int32 // Min block version of the parent blocks
+	AuxPowStartHeight      uint32
+	AuxPowMandatoryHeight  uint32
+
+	// BIP9 soft fork deployments, a deployment locks in when the number of
+	// blocks signalling it in a MinerConfirmationWindow reaches the
+	// RuleChangeActivationThreshold.
+	RuleChangeActivationThreshold uint32
+	MinerConfirmationWindow       uint32
+	Deployments                   []ConsensusDeployment
+}
+
+// ConsensusDeployment is a soft fork signalled by miners with a bit of the
+// block version. The start and expire times are compared with the median
+// time past of the blocks.
+type ConsensusDeployment struct {
+	Name       string
+	BitNumber  uint8
+	StartTime  int64 // Unix seconds
+	ExpireTime int64 // Unix seconds
 }
 
 type configParams struct {
@@ -154,5 +258,13 @@ func init() {
 	} else if Parameters.PowConfiguration.ActiveNet == "RegNet" {
 		Parameters.ChainParam = regNet
 	}
+	if Parameters.ChainParam != nil && Parameters.MaxReorgDepth != 0 {
+		Parameters.ChainParam.MaxReorgDepth = Parameters.MaxReorgDepth
+	}
+	// the difficulty algorithm is a consensus rule but on the private RegNet
+	if Parameters.ChainParam == regNet && Parameters.DifficultyAlgorithm != "" {
+		Parameters.ChainParam.DifficultyAlgorithm = Parameters.DifficultyAlgorithm
+		Parameters.ChainParam.DifficultyActivationHeight = 1
+	}
 
 }
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// The metrics are written in the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type collector interface {
+	writeTo(w io.Writer)
+}
+
+var registry struct {
+	sync.RWMutex
+	collectors []collector
+}
+
+func register(c collector) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.collectors = append(registry.collectors, c)
+}
+
+// WritePrometheus writes all the registered metrics to w
+func WritePrometheus(w io.Writer) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, c := range registry.collectors {
+		c.writeTo(w)
+	}
+}
+
+// WriteGauge writes a gauge whose value is only known at scrape time
+func WriteGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+}
+
+// WriteCounter writes a counter kept outside of the registry
+func WriteCounter(w io.Writer, name, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	value uint64 // Keep the atomic value 64-bit aligned
+	name  string
+	help  string
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+func (c *Counter) writeTo(w io.Writer) {
+	WriteCounter(w, c.name, c.help, c.Value())
+}
+
+// Gauge is a value which can go up and down
+type Gauge struct {
+	bits uint64
+	name string
+	help string
+}
+
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) writeTo(w io.Writer) {
+	WriteGauge(w, g.name, g.help, g.Value())
+}
+
+type summaryValue struct {
+	count uint64
+	sum   float64
+}
+
+// Summary counts the observations and their sum, partitioned by one label
+type Summary struct {
+	sync.Mutex
+	name   string
+	help   string
+	label  string
+	values map[string]*summaryValue
+}
+
+func NewSummary(name, help, label string) *Summary {
+	s := &Summary{
+		name:   name,
+		help:   help,
+		label:  label,
+		values: make(map[string]*summaryValue),
+	}
+	register(s)
+	return s
+}
+
+func (s *Summary) Observe(labelValue string, v float64) {
+	s.Lock()
+	defer s.Unlock()
+	sv, ok := s.values[labelValue]
+	if !ok {
+		sv = new(summaryValue)
+		s.values[labelValue] = sv
+	}
+	sv.count++
+	sv.sum += v
+}
+
+// Value returns the count and the sum of the observations of the label value
+func (s *Summary) Value(labelValue string) (uint64, float64) {
+	s.Lock()
+	defer s.Unlock()
+	sv, ok := s.values[labelValue]
+	if !ok {
+		return 0, 0
+	}
+	return sv.count, sv.sum
+}
+
+func (s *Summary) writeTo(w io.Writer) {
+	s.Lock()
+	defer s.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s summary\n", s.name, s.help, s.name)
+	labelValues := make([]string, 0, len(s.values))
+	for lv := range s.values {
+		labelValues = append(labelValues, lv)
+	}
+	sort.Strings(labelValues)
+	for _, lv := range labelValues {
+		sv := s.values[lv]
+		fmt.Fprintf(w, "%s_sum{%s=%q} %s\n", s.name, s.label, lv, formatFloat(sv.sum))
+		fmt.Fprintf(w, "%s_count{%s=%q} %d\n", s.name, s.label, lv, sv.count)
+	}
+}
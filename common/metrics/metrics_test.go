@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	c := NewCounter("test_counter_total", "Test counter.")
+	c.Inc()
+	c.Add(2)
+	g := NewGauge("test_gauge", "Test gauge.")
+	g.Set(1.5)
+	s := NewSummary("test_duration_seconds", "Test summary.", "method")
+	s.Observe("b", 0.5)
+	s.Observe("a", 0.25)
+	s.Observe("b", 0.5)
+
+	buf := new(bytes.Buffer)
+	WritePrometheus(buf)
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE test_counter_total counter\n",
+		"test_counter_total 3\n",
+		"# TYPE test_gauge gauge\n",
+		"test_gauge 1.5\n",
+		"# TYPE test_duration_seconds summary\n",
+		"test_duration_seconds_sum{method=\"a\"} 0.25\n",
+		"test_duration_seconds_count{method=\"b\"} 2\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Index(out, "{method=\"a\"}") > strings.Index(out, "{method=\"b\"}") {
+		t.Error("summary label values are not sorted")
+	}
+}
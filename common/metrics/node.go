@@ -0,0 +1,21 @@
+package metrics
+
+// The metrics updated by the node modules. The values which can be read from
+// the ledger and the network at any time are collected by the metrics server
+// at scrape time instead.
+var (
+	RPCDuration = NewSummary("dna_rpc_request_duration_seconds",
+		"Time spent handling the JSON RPC requests.", "method")
+	PersistDuration = NewSummary("dna_chainstore_persist_duration_seconds",
+		"Time spent persisting the tasks of the chain store.", "task")
+	Reorgs = NewCounter("dna_chain_reorgs_total",
+		"Number of chain reorganizations.")
+	ReorgDepth = NewGauge("dna_chain_last_reorg_depth",
+		"Number of blocks disconnected by the last chain reorganization.")
+	PowHashes = NewCounter("dna_pow_hashes_total",
+		"Number of block hashes computed by the CPU miner.")
+	PowHashRate = NewGauge("dna_pow_hashrate",
+		"Hashes per second of the CPU miner.")
+	PowBlocksFound = NewCounter("dna_pow_blocks_found_total",
+		"Number of blocks mined by this node and accepted in the main chain.")
+)
@@ -108,7 +108,10 @@ func ToScriptHash(address string) (Uint160, error) {
 		return Uint160{}, err
 	}
 
-	x, _ := new(big.Int).SetString(string(decoded), 10)
+	x, ok := new(big.Int).SetString(string(decoded), 10)
+	if !ok || len(x.Bytes()) < 21 {
+		return Uint160{}, errors.New("[AddressToProgramHash]: invalid address length.")
+	}
 	ph, err := Uint160ParseFromBytes(x.Bytes()[1:21])
 	if err != nil {
 		return Uint160{}, err
@@ -2,18 +2,23 @@ package pow
 
 import (
 	"DNA_POW/net/protocol"
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cl "DNA_POW/account"
 	. "DNA_POW/common"
 	"DNA_POW/common/config"
 	"DNA_POW/common/log"
+	"DNA_POW/common/metrics"
 	"DNA_POW/core/auxpow"
 	"DNA_POW/core/ledger"
 	tx "DNA_POW/core/transaction"
@@ -27,10 +32,11 @@ import (
 var TaskCh chan bool
 
 const (
-	maxNonce       = ^uint32(0) // 2^32 - 1
-	maxExtraNonce  = ^uint64(0) // 2^64 - 1
-	hpsUpdateSecs  = 10
-	hashUpdateSecs = 15
+	maxNonce            = ^uint32(0) // 2^32 - 1
+	maxExtraNonce       = ^uint64(0) // 2^64 - 1
+	hpsUpdateSecs       = 10
+	hashUpdateSecs      = 15
+	templateRefreshSecs = 60 // Min age of the block template to pick up new transactions
 )
 
 var (
@@ -56,6 +62,12 @@ type PowService struct {
 	started        bool
 	discreteMining bool
 	localNet       protocol.Noder
+	numWorkers     uint32
+	activeWorkers  int32
+
+	updateHashes      chan uint64
+	queryHashesPerSec chan float64
+	tipChanged        chan struct{}
 
 	blockPersistCompletedSubscriber events.Subscriber
 	RollbackTransactionSubscriber   events.Subscriber
@@ -157,14 +169,39 @@ func (s txSorter) Less(i, j int) bool {
 }
 
 func (pow *PowService) GenerateBlock(addr string) (*ledger.Block, error) {
+	var txPool txSorter
+	txPool = make([]*tx.Transaction, 0)
+	transactionsPool := pow.localNet.GetTxnPool(false)
+	for _, v := range transactionsPool {
+		txPool = append(txPool, v)
+	}
+	sort.Sort(sort.Reverse(txPool))
+
+	return pow.generateBlock(addr, txPool, false)
+}
+
+// GenerateBlockWithTxs returns a block template including exactly the
+// transactions in order, they all have to be valid in the next block.
+func (pow *PowService) GenerateBlockWithTxs(addr string, txs []*tx.Transaction) (*ledger.Block, error) {
+	return pow.generateBlock(addr, txs, true)
+}
+
+// generateBlock returns a block template with the transactions in order. The
+// transactions not allowed in the next block are skipped, or fail the
+// template when all of them are required.
+func (pow *PowService) generateBlock(addr string, txs []*tx.Transaction, requireAll bool) (*ledger.Block, error) {
 	nextBlockHeight := ledger.DefaultLedger.Blockchain.GetBestHeight() + 1
 	coinBaseTx, err := pow.CreateCoinbaseTrx(nextBlockHeight, addr)
 	if err != nil {
 		return nil, err
 	}
+	version, err := ledger.DefaultLedger.Blockchain.ComputeBlockVersion(ledger.DefaultLedger.Blockchain.BestChain)
+	if err != nil {
+		return nil, err
+	}
 
 	blockData := &ledger.Blockdata{
-		Version:          0,
+		Version:          version,
 		PrevBlockHash:    *ledger.DefaultLedger.Blockchain.BestChain.Hash,
 		TransactionsRoot: Uint256{},
 		Timestamp:        uint32(ledger.DefaultLedger.Blockchain.MedianAdjustedTime().Unix()),
@@ -183,33 +220,41 @@ func (pow *PowService) GenerateBlock(addr string) (*ledger.Block, error) {
 	calcTxsSize := coinBaseTx.GetSize()
 	calcTxsAmount := 1
 	totalFee := int64(0)
-	var txPool txSorter
-	txPool = make([]*tx.Transaction, 0)
-	transactionsPool := pow.localNet.GetTxnPool(false)
-	for _, v := range transactionsPool {
-		txPool = append(txPool, v)
-	}
-	sort.Sort(sort.Reverse(txPool))
 
-	for _, tx := range txPool {
+	for _, tx := range txs {
 		if (tx.GetSize() + calcTxsSize) > ledger.MaxBlockSize {
+			if requireAll {
+				return nil, errors.New("transactions exceed the max block size")
+			}
 			break
 		}
 		if calcTxsAmount >= config.Parameters.MaxTxInBlock {
+			if requireAll {
+				return nil, errors.New("transactions exceed the max transactions in block")
+			}
 			break
 		}
 
-		if !ledger.IsFinalizedTransaction(tx, nextBlockHeight) {
+		if err := ledger.DefaultLedger.Blockchain.CheckTransactionLocks(tx, ledger.DefaultLedger.Blockchain.BestChain); err != nil {
+			if requireAll {
+				return nil, fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+			}
 			continue
 		}
 
 		if errCode := ledger.CheckTransactionContext(tx, ledger.DefaultLedger); errCode != ErrNoError {
 			log.Info("generate block, wrong tx", tx.Hash())
+			if requireAll {
+				return nil, fmt.Errorf("transaction %x: %v", tx.Hash(), errCode)
+			}
 			continue
 		}
 
 		fee := tx.GetFee(ledger.DefaultLedger.Blockchain.AssetID)
 		if fee != int64(tx.Fee) {
+			if requireAll {
+				return nil, fmt.Errorf("transaction %x: fee mismatch", tx.Hash())
+			}
 			continue
 		}
 		msgBlock.Transactions = append(msgBlock.Transactions, tx)
@@ -231,23 +276,59 @@ func (pow *PowService) GenerateBlock(addr string) (*ledger.Block, error) {
 	txRoot, _ := crypto.ComputeRoot(txHash)
 	msgBlock.Blockdata.TransactionsRoot = txRoot
 
-	msgBlock.Blockdata.Bits, err = ledger.CalcNextRequiredDifficulty(ledger.DefaultLedger.Blockchain.BestChain, time.Now())
+	blockTime := time.Unix(int64(blockData.Timestamp), 0)
+	msgBlock.Blockdata.Bits, err = ledger.CalcNextRequiredDifficulty(ledger.DefaultLedger.Blockchain.BestChain, blockTime)
 	log.Info("difficulty: ", msgBlock.Blockdata.Bits)
 
 	return msgBlock, err
 }
 
-func (pow *PowService) DiscreteMining(n uint32) ([]*Uint256, error) {
+// startDiscreteMining claims the miner for the RPC driven mining
+func (pow *PowService) startDiscreteMining() error {
 	pow.Mutex.Lock()
-
+	defer pow.Mutex.Unlock()
 	if pow.started || pow.discreteMining {
-		pow.Mutex.Unlock()
-		return nil, errors.New("Server is already CPU mining.")
+		return errors.New("Server is already CPU mining.")
 	}
-
 	pow.started = true
 	pow.discreteMining = true
+	return nil
+}
+
+func (pow *PowService) stopDiscreteMining() {
+	pow.Mutex.Lock()
+	pow.started = false
+	pow.discreteMining = false
 	pow.Mutex.Unlock()
+}
+
+// submitSolvedBlock adds the solved block to the chain and relays it, the
+// block has to become the best block.
+func (pow *PowService) submitSolvedBlock(msgBlock *ledger.Block) error {
+	inMainChain, isOrphan, err := ledger.DefaultLedger.Blockchain.AddBlock(msgBlock)
+	if err != nil {
+		return err
+	}
+	//TODO if co-mining condition
+	if isOrphan || !inMainChain {
+		return errors.New("mined block is not on the best chain")
+	}
+	metrics.PowBlocksFound.Inc()
+	pow.BroadcastBlock(msgBlock)
+	return nil
+}
+
+func (pow *PowService) DiscreteMining(n uint32) ([]*Uint256, error) {
+	return pow.GenerateToAddress(n, pow.PayToAddr)
+}
+
+// GenerateToAddress mines n blocks paying to the address and returns their
+// hashes.
+func (pow *PowService) GenerateToAddress(n uint32, addr string) ([]*Uint256, error) {
+	if err := pow.startDiscreteMining(); err != nil {
+		return nil, err
+	}
+	defer pow.stopDiscreteMining()
 
 	log.Tracef("Pow generating %d blocks", n)
 	i := uint32(0)
@@ -263,32 +344,22 @@ func (pow *PowService) DiscreteMining(n uint32) ([]*Uint256, error) {
 			continue
 		}
 
-		msgBlock, err := pow.GenerateBlock(pow.PayToAddr)
+		msgBlock, err := pow.GenerateBlock(addr)
 		if err != nil {
 			log.Trace("generage block err", err)
 			continue
 		}
 
-		if pow.SolveBlock(msgBlock, ticker) {
+		if pow.SolveBlock(msgBlock, ticker, nil) {
 			if msgBlock.Blockdata.Height == ledger.DefaultLedger.Blockchain.GetBestHeight()+1 {
-				inMainChain, isOrphan, err := ledger.DefaultLedger.Blockchain.AddBlock(msgBlock)
-				if err != nil {
+				if err := pow.submitSolvedBlock(msgBlock); err != nil {
 					log.Trace(err)
 					continue
 				}
-				//TODO if co-mining condition
-				if isOrphan || !inMainChain {
-					continue
-				}
-				pow.BroadcastBlock(msgBlock)
 				h := msgBlock.Hash()
 				blockHashes[i] = &h
 				i++
 				if i == n {
-					pow.Mutex.Lock()
-					pow.started = false
-					pow.discreteMining = false
-					pow.Mutex.Unlock()
 					return blockHashes, nil
 				}
 			}
@@ -296,36 +367,226 @@ func (pow *PowService) DiscreteMining(n uint32) ([]*Uint256, error) {
 	}
 }
 
-func (pow *PowService) SolveBlock(MsgBlock *ledger.Block, ticker *time.Ticker) bool {
-	header := MsgBlock.Blockdata
-	targetDifficulty := ledger.CompactToBig(header.Bits)
+// MineBlockWithTxs mines a block including exactly the transactions, paying
+// to the address, and returns its hash.
+func (pow *PowService) MineBlockWithTxs(addr string, txs []*tx.Transaction) (*Uint256, error) {
+	if err := pow.startDiscreteMining(); err != nil {
+		return nil, err
+	}
+	defer pow.stopDiscreteMining()
 
-	for extraNonce := uint64(0); extraNonce < maxExtraNonce; extraNonce++ {
-		attr := binary.BigEndian.Uint64(MsgBlock.Transactions[0].Attributes[0].Data)
-		attr += extraNonce
-		binary.BigEndian.PutUint64(MsgBlock.Transactions[0].Attributes[0].Data, attr)
+	ticker := time.NewTicker(time.Second * hashUpdateSecs)
+	defer ticker.Stop()
+	for {
+		// The template is regenerated when the best chain moves
+		msgBlock, err := pow.GenerateBlockWithTxs(addr, txs)
+		if err != nil {
+			return nil, err
+		}
+		if !pow.SolveBlock(msgBlock, ticker, nil) ||
+			msgBlock.Blockdata.Height != ledger.DefaultLedger.Blockchain.GetBestHeight()+1 {
+			continue
+		}
+		if err := pow.submitSolvedBlock(msgBlock); err != nil {
+			return nil, err
+		}
+		h := msgBlock.Hash()
+		return &h, nil
+	}
+}
+
+// speedMonitor keeps a rolling hashes per second figure of all the mining
+// workers, which report their hashes every hashUpdateSecs.
+func (pow *PowService) speedMonitor() {
+	var hashesPerSec float64
+	var totalHashes uint64
+	ticker := time.NewTicker(time.Second * hpsUpdateSecs)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case numHashes := <-pow.updateHashes:
+			totalHashes += numHashes
+			metrics.PowHashes.Add(numHashes)
+
+		case <-ticker.C:
+			curHashesPerSec := float64(totalHashes) / hpsUpdateSecs
+			if hashesPerSec == 0 {
+				hashesPerSec = curHashesPerSec
+			}
+			hashesPerSec = (hashesPerSec + curHashesPerSec) / 2
+			totalHashes = 0
+			if atomic.LoadInt32(&pow.activeWorkers) == 0 {
+				hashesPerSec = 0
+			}
+			metrics.PowHashRate.Set(hashesPerSec)
+			if hashesPerSec != 0 {
+				log.Debugf("Hash speed: %6.0f kilohashes/s", hashesPerSec/1000)
+			}
+
+		case pow.queryHashesPerSec <- hashesPerSec:
+			// Nothing to do.
+		}
+	}
+}
+
+// HashesPerSecond returns the rolling hashes per second of the CPU miner
+func (pow *PowService) HashesPerSecond() float64 {
+	return <-pow.queryHashesPerSec
+}
+
+func (pow *PowService) IsMining() bool {
+	pow.Mutex.Lock()
+	defer pow.Mutex.Unlock()
+	return pow.started
+}
+
+func (pow *PowService) NumWorkers() uint32 {
+	return pow.numWorkers
+}
+
+// blockWithExtraNonce returns a copy of the block template whose coinbase
+// nonce attribute is increased by extraNonce, the transactions root of the
+// copy is rebuilt with the new coinbase.
+func blockWithExtraNonce(template *ledger.Block, extraNonce uint64) (*ledger.Block, error) {
+	buf := new(bytes.Buffer)
+	if err := template.Transactions[0].Serialize(buf); err != nil {
+		return nil, err
+	}
+	coinbase := new(tx.Transaction)
+	if err := coinbase.Deserialize(buf); err != nil {
+		return nil, err
+	}
+	data := coinbase.Attributes[0].Data
+	binary.BigEndian.PutUint64(data, binary.BigEndian.Uint64(data)+extraNonce)
+
+	txs := make([]*tx.Transaction, len(template.Transactions))
+	copy(txs, template.Transactions)
+	txs[0] = coinbase
+	txHash := make([]Uint256, 0, len(txs))
+	for _, txn := range txs {
+		txHash = append(txHash, txn.Hash())
+	}
+	txRoot, err := crypto.ComputeRoot(txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	header := *template.Blockdata
+	header.TransactionsRoot = txRoot
+	return &ledger.Block{
+		Blockdata:    &header,
+		Transactions: txs,
+	}, nil
+}
+
+// solveWorker searches its part of the nonce space for each extranonce of
+// its own, so the workers never hash the same header.
+func (pow *PowService) solveWorker(template *ledger.Block, worker uint32, found chan<- *ledger.Block,
+	quit <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	atomic.AddInt32(&pow.activeWorkers, 1)
+	defer atomic.AddInt32(&pow.activeWorkers, -1)
+	ticker := time.NewTicker(time.Second * hashUpdateSecs)
+	defer ticker.Stop()
+	hashesCompleted := uint64(0)
+	defer func() {
+		pow.updateHashes <- hashesCompleted
+	}()
+
+	targetDifficulty := ledger.CompactToBig(template.Blockdata.Bits)
+	nonceSpan := maxNonce / pow.numWorkers
+	startNonce := worker * nonceSpan
+	endNonce := startNonce + nonceSpan - 1
+	if worker == pow.numWorkers-1 {
+		endNonce = maxNonce
+	}
 
-		for i := uint32(0); i <= maxNonce; i++ {
+	for extraNonce := uint64(worker); extraNonce < maxExtraNonce; extraNonce += uint64(pow.numWorkers) {
+		block, err := blockWithExtraNonce(template, extraNonce)
+		if err != nil {
+			log.Error("mining worker build block error: ", err)
+			return
+		}
+		header := block.Blockdata
+
+		for i := startNonce; ; i++ {
 			select {
+			case <-quit:
+				return
 			case <-ticker.C:
-				if MsgBlock.Blockdata.PrevBlockHash.CompareTo(*ledger.DefaultLedger.Blockchain.BestChain.Hash) != 0 {
-					return false
-				}
-				//UpdateBlockTime(msgBlock, m.server.blockManager)
-
+				pow.updateHashes <- hashesCompleted
+				hashesCompleted = 0
 			default:
 				// Non-blocking select to fall through
 			}
 
 			header.Nonce = i
 			hash := header.Hash()
+			hashesCompleted++
 			if ledger.HashToBig(&hash).Cmp(targetDifficulty) <= 0 {
-				return true
+				select {
+				case found <- block:
+				default:
+				}
+				return
+			}
+			if i == endNonce {
+				break
 			}
 		}
 	}
+}
+
+// isStale reports whether the block template should be regenerated because
+// the best chain moved or the transaction pool changed.
+func (pow *PowService) isStale(MsgBlock *ledger.Block, txnCount int, templateTime time.Time) bool {
+	if MsgBlock.Blockdata.PrevBlockHash.CompareTo(*ledger.DefaultLedger.Blockchain.BestChain.Hash) != 0 {
+		return true
+	}
+	return time.Since(templateTime) >= time.Second*templateRefreshSecs &&
+		pow.GetTransactionCount() != txnCount
+}
+
+// SolveBlock searches the nonce and extranonce space of the block with all
+// the workers. The solved block replaces MsgBlock and true is returned, it
+// returns false when the template becomes stale or quit is closed.
+func (pow *PowService) SolveBlock(MsgBlock *ledger.Block, ticker *time.Ticker, quit chan struct{}) bool {
+	// Drop the tip change notified before the template was generated
+	select {
+	case <-pow.tipChanged:
+	default:
+	}
+	txnCount := pow.GetTransactionCount()
+	templateTime := time.Now()
+
+	found := make(chan *ledger.Block, 1)
+	workerQuit := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := uint32(0); i < pow.numWorkers; i++ {
+		wg.Add(1)
+		go pow.solveWorker(MsgBlock, i, found, workerQuit, &wg)
+	}
+	defer func() {
+		close(workerQuit)
+		wg.Wait()
+	}()
 
-	return false
+	for {
+		select {
+		case block := <-found:
+			*MsgBlock = *block
+			return true
+		case <-pow.tipChanged:
+			return false
+		case <-ticker.C:
+			if pow.isStale(MsgBlock, txnCount, templateTime) {
+				return false
+			}
+		case <-quit:
+			return false
+		}
+	}
 }
 
 func (pow *PowService) BroadcastBlock(MsgBlock *ledger.Block) error {
@@ -394,6 +655,10 @@ func (pow *PowService) BlockPersistCompleted(v interface{}) {
 	log.Debug()
 	if block, ok := v.(*ledger.Block); ok {
 		log.Infof("persist block: %x", block.Hash())
+		select {
+		case pow.tipChanged <- struct{}{}:
+		default:
+		}
 		err := pow.localNet.CleanSubmittedTransactions(block)
 		if err != nil {
 			log.Warn(err)
@@ -412,12 +677,21 @@ func NewPowService(client cl.Client, logDictionary string, localNet protocol.Nod
 		ZMQPublish:     make(chan bool, 1),
 		localNet:       localNet,
 		logDictionary:  logDictionary,
+		numWorkers:     uint32(config.Parameters.MultiCoreNum),
+
+		updateHashes:      make(chan uint64),
+		queryHashesPerSec: make(chan float64),
+		tipChanged:        make(chan struct{}, 1),
+	}
+	if pow.numWorkers == 0 {
+		pow.numWorkers = uint32(runtime.NumCPU())
 	}
 
 	pow.blockPersistCompletedSubscriber = ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventBlockPersistCompleted, pow.BlockPersistCompleted)
 	pow.RollbackTransactionSubscriber = ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventRollbackTransaction, pow.RollbackTransaction)
 
 	go pow.ZMQServer()
+	go pow.speedMonitor()
 	log.Trace("pow Service Init succeed and ZMQServer start succeed")
 	return pow
 }
@@ -456,7 +730,7 @@ out:
 
 		isAuxPow := config.Parameters.PowConfiguration.CoMining
 		//begin to mine the block with POW
-		if generateStatus && !isAuxPow && pow.SolveBlock(msgBlock, ticker) {
+		if generateStatus && !isAuxPow && pow.SolveBlock(msgBlock, ticker, pow.quit) {
 			//send the valid block to p2p networkd
 			if msgBlock.Blockdata.Height == ledger.DefaultLedger.Blockchain.GetBestHeight()+1 {
 				inMainChain, isOrphan, err := ledger.DefaultLedger.Blockchain.AddBlock(msgBlock)
@@ -468,6 +742,7 @@ out:
 				if isOrphan || !inMainChain {
 					continue
 				}
+				metrics.PowBlocksFound.Inc()
 				//pow.ZMQClientSend(*msgBlock)
 				pow.BroadcastBlock(msgBlock)
 			}
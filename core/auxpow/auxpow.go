@@ -6,12 +6,13 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 )
 
 var (
-	AuxPowChainID         = 1
 	pchMergedMiningHeader = []byte{0xfa, 0xbe, 'm', 'm'}
 )
 
@@ -157,7 +158,36 @@ func (ap *AuxPow) Deserialize(r io.Reader) error {
 	return nil
 }
 
+// IsEmpty reports whether the block carries no aux proof of work
+func (ap *AuxPow) IsEmpty() bool {
+	return ap.ParBlockHeader == BtcBlockHeader{}
+}
+
+// GetChainID returns the chain ID a block version claims, merge mined chains
+// put their chain ID in the upper 16 bits of the block version.
+func GetChainID(version int32) int {
+	return int(version >> 16)
+}
+
+// CheckParentBlock checks the parent block is allowed to carry the proof of
+// work of the aux chain. With strictChainId, a parent block claiming the
+// chain ID of the aux chain is rejected, so the same chain can not be its
+// own parent.
+func (ap *AuxPow) CheckParentBlock(chainId int, strictChainId bool, minParentVersion int32) error {
+	if ap.ParBlockHeader.Version < minParentVersion {
+		return fmt.Errorf("parent block version %d is below the minimum %d",
+			ap.ParBlockHeader.Version, minParentVersion)
+	}
+	if strictChainId && GetChainID(ap.ParBlockHeader.Version) == chainId {
+		return errors.New("parent block has the chain ID of the aux chain")
+	}
+	return nil
+}
+
 func (ap *AuxPow) Check(hashAuxBlock Uint256, chainId int) bool {
+	if len(ap.ParCoinbaseTx.TxIn) == 0 {
+		return false
+	}
 	if CheckMerkleBranch(ap.ParCoinbaseTx.Hash(), ap.ParCoinBaseMerkle, ap.ParMerkleIndex) != ap.ParBlockHeader.MerkleRoot {
 		return false
 	}
@@ -107,14 +107,14 @@ func Test_GetExpectedIndex(t *testing.T) {
 	target := 684357196
 	index := GetExpectedIndex(nonce, chainId, height)
 	if index != target {
-		t.Error("1: index need %d, but get %d", target, index)
+		t.Errorf("1: index need %d, but get %d", target, index)
 	}
 
 	height = 1
 	target = 0
 	index = GetExpectedIndex(nonce, chainId, height)
 	if index != target {
-		t.Error("2: index need %d, but get %d", target, index)
+		t.Errorf("2: index need %d, but get %d", target, index)
 	}
 }
 func Test_CheckMerkleBranch(t *testing.T) {
@@ -262,3 +262,62 @@ func Test_Check2(t *testing.T) {
 	}
 
 }
+
+func Test_CheckParentBlock(t *testing.T) {
+	tests := []struct {
+		parentVersion    int32
+		chainId          int
+		strictChainId    bool
+		minParentVersion int32
+		valid            bool
+	}{
+		// Bitcoin style parent versions claim chain ID 0
+		{2, 1, true, 2, true},
+		{0x20000000, 1, true, 2, true},
+		{1, 1, true, 2, false},
+		{1, 1, false, 1, true},
+		// The parent claims the chain ID of the aux chain
+		{1<<16 | 2, 1, true, 2, false},
+		{1<<16 | 2, 1, false, 2, true},
+		{1<<16 | 2, 2, true, 2, true},
+		{0x62<<16 | 4, 0x62, true, 2, false},
+	}
+	for i, test := range tests {
+		var ap AuxPow
+		ap.ParBlockHeader.Version = test.parentVersion
+		err := ap.CheckParentBlock(test.chainId, test.strictChainId, test.minParentVersion)
+		if (err == nil) != test.valid {
+			t.Errorf("%d: parent version %08x chain ID %d, got %v", i, test.parentVersion, test.chainId, err)
+		}
+	}
+
+	if GetChainID(0x00620104) != 0x62 {
+		t.Error("GetChainID fail")
+	}
+}
+
+func Test_IsEmpty(t *testing.T) {
+	var ap AuxPow
+	if !ap.IsEmpty() {
+		t.Error("zero aux pow is not empty")
+	}
+	if ap.Check(Uint256{}, 1) {
+		t.Error("empty aux pow passed the check")
+	}
+
+	auxDataStr := "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4902e174044c1dcb592f4254432e434f4d2ffabe6d6d02f7c9edfc32335aa1956d16842407aa355a172d5bdf66e3cc15b134ef8574670100000000000000010000001017000000000000ffffffff0200000000000000001976a91489893957178347e87e2bb3850e6f6937de7372b288ac0000000000000000266a24aa21a9ede2f61c3f71d1defd3fa999dfa36953755c690689799962b48bebd836974e8cf900000000262b25ee945edb5655e17484431aaa81688950aa5381009ee1e5e775b5d6e3960000000000000000000000000020f9e2a4a5f4cd21fd1b126170bc12a90acfddb7fea5820e41d59ace0569affd35aef1152384b83c5b6f670cb43a597666803de4d0ad3faf423124553cbe68a454561dcb59ffff7f20561dcb59"
+	auxData, _ := HexStringToBytes(auxDataStr)
+	if err := ap.Deserialize(bytes.NewBuffer(auxData)); err != nil {
+		t.Fatal(err)
+	}
+	if ap.IsEmpty() {
+		t.Error("aux pow with a parent block is empty")
+	}
+	// The parent is a version 0x20000000 block, which claims chain ID 0x2000
+	if err := ap.CheckParentBlock(1, true, 2); err != nil {
+		t.Error(err)
+	}
+	if err := ap.CheckParentBlock(0x2000, true, 2); err == nil {
+		t.Error("parent block claiming the aux chain ID passed the check")
+	}
+}
@@ -0,0 +1,15 @@
+package auxpow
+
+import (
+	. "DNA_POW/common"
+)
+
+// HexToBytes and ToHexString are the hex helpers the test vectors were written
+// with, in terms of the ones of the common package.
+func HexToBytes(value string) ([]byte, error) {
+	return HexStringToBytes(value)
+}
+
+func ToHexString(data []byte) string {
+	return BytesToHexString(data)
+}
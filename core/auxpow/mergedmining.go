@@ -0,0 +1,166 @@
+package auxpow
+
+import (
+	. "DNA_POW/common"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	MaxAuxMerkleHeight = 30      // The merkle size of the aux chains is at most 2^30
+	MaxAuxMerkleNonce  = 1 << 16 // Nonces tried for each merkle height
+)
+
+// MergedMining coordinates mining several aux chains with one parent block.
+// The aux block hashes are the leaves of the aux chain merkle tree, each at
+// the slot GetExpectedIndex picks for the chain ID, and the root of the tree
+// is committed in the parent coinbase.
+type MergedMining struct {
+	chains       map[int]Uint256 // Aux block hash by chain ID
+	index        map[int]int     // Leaf index by chain ID
+	merkleHeight int
+	nonce        uint32
+	levels       [][]Uint256 // The tree from the leaves up to the root
+}
+
+// NewMergedMining builds the aux chain merkle tree of the aux block hashes,
+// keyed by chain ID. It picks the smallest tree and a nonce which give every
+// chain a slot of its own.
+func NewMergedMining(chains map[int]Uint256) (*MergedMining, error) {
+	if len(chains) == 0 {
+		return nil, errors.New("no aux chain to merge mine")
+	}
+
+	merkleHeight := 0
+	for 1<<uint(merkleHeight) < len(chains) {
+		merkleHeight++
+	}
+	for ; merkleHeight <= MaxAuxMerkleHeight; merkleHeight++ {
+		for nonce := uint32(0); nonce < MaxAuxMerkleNonce; nonce++ {
+			index, ok := assignSlots(chains, nonce, merkleHeight)
+			if !ok {
+				continue
+			}
+			mm := &MergedMining{
+				chains:       chains,
+				index:        index,
+				merkleHeight: merkleHeight,
+				nonce:        nonce,
+			}
+			mm.buildTree()
+			return mm, nil
+		}
+	}
+	return nil, errors.New("no aux merkle tree gives the chain IDs different slots")
+}
+
+// assignSlots returns the leaf index of each chain, it fails when two chains
+// collide in the same slot.
+func assignSlots(chains map[int]Uint256, nonce uint32, merkleHeight int) (map[int]int, bool) {
+	index := make(map[int]int, len(chains))
+	used := make(map[int]bool, len(chains))
+	for chainID := range chains {
+		slot := GetExpectedIndex(nonce, chainID, merkleHeight)
+		if used[slot] {
+			return nil, false
+		}
+		used[slot] = true
+		index[chainID] = slot
+	}
+	return index, true
+}
+
+// buildTree hashes the tree the same way CheckMerkleBranch does, the slots
+// without an aux chain are left as zero hashes.
+func (mm *MergedMining) buildTree() {
+	leaves := make([]Uint256, 1<<uint(mm.merkleHeight))
+	for chainID, hash := range mm.chains {
+		leaves[mm.index[chainID]] = hash
+	}
+	mm.levels = [][]Uint256{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([]Uint256, len(level)/2)
+		for i := range next {
+			temp := make([]uint8, 0, 64)
+			temp = append(temp, level[2*i][:]...)
+			temp = append(temp, level[2*i+1][:]...)
+			next[i] = Uint256(sha256.Sum256(temp))
+		}
+		mm.levels = append(mm.levels, next)
+		level = next
+	}
+}
+
+func (mm *MergedMining) Root() Uint256 {
+	return mm.levels[len(mm.levels)-1][0]
+}
+
+func (mm *MergedMining) MerkleSize() uint32 {
+	return 1 << uint(mm.merkleHeight)
+}
+
+func (mm *MergedMining) Nonce() uint32 {
+	return mm.nonce
+}
+
+// Index returns the slot of the chain in the aux chain merkle tree
+func (mm *MergedMining) Index(chainID int) (int, bool) {
+	index, ok := mm.index[chainID]
+	return index, ok
+}
+
+// Commitment returns the data the parent coinbase script has to include:
+// the merged mining header, the aux merkle root, the merkle size and the
+// nonce.
+func (mm *MergedMining) Commitment() []byte {
+	root := mm.Root()
+	buf := new(bytes.Buffer)
+	buf.Write(pchMergedMiningHeader)
+	buf.Write(root.ToArray())
+	binary.Write(buf, binary.LittleEndian, mm.MerkleSize())
+	binary.Write(buf, binary.LittleEndian, mm.nonce)
+	return buf.Bytes()
+}
+
+// branch returns the sibling hashes from the leaf up to the root
+func (mm *MergedMining) branch(index int) []Uint256 {
+	branch := make([]Uint256, 0, mm.merkleHeight)
+	for _, level := range mm.levels[:len(mm.levels)-1] {
+		branch = append(branch, level[index^1])
+		index >>= 1
+	}
+	return branch
+}
+
+// AuxPow builds the proof of the chain from the solved parent block, which is
+// given by its header, its coinbase and the merkle branch of the coinbase.
+func (mm *MergedMining) AuxPow(chainID int, parCoinbaseTx BtcTx, parCoinBaseMerkle []Uint256,
+	parMerkleIndex int, parBlockHeader BtcBlockHeader) (*AuxPow, error) {
+	index, ok := mm.index[chainID]
+	if !ok {
+		return nil, fmt.Errorf("chain ID %d is not merge mined", chainID)
+	}
+	ap := NewAuxPow(mm.branch(index), index, parCoinbaseTx, parCoinBaseMerkle, parMerkleIndex, parBlockHeader)
+	ap.ParentHash = parBlockHeader.Hash()
+	if !ap.Check(mm.chains[chainID], chainID) {
+		return nil, fmt.Errorf("parent block does not commit to chain ID %d", chainID)
+	}
+	return ap, nil
+}
+
+// AuxPows splits the solved parent block into the proofs of all the chains
+func (mm *MergedMining) AuxPows(parCoinbaseTx BtcTx, parCoinBaseMerkle []Uint256,
+	parMerkleIndex int, parBlockHeader BtcBlockHeader) (map[int]*AuxPow, error) {
+	auxPows := make(map[int]*AuxPow, len(mm.chains))
+	for chainID := range mm.chains {
+		ap, err := mm.AuxPow(chainID, parCoinbaseTx, parCoinBaseMerkle, parMerkleIndex, parBlockHeader)
+		if err != nil {
+			return nil, err
+		}
+		auxPows[chainID] = ap
+	}
+	return auxPows, nil
+}
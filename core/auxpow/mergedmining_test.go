@@ -0,0 +1,133 @@
+package auxpow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+
+	. "DNA_POW/common"
+)
+
+const testChainID = 1
+
+func testAuxHash(s string) Uint256 {
+	return Uint256(sha256.Sum256([]byte(s)))
+}
+
+// parentBlock returns a parent coinbase with the script data and a parent
+// header whose merkle root is the coinbase hash.
+func parentBlock(script []byte) (BtcTx, BtcBlockHeader) {
+	txIn := &BtcTxIn{
+		PreviousOutPoint: BtcOutPoint{Index: 0xffffffff},
+		SignatureScript:  append([]byte{0x03, 0x4e, 0x01, 0x05}, script...),
+	}
+	txOut := &BtcTxOut{Value: 2504275756, PkScript: []byte{0x6a}}
+	coinbase := NewBtcTx([]*BtcTxIn{txIn}, []*BtcTxOut{txOut})
+	header := BtcBlockHeader{
+		Version:    2,
+		MerkleRoot: coinbase.Hash(),
+		Timestamp:  1415239972,
+		Bits:       0x181bc330,
+	}
+	return *coinbase, header
+}
+
+func Test_MergedMining(t *testing.T) {
+	chains := map[int]Uint256{
+		testChainID: testAuxHash("dna"),
+		2:           testAuxHash("aux chain 2"),
+		7:           testAuxHash("aux chain 7"),
+		98:          testAuxHash("aux chain 98"),
+		99:          testAuxHash("aux chain 99"),
+	}
+	mm, err := NewMergedMining(chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mm.MerkleSize() < uint32(len(chains)) {
+		t.Fatalf("merkle size %d is smaller than the chain count", mm.MerkleSize())
+	}
+	slots := make(map[int]bool)
+	for chainID := range chains {
+		index, ok := mm.Index(chainID)
+		if !ok || slots[index] {
+			t.Fatalf("chain %d has no slot of its own", chainID)
+		}
+		slots[index] = true
+	}
+
+	coinbase, header := parentBlock(mm.Commitment())
+	auxPows, err := mm.AuxPows(coinbase, []Uint256{}, 0, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for chainID, hash := range chains {
+		ap := auxPows[chainID]
+		if !ap.Check(hash, chainID) {
+			t.Errorf("aux pow of chain %d check failed", chainID)
+		}
+		if ap.Check(hash, chainID+1000) {
+			t.Errorf("aux pow of chain %d passed for another chain ID", chainID)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := ap.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		var ap2 AuxPow
+		if err := ap2.Deserialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ap.AuxMerkleBranch, ap2.AuxMerkleBranch) || !ap2.Check(hash, chainID) {
+			t.Errorf("aux pow of chain %d does not survive serialization", chainID)
+		}
+	}
+
+	if _, err := mm.AuxPow(3, coinbase, []Uint256{}, 0, header); err == nil {
+		t.Error("expected an error for a chain which is not merge mined")
+	}
+}
+
+func Test_MergedMiningSingleChain(t *testing.T) {
+	hash := testAuxHash("dna")
+	mm, err := NewMergedMining(map[int]Uint256{testChainID: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single chain commits its own block hash, the same as mining it alone
+	if mm.MerkleSize() != 1 || mm.Root() != hash {
+		t.Errorf("single chain merkle size %d, root %x", mm.MerkleSize(), mm.Root())
+	}
+
+	coinbase, header := parentBlock(mm.Commitment())
+	ap, err := mm.AuxPow(testChainID, coinbase, []Uint256{}, 0, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ap.AuxMerkleBranch) != 0 || ap.ParentHash != header.Hash() {
+		t.Error("unexpected single chain aux pow")
+	}
+}
+
+func Test_MergedMiningWrongParent(t *testing.T) {
+	chains := map[int]Uint256{testChainID: testAuxHash("dna"), 2: testAuxHash("aux chain 2")}
+	mm, err := NewMergedMining(chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parent coinbase commits to another tree
+	other, _ := NewMergedMining(map[int]Uint256{testChainID: testAuxHash("other"), 2: testAuxHash("aux chain 2")})
+	coinbase, header := parentBlock(other.Commitment())
+	if _, err := mm.AuxPows(coinbase, []Uint256{}, 0, header); err == nil {
+		t.Error("expected an error for a parent committing to another tree")
+	}
+
+	// The parent header does not include the coinbase
+	coinbase, header = parentBlock(mm.Commitment())
+	header.MerkleRoot = testAuxHash("merkle root")
+	if _, err := mm.AuxPows(coinbase, []Uint256{}, 0, header); err == nil {
+		t.Error("expected an error for a parent header without the coinbase")
+	}
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/core/auxpow"
+)
+
+// testAuxPowHeader returns a header of the height, merge mined with a valid
+// aux proof of work if asked.
+func testAuxPowHeader(t *testing.T, height uint32, merged bool) *Blockdata {
+	header := &Blockdata{Version: 1, Height: height}
+	if !merged {
+		return header
+	}
+	chainID := config.Parameters.ChainParam.AuxPowChainID
+	mm, err := auxpow.NewMergedMining(map[int]Uint256{chainID: header.Hash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txIn := &auxpow.BtcTxIn{
+		PreviousOutPoint: auxpow.BtcOutPoint{Index: 0xffffffff},
+		SignatureScript:  mm.Commitment(),
+	}
+	coinbase := auxpow.NewBtcTx([]*auxpow.BtcTxIn{txIn}, []*auxpow.BtcTxOut{{PkScript: []byte{0x6a}}})
+	parent := auxpow.BtcBlockHeader{Version: 2, MerkleRoot: coinbase.Hash()}
+	ap, err := mm.AuxPow(chainID, *coinbase, []Uint256{}, 0, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.AuxPow = *ap
+	return header
+}
+
+func TestCheckAuxPowActivation(t *testing.T) {
+	params := *config.Parameters.ChainParam
+	test := params
+	test.AuxPowChainID = 1
+	test.AuxPowStrictChainID = true
+	test.AuxPowMinParentVersion = 2
+	test.AuxPowStartHeight = 10
+	test.AuxPowMandatoryHeight = 20
+	config.Parameters.ChainParam = &test
+	defer func() { config.Parameters.ChainParam = &params }()
+
+	tests := []struct {
+		height uint32
+		merged bool
+		valid  bool
+	}{
+		// before the start height
+		{5, false, true},
+		{5, true, false},
+		// from the start height up to the mandatory height
+		{10, false, true},
+		{10, true, true},
+		{19, false, true},
+		{19, true, true},
+		// from the mandatory height
+		{20, false, false},
+		{20, true, true},
+		{25, false, false},
+		{25, true, true},
+	}
+	for _, test := range tests {
+		isAuxPow, err := CheckAuxPow(testAuxPowHeader(t, test.height, test.merged))
+		if (err == nil) != test.valid {
+			t.Errorf("height %d, merged %v: unexpected error %v", test.height, test.merged, err)
+		}
+		if err == nil && isAuxPow != test.merged {
+			t.Errorf("height %d, merged %v: reported merged %v", test.height, test.merged, isAuxPow)
+		}
+	}
+
+	// The proof of another header is rejected
+	header := testAuxPowHeader(t, 15, true)
+	header.Nonce++
+	if _, err := CheckAuxPow(header); err == nil {
+		t.Error("aux pow of another header accepted")
+	}
+	// A parent block of the chain ID of the aux chain is rejected
+	header = testAuxPowHeader(t, 15, true)
+	header.AuxPow.ParBlockHeader.Version = 2 | 1<<16
+	if _, err := CheckAuxPow(header); err == nil {
+		t.Error("aux pow of a parent block with the aux chain ID accepted")
+	}
+}
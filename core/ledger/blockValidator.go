@@ -3,14 +3,11 @@ package ledger
 import (
 	. "DNA_POW/common"
 	"DNA_POW/common/config"
-	"DNA_POW/core/auxpow"
-	tx "DNA_POW/core/transaction"
 	"DNA_POW/crypto"
 	. "DNA_POW/errors"
 
 	"errors"
 	"fmt"
-	"math"
 	"math/big"
 	"time"
 )
@@ -21,9 +18,9 @@ const (
 
 func PowCheckBlockSanity(block *Block, powLimit *big.Int, timeSource MedianTimeSource) error {
 	header := block.Blockdata
-	isAuxPow := config.Parameters.PowConfiguration.CoMining
-	if isAuxPow && !header.AuxPow.Check(header.Hash(), auxpow.AuxPowChainID) {
-		return errors.New("[PowCheckBlockSanity] block check proof is failed")
+	isAuxPow, err := CheckAuxPow(header)
+	if err != nil {
+		return errors.New("[PowCheckBlockSanity] " + err.Error())
 	}
 	if CheckProofOfWork(header, powLimit, isAuxPow) != nil {
 		return errors.New("[PowCheckBlockSanity] block check proof is failed.")
@@ -131,14 +128,11 @@ func PowCheckBlockContext(block *Block, prevNode *BlockNode, ledger *Ledger) err
 		return errors.New("block timestamp is not after expected")
 	}
 
-	// The height of this block is one more than the referenced
-	// previous block.
-	blockHeight := prevNode.Height + 1
-
-	// Ensure all transactions in the block are finalized.
+	// Ensure all transactions in the block are finalized and their
+	// relative lock times are reached.
 	for _, txn := range block.Transactions[1:] {
-		if !IsFinalizedTransaction(txn, blockHeight) {
-			return errors.New("block contains unfinalized transaction")
+		if err := ledger.Blockchain.CheckTransactionLocks(txn, prevNode); err != nil {
+			return errors.New("block contains unfinalized transaction: " + err.Error())
 		}
 	}
 
@@ -152,6 +146,31 @@ func PowCheckBlockContext(block *Block, prevNode *BlockNode, ledger *Ledger) err
 	return nil
 }
 
+// CheckAuxPow checks whether the header is allowed to or has to carry an aux
+// proof of work at its height and validates the proof it carries. It returns
+// whether the header is merge mined.
+func CheckAuxPow(header *Blockdata) (bool, error) {
+	params := config.Parameters.ChainParam
+	if header.AuxPow.IsEmpty() {
+		if header.Height >= params.AuxPowMandatoryHeight {
+			return false, errors.New("block is not merge mined after the aux pow mandatory height")
+		}
+		return false, nil
+	}
+
+	if header.Height < params.AuxPowStartHeight {
+		return false, errors.New("block is merge mined before the aux pow start height")
+	}
+	err := header.AuxPow.CheckParentBlock(params.AuxPowChainID, params.AuxPowStrictChainID, params.AuxPowMinParentVersion)
+	if err != nil {
+		return false, err
+	}
+	if !header.AuxPow.Check(header.Hash(), params.AuxPowChainID) {
+		return false, errors.New("block check aux proof is failed")
+	}
+	return true, nil
+}
+
 func CheckProofOfWork(bd *Blockdata, powLimit *big.Int, isAuxPow bool) error {
 	// The target difficulty must be larger than zero.
 	target := CompactToBig(bd.Bits)
@@ -178,26 +197,3 @@ func CheckProofOfWork(bd *Blockdata, powLimit *big.Int, isAuxPow bool) error {
 
 	return nil
 }
-
-func IsFinalizedTransaction(msgTx *tx.Transaction, blockHeight uint32) bool {
-	// Lock time of zero means the transaction is finalized.
-	lockTime := msgTx.LockTime
-	if lockTime == 0 {
-		return true
-	}
-
-	//FIXME only height
-	if lockTime < blockHeight {
-		return true
-	}
-
-	// At this point, the transaction's lock time hasn't occurred yet, but
-	// the transaction might still be finalized if the sequence number
-	// for all transaction inputs is maxed out.
-	for _, txIn := range msgTx.UTXOInputs {
-		if txIn.Sequence != math.MaxUint16 {
-			return false
-		}
-	}
-	return true
-}
@@ -4,6 +4,7 @@ import (
 	. "DNA_POW/common"
 	"DNA_POW/common/config"
 	"DNA_POW/common/log"
+	"DNA_POW/common/metrics"
 	. "DNA_POW/errors"
 	"DNA_POW/events"
 	"container/list"
@@ -49,6 +50,18 @@ type Blockchain struct {
 	mutex          sync.RWMutex
 	Ledger         *Ledger
 	AssetID        Uint256
+
+	deploymentStates map[string]map[Uint256]ThresholdState // By the last block of a window
+	deploymentLock   sync.Mutex
+
+	quarantined     map[Uint256]uint32 // Deep reorg blocks and their descendants, by height
+	reorgRejections []*ReorgRejection
+	reorgLock       sync.Mutex
+
+	invalidated map[Uint256]struct{} // By invalidateblock and their descendants
+	invalidLock sync.Mutex
+
+	tipChanges chainTipQueue
 }
 
 func NewBlockchain(height uint32, ledger *Ledger) *Blockchain {
@@ -68,6 +81,10 @@ func NewBlockchain(height uint32, ledger *Ledger) *Blockchain {
 		BCEvents: events.NewEvent(),
 		Ledger:   ledger,
 		AssetID:  Uint256{},
+
+		deploymentStates: make(map[string]map[Uint256]ThresholdState),
+		quarantined:      make(map[Uint256]uint32),
+		invalidated:      make(map[Uint256]struct{}),
 	}
 }
 
@@ -264,6 +281,13 @@ func (bc *Blockchain) IsKnownOrphan(hash *Uint256) bool {
 	return false
 }
 
+func (bc *Blockchain) GetOrphanCount() int {
+	bc.OrphanLock.RLock()
+	defer bc.OrphanLock.RUnlock()
+
+	return len(bc.Orphans)
+}
+
 func (bc *Blockchain) GetOrphanRoot(hash *Uint256) *Uint256 {
 	bc.OrphanLock.RLock()
 	defer bc.OrphanLock.RUnlock()
@@ -653,6 +677,7 @@ func (bc *Blockchain) ReorganizeChain(detachNodes, attachNodes *list.List) error
 	//}
 
 	// Disconnect blocks from the main chain.
+	oldTip := bc.BestChain
 	for e := detachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*BlockNode)
 		block, err := bc.Ledger.Store.GetBlock(*n.Hash)
@@ -666,6 +691,7 @@ func (bc *Blockchain) ReorganizeChain(detachNodes, attachNodes *list.List) error
 	}
 
 	// Connect the new best chain blocks.
+	fork := bc.BestChain
 	for e := attachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*BlockNode)
 		block := bc.BlockCache[*n.Hash]
@@ -676,6 +702,10 @@ func (bc *Blockchain) ReorganizeChain(detachNodes, attachNodes *list.List) error
 		delete(bc.BlockCache, *n.Hash)
 	}
 
+	metrics.Reorgs.Inc()
+	metrics.ReorgDepth.Set(float64(detachNodes.Len()))
+	bc.notifyChainTipChange(newChainTipChange(oldTip, fork, detachNodes, attachNodes))
+
 	// Log the point where the chain forked.
 	//firstAttachNode := attachNodes.Front().Value.(*BlockNode)
 	//forkNode, err := bc.GetPrevNodeFromNode(firstAttachNode)
@@ -805,6 +835,15 @@ func (bc *Blockchain) maybeAcceptBlock(block *Block) (bool, error) {
 		return false, fmt.Errorf("wrong block height!")
 	}
 
+	// The block must not build on an invalidated block, nor fork the best
+	// chain deeper than the max reorg depth.
+	if err := bc.checkInvalidParent(block, prevNode); err != nil {
+		return false, err
+	}
+	if err := bc.checkReorgDepth(block, prevNode); err != nil {
+		return false, err
+	}
+
 	// The block must pass all of the validation rules which depend on the
 	// position of the block within the block chain.
 	err = PowCheckBlockContext(block, prevNode, bc.Ledger)
@@ -863,6 +902,7 @@ func (bc *Blockchain) ConnectBestChain(node *BlockNode, block *Block) (bool, err
 		//}
 
 		// Connect the block to the main chain.
+		oldTip := bc.BestChain
 		err := bc.ConnectBlock(node, block)
 		if err != nil {
 			return false, err
@@ -873,6 +913,12 @@ func (bc *Blockchain) ConnectBestChain(node *BlockNode, block *Block) (bool, err
 			node.Parent.Children = append(node.Parent.Children, node)
 		}
 
+		if oldTip != nil {
+			attachNodes := list.New()
+			attachNodes.PushBack(node)
+			bc.notifyChainTipChange(newChainTipChange(oldTip, oldTip, list.New(), attachNodes))
+		}
+
 		return true, nil
 	}
 
@@ -979,6 +1025,12 @@ func (bc *Blockchain) ProcessBlock(block *Block, timeSource MedianTimeSource, fl
 		return false, false, err
 	}
 
+	// The block must not be on a branch rejected for a deep reorg, which is
+	// checked after the proof of work so blocks of no work aren't quarantined.
+	if err := bc.checkQuarantine(block); err != nil {
+		return false, false, err
+	}
+
 	blockHeader := block.Blockdata
 
 	// Handle orphan blocks.
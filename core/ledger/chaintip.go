@@ -0,0 +1,95 @@
+package ledger
+
+import (
+	"container/list"
+	"sync"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/log"
+	"DNA_POW/events"
+)
+
+// ChainTipChange describes a change of the best chain tip as a whole. When a
+// block extends the best chain the fork is the old tip and nothing is
+// detached, when blocks are only disconnected the fork is the new tip. It is
+// the value of the EventChainTipChanged event, which is delivered to the
+// handlers one change at a time in the order of the Sequence.
+type ChainTipChange struct {
+	Sequence   uint64
+	OldTip     Uint256
+	OldHeight  uint32
+	NewTip     Uint256
+	NewHeight  uint32
+	Fork       Uint256
+	ForkHeight uint32
+	Detached   []Uint256 // From the old tip down to the fork
+	Attached   []Uint256 // From above the fork up to the new tip
+}
+
+func newChainTipChange(oldTip, fork *BlockNode, detachNodes, attachNodes *list.List) *ChainTipChange {
+	change := &ChainTipChange{
+		OldTip:     *oldTip.Hash,
+		OldHeight:  oldTip.Height,
+		NewTip:     *fork.Hash,
+		NewHeight:  fork.Height,
+		Fork:       *fork.Hash,
+		ForkHeight: fork.Height,
+		Detached:   make([]Uint256, 0, detachNodes.Len()),
+		Attached:   make([]Uint256, 0, attachNodes.Len()),
+	}
+	for e := detachNodes.Front(); e != nil; e = e.Next() {
+		change.Detached = append(change.Detached, *e.Value.(*BlockNode).Hash)
+	}
+	for e := attachNodes.Front(); e != nil; e = e.Next() {
+		n := e.Value.(*BlockNode)
+		change.Attached = append(change.Attached, *n.Hash)
+		change.NewTip = *n.Hash
+		change.NewHeight = n.Height
+	}
+	return change
+}
+
+// chainTipQueue keeps the chain tip changes not delivered yet. A single
+// goroutine, running while the queue is not empty, delivers them.
+type chainTipQueue struct {
+	sync.Mutex
+	sequence   uint64 // Of the latest ChainTipChange
+	changes    []*ChainTipChange
+	delivering bool
+}
+
+func (bc *Blockchain) notifyChainTipChange(change *ChainTipChange) {
+	q := &bc.tipChanges
+	q.Lock()
+	defer q.Unlock()
+	q.sequence++
+	change.Sequence = q.sequence
+	log.Debugf("Chain tip changed from %x at height %d to %x at height %d, "+
+		"%d blocks detached", change.OldTip.ToArrayReverse(), change.OldHeight,
+		change.NewTip.ToArrayReverse(), change.NewHeight, len(change.Detached))
+	q.changes = append(q.changes, change)
+	if !q.delivering {
+		q.delivering = true
+		go bc.deliverChainTipChanges()
+	}
+}
+
+// deliverChainTipChanges notifies the queued changes one after another until
+// the queue is empty.
+func (bc *Blockchain) deliverChainTipChanges() {
+	q := &bc.tipChanges
+	for {
+		q.Lock()
+		if len(q.changes) == 0 {
+			q.delivering = false
+			q.Unlock()
+			return
+		}
+		change := q.changes[0]
+		q.changes[0] = nil
+		q.changes = q.changes[1:]
+		q.Unlock()
+
+		bc.BCEvents.NotifyInOrder(events.EventChainTipChanged, change)
+	}
+}
@@ -0,0 +1,81 @@
+package ledger
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	. "DNA_POW/common"
+	"DNA_POW/events"
+)
+
+func TestNewChainTipChange(t *testing.T) {
+	_, nodes := mainChain(5)
+	side4 := &BlockNode{Hash: &Uint256{0xf4}, Height: 4, Parent: nodes[3]}
+	side5 := &BlockNode{Hash: &Uint256{0xf5}, Height: 5, Parent: side4}
+	side6 := &BlockNode{Hash: &Uint256{0xf6}, Height: 6, Parent: side5}
+
+	detachNodes, attachNodes := list.New(), list.New()
+	detachNodes.PushBack(nodes[5])
+	detachNodes.PushBack(nodes[4])
+	for _, n := range []*BlockNode{side4, side5, side6} {
+		attachNodes.PushBack(n)
+	}
+	change := newChainTipChange(nodes[5], nodes[3], detachNodes, attachNodes)
+
+	if change.OldTip != *nodes[5].Hash || change.OldHeight != 5 ||
+		change.NewTip != *side6.Hash || change.NewHeight != 6 ||
+		change.Fork != *nodes[3].Hash || change.ForkHeight != 3 {
+		t.Fatalf("unexpected tips %+v", change)
+	}
+	if len(change.Detached) != 2 || change.Detached[0] != *nodes[5].Hash || change.Detached[1] != *nodes[4].Hash {
+		t.Errorf("unexpected detached blocks %x", change.Detached)
+	}
+	if len(change.Attached) != 3 || change.Attached[0] != *side4.Hash || change.Attached[2] != *side6.Hash {
+		t.Errorf("unexpected attached blocks %x", change.Attached)
+	}
+}
+
+func TestChainTipChangeSequence(t *testing.T) {
+	bc, nodes := mainChain(2)
+	var changes []*ChainTipChange
+	for i := 1; i <= 2; i++ {
+		change := newChainTipChange(nodes[i-1], nodes[i-1], list.New(), list.New())
+		bc.notifyChainTipChange(change)
+		changes = append(changes, change)
+	}
+	if changes[0].Sequence == 0 || changes[1].Sequence <= changes[0].Sequence {
+		t.Errorf("changes of sequences %d and %d", changes[0].Sequence, changes[1].Sequence)
+	}
+}
+
+func TestChainTipChangeOrder(t *testing.T) {
+	bc, nodes := mainChain(1)
+	const n = 50
+	delivered := make(chan uint64, n)
+	for i := 0; i < 2; i++ {
+		bc.BCEvents.Subscribe(events.EventChainTipChanged, func(v interface{}) {
+			change := v.(*ChainTipChange)
+			// a slow handler of the first change must not let the next pass
+			if change.Sequence == 1 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			delivered <- change.Sequence
+		})
+	}
+	for i := 0; i < n/2; i++ {
+		bc.notifyChainTipChange(newChainTipChange(nodes[0], nodes[1], list.New(), list.New()))
+	}
+
+	// each change reaches both handlers before the next one is delivered
+	for i := 0; i < n; i++ {
+		select {
+		case sequence := <-delivered:
+			if want := uint64(i/2 + 1); sequence != want {
+				t.Fatalf("delivery %d: got sequence %d, want %d", i, sequence, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("delivery %d timed out", i)
+		}
+	}
+}
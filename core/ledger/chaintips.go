@@ -0,0 +1,216 @@
+package ledger
+
+import (
+	"container/list"
+	"fmt"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/log"
+)
+
+// The status of a chain tip
+const (
+	ChainTipActive      = "active"       // The tip of the best chain
+	ChainTipValidFork   = "valid-fork"   // A side chain with all its blocks
+	ChainTipHeadersOnly = "headers-only" // Headers above the best chain without the blocks
+	ChainTipInvalid     = "invalid"      // A branch including an invalidated block
+)
+
+// ChainTip is the tip of a branch of the block tree. The branch length is the
+// number of blocks from the fork point with the best chain.
+type ChainTip struct {
+	Hash      Uint256
+	Height    uint32
+	BranchLen uint32
+	Status    string
+}
+
+// tips returns the nodes of the block index without children
+func (bc *Blockchain) tips() []*BlockNode {
+	bc.IndexLock.Lock()
+	defer bc.IndexLock.Unlock()
+	var tips []*BlockNode
+	for _, node := range bc.Index {
+		if len(node.Children) == 0 {
+			tips = append(tips, node)
+		}
+	}
+	return tips
+}
+
+// forkNode returns the latest ancestor of the node on the best chain
+func forkNode(node *BlockNode) *BlockNode {
+	for node.Parent != nil && !node.InMainChain {
+		node = node.Parent
+	}
+	return node
+}
+
+// ChainTips returns the tips of all the branches known to the block index and
+// the tip of the headers synced above the best chain.
+func (bc *Blockchain) ChainTips() []ChainTip {
+	bc.mutex.RLock()
+	defer bc.mutex.RUnlock()
+
+	tips := []ChainTip{{
+		Hash:   *bc.BestChain.Hash,
+		Height: bc.BestChain.Height,
+		Status: ChainTipActive,
+	}}
+	for _, node := range bc.tips() {
+		if node == bc.BestChain {
+			continue
+		}
+		tip := ChainTip{
+			Hash:      *node.Hash,
+			Height:    node.Height,
+			BranchLen: node.Height - forkNode(node).Height,
+			Status:    ChainTipValidFork,
+		}
+		if bc.isInvalid(*node.Hash) {
+			tip.Status = ChainTipInvalid
+		}
+		tips = append(tips, tip)
+	}
+
+	// The headers are synced along the best chain
+	headerHeight := bc.Ledger.Store.GetHeaderHeight()
+	if headerHeight > bc.BestChain.Height {
+		tips = append(tips, ChainTip{
+			Hash:      bc.Ledger.Store.GetCurrentHeaderHash(),
+			Height:    headerHeight,
+			BranchLen: headerHeight - bc.BestChain.Height,
+			Status:    ChainTipHeadersOnly,
+		})
+	}
+	return tips
+}
+
+// OrphanBlocks returns the blocks whose parents are unknown yet
+func (bc *Blockchain) OrphanBlocks() []*OrphanBlock {
+	bc.OrphanLock.RLock()
+	defer bc.OrphanLock.RUnlock()
+	orphans := make([]*OrphanBlock, 0, len(bc.Orphans))
+	for _, orphan := range bc.Orphans {
+		orphans = append(orphans, orphan)
+	}
+	return orphans
+}
+
+func (bc *Blockchain) isInvalid(hash Uint256) bool {
+	bc.invalidLock.Lock()
+	defer bc.invalidLock.Unlock()
+	_, ok := bc.invalidated[hash]
+	return ok
+}
+
+// setInvalid marks or unmarks the node and all its descendants as invalid
+func (bc *Blockchain) setInvalid(node *BlockNode, invalid bool) {
+	bc.invalidLock.Lock()
+	defer bc.invalidLock.Unlock()
+	nodes := []*BlockNode{node}
+	for len(nodes) > 0 {
+		n := nodes[len(nodes)-1]
+		nodes = append(nodes[:len(nodes)-1], n.Children...)
+		if invalid {
+			bc.invalidated[*n.Hash] = struct{}{}
+		} else {
+			delete(bc.invalidated, *n.Hash)
+		}
+	}
+}
+
+// checkInvalidParent rejects the blocks building on an invalidated block,
+// they are invalid as well.
+func (bc *Blockchain) checkInvalidParent(block *Block, prevNode *BlockNode) error {
+	if prevNode == nil || !bc.isInvalid(*prevNode.Hash) {
+		return nil
+	}
+	hash := block.Hash()
+	bc.invalidLock.Lock()
+	bc.invalidated[hash] = struct{}{}
+	bc.invalidLock.Unlock()
+	return fmt.Errorf("block %x extends the invalidated block %x",
+		hash.ToArrayReverse(), prevNode.Hash.ToArrayReverse())
+}
+
+// hasSideBlocks reports whether the blocks of the branch above the best chain
+// are all in the side chain cache, which is needed to reorganize onto it.
+func (bc *Blockchain) hasSideBlocks(node *BlockNode) bool {
+	for ; node != nil && !node.InMainChain; node = node.Parent {
+		if _, ok := bc.BlockCache[*node.Hash]; !ok {
+			return false
+		}
+	}
+	return node != nil
+}
+
+// activateBestChain reorganizes the chain onto the valid branch with the most
+// work when it has more work than the best chain.
+func (bc *Blockchain) activateBestChain() error {
+	best := bc.BestChain
+	for _, tip := range bc.tips() {
+		if tip.InMainChain || bc.isInvalid(*tip.Hash) || tip.WorkSum.Cmp(best.WorkSum) <= 0 {
+			continue
+		}
+		if bc.hasSideBlocks(tip) {
+			best = tip
+		}
+	}
+	if best == bc.BestChain {
+		return nil
+	}
+
+	log.Infof("REORGANIZE: Block %v is the valid tip with the most work.", best.Hash)
+	detachNodes, attachNodes := bc.GetReorganizeNodes(best)
+	return bc.ReorganizeChain(detachNodes, attachNodes)
+}
+
+// InvalidateBlock marks the block and its descendants as invalid. When the
+// block is on the best chain it is disconnected with the blocks above it and
+// the chain moves to the valid branch with the most work.
+func (bc *Blockchain) InvalidateBlock(hash Uint256) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	node, ok := bc.LookupNodeInIndex(&hash)
+	if !ok {
+		return fmt.Errorf("block %x is not in the block index", hash.ToArrayReverse())
+	}
+	if node.Parent == nil {
+		return fmt.Errorf("block %x has no parent in memory to fall back to", hash.ToArrayReverse())
+	}
+	bc.setInvalid(node, true)
+
+	if node.InMainChain {
+		detachNodes := list.New()
+		for n := bc.BestChain; n != node.Parent; n = n.Parent {
+			detachNodes.PushBack(n)
+		}
+		log.Infof("INVALIDATE: Disconnecting %d blocks down to height %d.", detachNodes.Len(), node.Parent.Height)
+		if err := bc.ReorganizeChain(detachNodes, list.New()); err != nil {
+			return err
+		}
+	}
+	return bc.activateBestChain()
+}
+
+// ReconsiderBlock removes the invalid mark from the block, its ancestors and
+// descendants, and moves the chain to the valid branch with the most work.
+func (bc *Blockchain) ReconsiderBlock(hash Uint256) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	node, ok := bc.LookupNodeInIndex(&hash)
+	if !ok {
+		return fmt.Errorf("block %x is not in the block index", hash.ToArrayReverse())
+	}
+	bc.setInvalid(node, false)
+	bc.invalidLock.Lock()
+	for n := node.Parent; n != nil; n = n.Parent {
+		delete(bc.invalidated, *n.Hash)
+	}
+	bc.invalidLock.Unlock()
+
+	return bc.activateBestChain()
+}
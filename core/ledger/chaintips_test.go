@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	. "DNA_POW/common"
+)
+
+// addSideNode links a side chain node with the work sum onto the parent
+func addSideNode(bc *Blockchain, parent *BlockNode, id byte, work int64) *BlockNode {
+	node := &BlockNode{
+		Hash:       &Uint256{id},
+		ParentHash: parent.Hash,
+		Height:     parent.Height + 1,
+		WorkSum:    big.NewInt(work),
+		Parent:     parent,
+	}
+	parent.Children = append(parent.Children, node)
+	bc.AddNodeToIndex(node)
+	return node
+}
+
+func TestChainTipsInvalidate(t *testing.T) {
+	bc, nodes := mainChain(10)
+	side8 := addSideNode(bc, nodes[7], 0xf8, 9)
+	side9 := addSideNode(bc, side8, 0xf9, 10)
+
+	tips := bc.tips()
+	if len(tips) != 2 {
+		t.Fatalf("got %d tips, want 2", len(tips))
+	}
+	for _, tip := range tips {
+		if tip != nodes[10] && tip != side9 {
+			t.Errorf("unexpected tip at height %d", tip.Height)
+		}
+	}
+	if fork := forkNode(side9); fork != nodes[7] {
+		t.Errorf("fork at height %d, want 7", fork.Height)
+	}
+
+	if err := bc.InvalidateBlock(*side8.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if !bc.isInvalid(*side8.Hash) || !bc.isInvalid(*side9.Hash) || bc.isInvalid(*nodes[7].Hash) {
+		t.Error("invalid marks are not on the block and its descendants only")
+	}
+	child := &Block{Blockdata: &Blockdata{PrevBlockHash: *side9.Hash, Height: 10}}
+	if err := bc.checkInvalidParent(child, side9); err == nil || !bc.isInvalid(child.Hash()) {
+		t.Error("block on an invalidated branch accepted")
+	}
+	if err := bc.checkInvalidParent(child, nodes[10]); err != nil {
+		t.Errorf("block on the best chain rejected: %v", err)
+	}
+
+	// A heavier side chain without its blocks cached does not take over
+	side11 := addSideNode(bc, side9, 0xfb, 100)
+	if err := bc.ReconsiderBlock(*side11.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if bc.isInvalid(*side8.Hash) || bc.isInvalid(*side11.Hash) {
+		t.Error("invalid marks are not removed from the ancestors")
+	}
+	if bc.BestChain != nodes[10] {
+		t.Error("best chain moved onto a branch without its blocks")
+	}
+
+	if err := bc.InvalidateBlock(Uint256{0xee}); err == nil {
+		t.Error("expected an error for an unknown block")
+	}
+}
@@ -18,13 +18,69 @@ var (
 	maxRetargetTimespan = int64(targetTimespan * config.Parameters.ChainParam.AdjustmentFactor)
 )
 
+// DifficultyAlgorithm calculates the difficulty bits required for the block
+// following prevNode.
+type DifficultyAlgorithm interface {
+	Name() string
+	NextRequiredDifficulty(prevNode *BlockNode, newBlockTime time.Time) (uint32, error)
+}
+
+var difficultyAlgorithms = map[string]DifficultyAlgorithm{}
+
+func init() {
+	RegisterDifficultyAlgorithm(&retargetDifficulty{})
+	params := config.Parameters.ChainParam
+	RegisterDifficultyAlgorithm(NewLWMA(params.LwmaAveragingWindow, params.TargetTimePerBlock, params.PowLimit))
+}
+
+// RegisterDifficultyAlgorithm makes the algorithm selectable by its name in
+// the chain parameters, an algorithm with the same name is replaced.
+func RegisterDifficultyAlgorithm(algo DifficultyAlgorithm) {
+	difficultyAlgorithms[algo.Name()] = algo
+}
+
+// difficultyAlgorithm returns the algorithm in effect for the block at height
+func difficultyAlgorithm(height uint32) (DifficultyAlgorithm, error) {
+	name := RetargetAlgorithm
+	params := config.Parameters.ChainParam
+	if params.DifficultyAlgorithm != "" && height >= params.DifficultyActivationHeight {
+		name = params.DifficultyAlgorithm
+	}
+	algo, ok := difficultyAlgorithms[name]
+	if !ok {
+		return nil, errors.New("unknown difficulty algorithm " + name)
+	}
+	return algo, nil
+}
+
 func CalcNextRequiredDifficulty(prevNode *BlockNode, newBlockTime time.Time) (uint32, error) {
 	// Genesis block.
-	if (prevNode.Height == 0) || (config.Parameters.ChainParam.Name == "RegNet") {
+	if prevNode.Height == 0 {
 		return uint32(config.Parameters.ChainParam.PowLimitBits), nil
 
 	}
 
+	algo, err := difficultyAlgorithm(prevNode.Height + 1)
+	if err != nil {
+		return 0, err
+	}
+	return algo.NextRequiredDifficulty(prevNode, newBlockTime)
+}
+
+// RetargetAlgorithm is the name of the Bitcoin style difficulty adjustment,
+// which retargets every blocksPerRetarget blocks.
+const RetargetAlgorithm = "retarget"
+
+type retargetDifficulty struct{}
+
+func (r *retargetDifficulty) Name() string {
+	return RetargetAlgorithm
+}
+
+func (r *retargetDifficulty) NextRequiredDifficulty(prevNode *BlockNode, newBlockTime time.Time) (uint32, error) {
+	if config.Parameters.ChainParam.PowNoRetargeting {
+		return uint32(config.Parameters.ChainParam.PowLimitBits), nil
+	}
 	// Return the previous block's difficulty requirements if this block
 	// is not at a difficulty retarget interval.
 	if (prevNode.Height+1)%blocksPerRetarget != 0 {
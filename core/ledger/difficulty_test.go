@@ -0,0 +1,207 @@
+package ledger
+
+import (
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"math"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	log.Init()
+	os.Exit(m.Run())
+}
+
+const testSpacing = 10 // Seconds
+
+var testPowLimit = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+
+// mineChain replays the solve times on top of the parent, each block gets
+// the bits the algorithm requires.
+func mineChain(t *testing.T, algo DifficultyAlgorithm, parent *BlockNode, solveTimes []int64) *BlockNode {
+	for _, solveTime := range solveTimes {
+		timestamp := uint32(int64(parent.Timestamp) + solveTime)
+		bits, err := algo.NextRequiredDifficulty(parent, time.Unix(int64(timestamp), 0))
+		if err != nil {
+			t.Fatalf("height %d: %v", parent.Height+1, err)
+		}
+		parent = &BlockNode{
+			Height:    parent.Height + 1,
+			Bits:      bits,
+			Timestamp: timestamp,
+			Parent:    parent,
+		}
+	}
+	return parent
+}
+
+func repeat(solveTime int64, n int) []int64 {
+	solveTimes := make([]int64, n)
+	for i := range solveTimes {
+		solveTimes[i] = solveTime
+	}
+	return solveTimes
+}
+
+func genesisNode(bits uint32) *BlockNode {
+	return &BlockNode{Height: 0, Bits: bits, Timestamp: 1500000000}
+}
+
+// ratio returns how many times the target of a is larger than the one of b
+func ratio(a, b uint32) float64 {
+	r, _ := new(big.Rat).SetFrac(CompactToBig(a), CompactToBig(b)).Float64()
+	return r
+}
+
+func TestLWMASteadyHashrate(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	const bits = 0x1e1da5ff
+	start := genesisNode(bits)
+	// Blocks found exactly on schedule keep the difficulty
+	tip := mineChain(t, lwma, start, repeat(testSpacing, 200))
+	if r := ratio(tip.Bits, bits); r < 0.99 || r > 1.01 {
+		t.Errorf("target changed by %f with steady solve times", r)
+	}
+}
+
+// mineWithHashrate mines n blocks with the hashrate relative to the one
+// which finds the blocks of baseBits on schedule.
+func mineWithHashrate(t *testing.T, algo DifficultyAlgorithm, parent *BlockNode, baseBits uint32,
+	hashrate float64, n int) *BlockNode {
+	for i := 0; i < n; i++ {
+		solveTime := int64(math.Floor(testSpacing*ratio(baseBits, parent.Bits)/hashrate + 0.5))
+		if solveTime < 1 {
+			solveTime = 1
+		}
+		parent = mineChain(t, algo, parent, []int64{solveTime})
+	}
+	return parent
+}
+
+func TestLWMAHashrateIncrease(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	const bits = 0x1e1da5ff
+	start := mineChain(t, lwma, genesisNode(bits), repeat(testSpacing, 100))
+
+	// Ten times the hashrate finds the blocks every second until the
+	// difficulty catches up.
+	tip := mineWithHashrate(t, lwma, start, bits, 10, 300)
+	if r := ratio(start.Bits, tip.Bits); r < 7 || r > 13 {
+		t.Errorf("difficulty rose by %f after ten times the hashrate", r)
+	}
+}
+
+func TestLWMAHashrateDrop(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	const bits = 0x1e1da5ff
+	start := mineChain(t, lwma, genesisNode(bits), repeat(testSpacing, 100))
+
+	// Most of the miners leave, the difficulty drops within a window
+	// instead of leaving the blocks stuck for hours.
+	tip := mineWithHashrate(t, lwma, start, bits, 0.1, 10)
+	if r := ratio(tip.Bits, start.Bits); r < 1.5 {
+		t.Errorf("difficulty only dropped by %f after 10 slow blocks", r)
+	}
+	tip = mineWithHashrate(t, lwma, tip, bits, 0.1, 300)
+	if r := ratio(tip.Bits, start.Bits); r < 7 || r > 13 {
+		t.Errorf("difficulty dropped by %f after a tenth of the hashrate", r)
+	}
+}
+
+func TestLWMATimestampManipulation(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	const bits = 0x1e1da5ff
+	start := mineChain(t, lwma, genesisNode(bits), repeat(testSpacing, 100))
+
+	// A block far in the future counts as 6 target spacings at most, and
+	// the block going back in time does not give the time back.
+	tip := mineChain(t, lwma, start, []int64{testSpacing * 1000, -testSpacing * 990})
+	if r := ratio(tip.Bits, start.Bits); r > 1.2 {
+		t.Errorf("target rose by %f after a forged timestamp", r)
+	}
+}
+
+func TestLWMAPowLimit(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	limitBits := BigToCompact(testPowLimit)
+	tip := mineChain(t, lwma, genesisNode(limitBits), repeat(testSpacing*100, 100))
+	if CompactToBig(tip.Bits).Cmp(testPowLimit) > 0 {
+		t.Errorf("target %08x is above the pow limit", tip.Bits)
+	}
+}
+
+func TestLWMAShortChain(t *testing.T) {
+	lwma := NewLWMA(60, testSpacing*time.Second, testPowLimit)
+	const bits = 0x1e1da5ff
+	// Fewer blocks than the window average over what is there
+	tip := mineChain(t, lwma, genesisNode(bits), repeat(testSpacing, 5))
+	if r := ratio(tip.Bits, bits); r < 0.99 || r > 1.01 {
+		t.Errorf("target changed by %f on a short chain", r)
+	}
+
+	// A window reaching past the pruned nodes is an error
+	orphan := &BlockNode{Height: 100, Bits: bits, Timestamp: 1500000000}
+	if _, err := lwma.NextRequiredDifficulty(orphan, time.Now()); err == nil {
+		t.Error("expected an error without the window blocks")
+	}
+}
+
+func TestDifficultyAlgorithmActivation(t *testing.T) {
+	saved := config.Parameters.ChainParam
+	defer func() { config.Parameters.ChainParam = saved }()
+	params := *saved
+	params.DifficultyAlgorithm = LwmaAlgorithm
+	params.DifficultyActivationHeight = 100
+	config.Parameters.ChainParam = &params
+
+	for _, test := range []struct {
+		height uint32
+		name   string
+	}{{1, RetargetAlgorithm}, {99, RetargetAlgorithm}, {100, LwmaAlgorithm}, {1000, LwmaAlgorithm}} {
+		algo, err := difficultyAlgorithm(test.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if algo.Name() != test.name {
+			t.Errorf("height %d: got algorithm %s, want %s", test.height, algo.Name(), test.name)
+		}
+	}
+
+	params.DifficultyAlgorithm = "unknown"
+	if _, err := difficultyAlgorithm(100); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
+
+func TestPowNoRetargeting(t *testing.T) {
+	saved := config.Parameters.ChainParam
+	defer func() { config.Parameters.ChainParam = saved }()
+	params := *saved
+	params.PowNoRetargeting = true
+	config.Parameters.ChainParam = &params
+
+	// The retarget keeps the pow limit on a net without retargeting
+	parent := mineChain(t, &retargetDifficulty{}, genesisNode(0x1d00ffff), repeat(1, int(blocksPerRetarget)+1))
+	bits, err := CalcNextRequiredDifficulty(parent, time.Unix(int64(parent.Timestamp)+1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits != params.PowLimitBits {
+		t.Errorf("got bits %08x, want the pow limit %08x", bits, params.PowLimitBits)
+	}
+
+	// LWMA selected on the net still adjusts
+	params.DifficultyAlgorithm = LwmaAlgorithm
+	params.DifficultyActivationHeight = 1
+	lwma := difficultyAlgorithms[LwmaAlgorithm]
+	want, err := lwma.NextRequiredDifficulty(parent, time.Unix(int64(parent.Timestamp)+1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits, _ := CalcNextRequiredDifficulty(parent, time.Unix(int64(parent.Timestamp)+1, 0)); bits != want {
+		t.Errorf("got bits %08x, want the lwma bits %08x", bits, want)
+	}
+}
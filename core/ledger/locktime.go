@@ -0,0 +1,173 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	tx "DNA_POW/core/transaction"
+)
+
+const (
+	// LockTimeThreshold is the number below which a lock time is a block
+	// height, and from which it is a Unix time.
+	LockTimeThreshold = 500000000
+
+	// SequenceFinal is the sequence of an input which does not prevent the
+	// transaction from being final. Before the csv deployment the lock time
+	// is only a block height and the final sequence is SequenceFinalLegacy.
+	SequenceFinal       = math.MaxUint32
+	SequenceFinalLegacy = math.MaxUint16
+
+	// The bits of the input sequence for the relative lock time, the same
+	// as BIP68. With SequenceLockTimeIsSeconds the lock is in units of
+	// 2^SequenceLockTimeGranularity seconds, otherwise in blocks.
+	SequenceLockTimeDisabled    = 1 << 31
+	SequenceLockTimeIsSeconds   = 1 << 22
+	SequenceLockTimeMask        = 0x0000ffff
+	SequenceLockTimeGranularity = 9
+
+	// DeploymentCSV is the deployment which enforces the relative lock times
+	DeploymentCSV = "csv"
+)
+
+// SequenceLock is the latest block height and median time past which the
+// relative lock times of a transaction do not allow, -1 means no lock.
+type SequenceLock struct {
+	Seconds     int64
+	BlockHeight int64
+}
+
+// IsFinalizedTransaction reports whether the lock time of the transaction
+// allows it in a block at blockHeight whose previous blocks have the median
+// time past blockTime. csvActive tells whether the csv deployment is active
+// for the block.
+func IsFinalizedTransaction(msgTx *tx.Transaction, blockHeight uint32, blockTime time.Time, csvActive bool) bool {
+	// Lock time of zero means the transaction is finalized.
+	lockTime := msgTx.LockTime
+	if lockTime == 0 {
+		return true
+	}
+
+	// The lock time is either a block height or a Unix time
+	blockTimeOrHeight := int64(blockHeight)
+	if csvActive && lockTime >= LockTimeThreshold {
+		blockTimeOrHeight = blockTime.Unix()
+	}
+	if int64(lockTime) < blockTimeOrHeight {
+		return true
+	}
+
+	// At this point, the transaction's lock time hasn't occurred yet, but
+	// the transaction might still be finalized if the sequence number
+	// for all transaction inputs is maxed out.
+	sequenceFinal := uint32(SequenceFinalLegacy)
+	if csvActive {
+		sequenceFinal = SequenceFinal
+	}
+	for _, txIn := range msgTx.UTXOInputs {
+		if txIn.Sequence != sequenceFinal {
+			return false
+		}
+	}
+	return true
+}
+
+// SequenceLockActive reports whether the sequence lock allows the transaction
+// in a block at blockHeight whose previous blocks have the median time past
+// medianTime.
+func SequenceLockActive(lock *SequenceLock, blockHeight uint32, medianTime time.Time) bool {
+	return lock.Seconds < medianTime.Unix() && lock.BlockHeight < int64(blockHeight)
+}
+
+// inputHeights returns the heights of the blocks including the transactions
+// referenced by the inputs. The transactions which are not in the ledger yet
+// are counted at nextHeight, they come earlier in the same block.
+func (bc *Blockchain) inputHeights(txn *tx.Transaction, nextHeight uint32) []uint32 {
+	heights := make([]uint32, len(txn.UTXOInputs))
+	for i, input := range txn.UTXOInputs {
+		heights[i] = nextHeight
+		if input.Sequence&SequenceLockTimeDisabled != 0 {
+			continue
+		}
+		if _, height, err := bc.Ledger.Store.GetTransaction(input.ReferTxID); err == nil {
+			heights[i] = height
+		}
+	}
+	return heights
+}
+
+// CalcSequenceLock returns the relative lock times of the transaction for the
+// block after prevNode.
+func (bc *Blockchain) CalcSequenceLock(txn *tx.Transaction, prevNode *BlockNode) (*SequenceLock, error) {
+	return bc.sequenceLock(txn, bc.inputHeights(txn, prevNode.Height+1), prevNode)
+}
+
+func (bc *Blockchain) sequenceLock(txn *tx.Transaction, inputHeights []uint32, prevNode *BlockNode) (*SequenceLock, error) {
+	lock := &SequenceLock{Seconds: -1, BlockHeight: -1}
+	if txn.IsCoinBaseTx() {
+		return lock, nil
+	}
+
+	for i, input := range txn.UTXOInputs {
+		sequence := input.Sequence
+		if sequence&SequenceLockTimeDisabled != 0 {
+			continue
+		}
+		inputHeight := inputHeights[i]
+		relativeLock := int64(sequence & SequenceLockTimeMask)
+
+		if sequence&SequenceLockTimeIsSeconds != 0 {
+			// The time lock counts from the median time past of the block
+			// before the one including the input.
+			prevHeight := inputHeight
+			if prevHeight > 0 {
+				prevHeight--
+			}
+			node, err := bc.ancestor(prevNode, prevHeight)
+			if err != nil {
+				return nil, err
+			}
+			if node == nil {
+				return nil, errors.New("unable to obtain the block of the input")
+			}
+			medianTime := CalcPastMedianTime(node).Unix()
+			timeLock := medianTime + relativeLock<<SequenceLockTimeGranularity - 1
+			if timeLock > lock.Seconds {
+				lock.Seconds = timeLock
+			}
+		} else {
+			blockHeight := int64(inputHeight) + relativeLock - 1
+			if blockHeight > lock.BlockHeight {
+				lock.BlockHeight = blockHeight
+			}
+		}
+	}
+	return lock, nil
+}
+
+// CheckTransactionLocks checks the lock time and, after the csv deployment,
+// the time lock and the relative lock times of the transaction allow it in
+// the block after prevNode.
+func (bc *Blockchain) CheckTransactionLocks(txn *tx.Transaction, prevNode *BlockNode) error {
+	csvActive, err := bc.IsDeploymentActive(prevNode, DeploymentCSV)
+	if err != nil {
+		return err
+	}
+	blockHeight := prevNode.Height + 1
+	medianTime := CalcPastMedianTime(prevNode)
+	if !IsFinalizedTransaction(txn, blockHeight, medianTime, csvActive) {
+		return errors.New("transaction lock time is not reached")
+	}
+	if !csvActive {
+		return nil
+	}
+	lock, err := bc.CalcSequenceLock(txn, prevNode)
+	if err != nil {
+		return err
+	}
+	if !SequenceLockActive(lock, blockHeight, medianTime) {
+		return errors.New("transaction relative lock time is not reached")
+	}
+	return nil
+}
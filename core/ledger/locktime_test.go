@@ -0,0 +1,87 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	tx "DNA_POW/core/transaction"
+)
+
+func lockTimeTx(lockTime uint32, sequences ...uint32) *tx.Transaction {
+	txn := &tx.Transaction{TxType: tx.TransferAsset, LockTime: lockTime}
+	for _, sequence := range sequences {
+		txn.UTXOInputs = append(txn.UTXOInputs, &tx.UTXOTxInput{Sequence: sequence})
+	}
+	return txn
+}
+
+func TestIsFinalizedTransaction(t *testing.T) {
+	blockTime := time.Unix(1500000000, 0)
+	tests := []struct {
+		name      string
+		txn       *tx.Transaction
+		height    uint32
+		finalized bool
+		legacy    bool // Before the csv deployment
+	}{
+		{"no lock time", lockTimeTx(0, 0), 100, true, true},
+		{"height reached", lockTimeTx(99, 0), 100, true, true},
+		{"height not reached", lockTimeTx(100, 0), 100, false, false},
+		{"height not reached with final inputs", lockTimeTx(100, SequenceFinal, SequenceFinal), 100, true, false},
+		{"height not reached with legacy final inputs", lockTimeTx(100, SequenceFinalLegacy, SequenceFinalLegacy), 100, false, true},
+		{"height not reached with one input not final", lockTimeTx(100, SequenceFinal, 0), 100, false, false},
+		{"time reached", lockTimeTx(1499999999, 0), 100, true, false},
+		{"time not reached", lockTimeTx(1500000000, 0), 100, false, false},
+		{"time not reached at a large height", lockTimeTx(1500000000, 0), LockTimeThreshold + 1, false, false},
+		{"time not reached but reached as a height", lockTimeTx(1500000000, 0), 1500000001, false, true},
+	}
+	for _, test := range tests {
+		if finalized := IsFinalizedTransaction(test.txn, test.height, blockTime, true); finalized != test.finalized {
+			t.Errorf("%s: got %v, want %v", test.name, finalized, test.finalized)
+		}
+		if finalized := IsFinalizedTransaction(test.txn, test.height, blockTime, false); finalized != test.legacy {
+			t.Errorf("%s before csv: got %v, want %v", test.name, finalized, test.legacy)
+		}
+	}
+}
+
+func TestSequenceLock(t *testing.T) {
+	const startTime = 1500000000
+	vc := newVersionChain(startTime)
+	vc.add(VBTopBits, 50)
+
+	// A relative lock of 10 blocks on an input at height 30
+	lock, err := vc.bc.sequenceLock(lockTimeTx(0, 10), []uint32{30}, vc.tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.BlockHeight != 39 || lock.Seconds != -1 {
+		t.Fatalf("block lock %+v, want height 39", lock)
+	}
+	if SequenceLockActive(lock, 39, time.Unix(startTime, 0)) || !SequenceLockActive(lock, 40, time.Unix(startTime, 0)) {
+		t.Error("block lock is not active from height 40")
+	}
+
+	// A relative lock of 2*512 seconds on an input at height 30 counts from
+	// the median time past of block 29, the timestamp of block 24.
+	lock, err = vc.bc.sequenceLock(lockTimeTx(0, SequenceLockTimeIsSeconds|2, 5), []uint32{30, 20}, vc.tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSeconds := int64(startTime + 24*60 + 2<<SequenceLockTimeGranularity - 1)
+	if lock.Seconds != wantSeconds || lock.BlockHeight != 24 {
+		t.Fatalf("time lock %+v, want seconds %d and height 24", lock, wantSeconds)
+	}
+	if SequenceLockActive(lock, 50, time.Unix(wantSeconds, 0)) || !SequenceLockActive(lock, 50, time.Unix(wantSeconds+1, 0)) {
+		t.Error("time lock is not active after its median time past")
+	}
+
+	// Inputs with the disable flag have no relative lock
+	lock, err = vc.bc.sequenceLock(lockTimeTx(0, SequenceLockTimeDisabled|10, SequenceFinal), []uint32{30, 30}, vc.tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.BlockHeight != -1 || lock.Seconds != -1 {
+		t.Errorf("disabled lock %+v, want no lock", lock)
+	}
+}
@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"DNA_POW/common/log"
+)
+
+// LwmaAlgorithm is the name of the linearly weighted moving average
+// difficulty adjustment.
+const LwmaAlgorithm = "lwma"
+
+// LWMA adjusts the difficulty every block from the targets and solve times of
+// the last Window blocks, the recent solve times weigh more so the difficulty
+// follows hashrate swings within a few blocks.
+type LWMA struct {
+	Window        uint32
+	TargetSpacing int64 // Seconds
+	PowLimit      *big.Int
+}
+
+func NewLWMA(window uint32, targetSpacing time.Duration, powLimit *big.Int) *LWMA {
+	return &LWMA{
+		Window:        window,
+		TargetSpacing: int64(targetSpacing / time.Second),
+		PowLimit:      powLimit,
+	}
+}
+
+func (l *LWMA) Name() string {
+	return LwmaAlgorithm
+}
+
+func (l *LWMA) NextRequiredDifficulty(prevNode *BlockNode, newBlockTime time.Time) (uint32, error) {
+	if l.Window == 0 || l.TargetSpacing <= 0 {
+		return 0, errors.New("invalid lwma parameters")
+	}
+	// Average over the blocks since the genesis while the chain is shorter
+	// than the window.
+	n := l.Window
+	if prevNode.Height < n {
+		n = prevNode.Height
+	}
+	if n == 0 {
+		return prevNode.Bits, nil
+	}
+
+	// Collect the window from the oldest to the newest, along with the
+	// block before it to time the first solve.
+	nodes := make([]*BlockNode, n+1)
+	node := prevNode
+	for i := int(n); i >= 0; i-- {
+		if node == nil {
+			return 0, errors.New("unable to obtain the lwma window blocks")
+		}
+		nodes[i] = node
+		node = node.Parent
+	}
+
+	// A timestamp earlier than the previous one counts as one second, and a
+	// single solve time is limited to 6 target spacings so a forged time
+	// can not drop the difficulty too much.
+	maxSolveTime := 6 * l.TargetSpacing
+	prevTimestamp := int64(nodes[0].Timestamp)
+	weightedSolveTimes := int64(0)
+	sumTarget := new(big.Int)
+	for i := uint32(1); i <= n; i++ {
+		timestamp := int64(nodes[i].Timestamp)
+		if timestamp <= prevTimestamp {
+			timestamp = prevTimestamp + 1
+		}
+		solveTime := timestamp - prevTimestamp
+		if solveTime > maxSolveTime {
+			solveTime = maxSolveTime
+		}
+		prevTimestamp = timestamp
+
+		weightedSolveTimes += solveTime * int64(i)
+		sumTarget.Add(sumTarget, CompactToBig(nodes[i].Bits))
+	}
+
+	// nextTarget = avgTarget * weightedSolveTimes / (k * TargetSpacing),
+	// k = n * (n + 1) / 2 is the sum of the weights.
+	k := int64(n) * int64(n+1) / 2
+	newTarget := new(big.Int).Mul(sumTarget, big.NewInt(weightedSolveTimes))
+	newTarget.Div(newTarget, big.NewInt(k*l.TargetSpacing*int64(n)))
+
+	if newTarget.Cmp(l.PowLimit) > 0 {
+		newTarget.Set(l.PowLimit)
+	}
+	newTargetBits := BigToCompact(newTarget)
+	log.Tracef("LWMA difficulty at block height %d, new target %08x", prevNode.Height+1, newTargetBits)
+
+	return newTargetBits, nil
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,8 +30,36 @@ var (
 	// median time data.  This is a variable as opposed to a constant so the
 	// test code can modify it.
 	maxMedianTimeEntries = 200
+
+	// mockTime replaces the local clock of the time sources when it is not
+	// zero, in Unix seconds.  It is set by the regression tests.
+	mockTime int64
 )
 
+// SetMockTime replaces the local clock of the time sources with the time, the
+// zero time restores the local clock.
+func SetMockTime(t time.Time) {
+	if t.IsZero() {
+		atomic.StoreInt64(&mockTime, 0)
+		return
+	}
+	atomic.StoreInt64(&mockTime, t.Unix())
+}
+
+// MockTime returns the mock time and whether it is set
+func MockTime() (time.Time, bool) {
+	t := atomic.LoadInt64(&mockTime)
+	return time.Unix(t, 0), t != 0
+}
+
+// localTime returns the local clock, or the mock time when it is set.
+func localTime() time.Time {
+	if t, ok := MockTime(); ok {
+		return t
+	}
+	return time.Now()
+}
+
 // MedianTimeSource provides a mechanism to add several time samples which are
 // used to determine a median time which is then used as an offset to the local
 // clock.
@@ -96,7 +125,7 @@ func (m *medianTime) AdjustedTime() time.Time {
 	defer m.mtx.Unlock()
 
 	// Limit the adjusted time to 1 second precision.
-	now := time.Unix(time.Now().Unix(), 0)
+	now := time.Unix(localTime().Unix(), 0)
 	return now.Add(time.Duration(m.offsetSecs) * time.Second)
 }
 
@@ -119,7 +148,7 @@ func (m *medianTime) AddTimeSample(sourceID string, timeVal time.Time) {
 	// of offsets while respecting the maximum number of allowed entries by
 	// replacing the oldest entry with the new entry once the maximum number
 	// of entries is reached.
-	now := time.Unix(time.Now().Unix(), 0)
+	now := time.Unix(localTime().Unix(), 0)
 	offsetSecs := int64(timeVal.Sub(now).Seconds())
 	numOffsets := len(m.offsets)
 	if numOffsets == maxMedianTimeEntries && maxMedianTimeEntries > 0 {
@@ -0,0 +1,25 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockTime(t *testing.T) {
+	defer SetMockTime(time.Time{})
+
+	mock := time.Unix(1500000000, 0)
+	SetMockTime(mock)
+	timeSource := NewMedianTime()
+	if adjusted := timeSource.AdjustedTime(); !adjusted.Equal(mock) {
+		t.Fatalf("adjusted time %v, want the mock time %v", adjusted, mock)
+	}
+
+	SetMockTime(time.Time{})
+	if _, ok := MockTime(); ok {
+		t.Fatal("mock time is still set")
+	}
+	if adjusted := timeSource.AdjustedTime(); time.Since(adjusted) > time.Minute {
+		t.Errorf("adjusted time %v is not the local clock", adjusted)
+	}
+}
@@ -0,0 +1,140 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"DNA_POW/events"
+)
+
+// maxReorgRejections is the number of the latest rejected reorganizations
+// kept for the RPC.
+const maxReorgRejections = 100
+
+// maxQuarantinedBlocks bounds the quarantine, the blocks below the max reorg
+// depth are dropped first as their fork is rejected again however deep.
+const maxQuarantinedBlocks = 10000
+
+// ReorgRejection describes a block rejected for forking the best chain deeper
+// than the MaxReorgDepth. It is the value of the EventReorgRejected event.
+type ReorgRejection struct {
+	Hash       Uint256
+	Height     uint32
+	ForkHeight uint32
+	BestHeight uint32
+	Depth      uint32
+	Time       time.Time
+}
+
+// isQuarantined reports whether the block was rejected for a deep reorg or
+// builds on such a block.
+func (bc *Blockchain) isQuarantined(hash Uint256) bool {
+	_, ok := bc.quarantinedHeight(hash)
+	return ok
+}
+
+func (bc *Blockchain) quarantinedHeight(hash Uint256) (uint32, bool) {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+	height, ok := bc.quarantined[hash]
+	return height, ok
+}
+
+func (bc *Blockchain) quarantine(hash Uint256, height uint32) {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+	bc.quarantined[hash] = height
+	if len(bc.quarantined) <= maxQuarantinedBlocks {
+		return
+	}
+
+	maxDepth := config.Parameters.ChainParam.MaxReorgDepth
+	if bc.BestChain != nil && bc.BestChain.Height > maxDepth {
+		lowest := bc.BestChain.Height - maxDepth
+		for h, height := range bc.quarantined {
+			if height < lowest {
+				delete(bc.quarantined, h)
+			}
+		}
+	}
+	for h := range bc.quarantined {
+		if len(bc.quarantined) <= maxQuarantinedBlocks {
+			break
+		}
+		if h != hash {
+			delete(bc.quarantined, h)
+		}
+	}
+}
+
+// checkQuarantine rejects the blocks building on a quarantined branch, which
+// are quarantined in turn at the height above their parent.
+func (bc *Blockchain) checkQuarantine(block *Block) error {
+	hash := block.Hash()
+	if bc.isQuarantined(hash) {
+		return fmt.Errorf("block %x is quarantined", hash.ToArrayReverse())
+	}
+	if height, ok := bc.quarantinedHeight(block.Blockdata.PrevBlockHash); ok {
+		bc.quarantine(hash, height+1)
+		return fmt.Errorf("block %x extends a quarantined branch", hash.ToArrayReverse())
+	}
+	return nil
+}
+
+// checkReorgDepth rejects the block when it forks the best chain deeper than
+// the MaxReorgDepth, however much work its branch has. The block is
+// quarantined and the EventReorgRejected event fires.
+func (bc *Blockchain) checkReorgDepth(block *Block, prevNode *BlockNode) error {
+	maxDepth := config.Parameters.ChainParam.MaxReorgDepth
+	if maxDepth == 0 || prevNode == nil || bc.BestChain == nil {
+		return nil
+	}
+
+	fork := prevNode
+	for fork != nil && !fork.InMainChain {
+		fork = fork.Parent
+	}
+	if fork == nil || bc.BestChain.Height-fork.Height <= maxDepth {
+		return nil
+	}
+
+	hash := block.Hash()
+	rejection := &ReorgRejection{
+		Hash:       hash,
+		Height:     prevNode.Height + 1,
+		ForkHeight: fork.Height,
+		BestHeight: bc.BestChain.Height,
+		Depth:      bc.BestChain.Height - fork.Height,
+		Time:       time.Now(),
+	}
+	bc.quarantine(hash, rejection.Height)
+	bc.reorgLock.Lock()
+	bc.reorgRejections = append(bc.reorgRejections, rejection)
+	if len(bc.reorgRejections) > maxReorgRejections {
+		bc.reorgRejections = bc.reorgRejections[1:]
+	}
+	bc.reorgLock.Unlock()
+
+	log.Warnf("REORG REJECTED: Block %x forks the chain at height %d, %d blocks "+
+		"below the best height %d, the max reorg depth is %d",
+		hash.ToArrayReverse(), fork.Height, rejection.Depth, rejection.BestHeight, maxDepth)
+	bc.BCEvents.Notify(events.EventReorgRejected, rejection)
+
+	return fmt.Errorf("block %x forks the chain %d blocks deep, beyond the max reorg depth %d",
+		hash.ToArrayReverse(), rejection.Depth, maxDepth)
+}
+
+// ReorgRejections returns the latest rejected reorganizations, the oldest
+// first.
+func (bc *Blockchain) ReorgRejections() []ReorgRejection {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+	rejections := make([]ReorgRejection, len(bc.reorgRejections))
+	for i, r := range bc.reorgRejections {
+		rejections[i] = *r
+	}
+	return rejections
+}
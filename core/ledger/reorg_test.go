@@ -0,0 +1,114 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+)
+
+func withMaxReorgDepth(depth uint32) func() {
+	saved := config.Parameters.ChainParam
+	params := *saved
+	params.MaxReorgDepth = depth
+	config.Parameters.ChainParam = &params
+	return func() { config.Parameters.ChainParam = saved }
+}
+
+// mainChain returns a best chain of the height and its nodes by height.
+func mainChain(height int) (*Blockchain, []*BlockNode) {
+	vc := newVersionChain(1500000000)
+	nodes := []*BlockNode{vc.tip}
+	for i := 0; i < height; i++ {
+		vc.add(VBTopBits, 1)
+		nodes = append(nodes, vc.tip)
+	}
+	for i, node := range nodes {
+		node.InMainChain = true
+		node.WorkSum = big.NewInt(int64(i + 1))
+		if i > 0 {
+			nodes[i-1].Children = []*BlockNode{node}
+		}
+		vc.bc.AddNodeToIndex(node)
+	}
+	vc.bc.BestChain = vc.tip
+	return vc.bc, nodes
+}
+
+func sideBlock(prevNode *BlockNode, nonce uint32) *Block {
+	return &Block{Blockdata: &Blockdata{
+		PrevBlockHash: *prevNode.Hash,
+		Height:        prevNode.Height + 1,
+		Nonce:         nonce,
+	}}
+}
+
+func TestCheckReorgDepth(t *testing.T) {
+	defer withMaxReorgDepth(10)()
+	bc, nodes := mainChain(30)
+
+	// Forking 10 blocks below the tip is allowed
+	if err := bc.checkReorgDepth(sideBlock(nodes[20], 1), nodes[20]); err != nil {
+		t.Fatalf("fork at the max depth rejected: %v", err)
+	}
+
+	// A side chain node whose fork point is 11 blocks deep
+	side := &BlockNode{Hash: &Uint256{0xff}, ParentHash: nodes[19].Hash, Height: 20, Parent: nodes[19]}
+	deep := sideBlock(side, 2)
+	if err := bc.checkReorgDepth(deep, side); err == nil {
+		t.Fatal("fork beyond the max depth accepted")
+	}
+	rejections := bc.ReorgRejections()
+	if len(rejections) != 1 || rejections[0].Hash != deep.Hash() ||
+		rejections[0].ForkHeight != 19 || rejections[0].Depth != 11 {
+		t.Fatalf("unexpected rejections %+v", rejections)
+	}
+
+	// The rejected block and its descendants are quarantined
+	if err := bc.checkQuarantine(deep); err == nil {
+		t.Error("rejected block is not quarantined")
+	}
+	child := &Block{Blockdata: &Blockdata{PrevBlockHash: deep.Hash(), Height: 22}}
+	if err := bc.checkQuarantine(child); err == nil {
+		t.Error("descendant of a rejected block is not quarantined")
+	}
+	if !bc.isQuarantined(child.Hash()) {
+		t.Error("descendant is not added to the quarantine")
+	}
+	if err := bc.checkQuarantine(sideBlock(nodes[30], 3)); err != nil {
+		t.Errorf("block on the best chain quarantined: %v", err)
+	}
+}
+
+func TestCheckReorgDepthDisabled(t *testing.T) {
+	defer withMaxReorgDepth(0)()
+	bc, nodes := mainChain(30)
+	if err := bc.checkReorgDepth(sideBlock(nodes[1], 1), nodes[1]); err != nil {
+		t.Errorf("fork rejected without a max depth: %v", err)
+	}
+	if len(bc.ReorgRejections()) != 0 {
+		t.Error("rejection recorded without a max depth")
+	}
+}
+
+func TestQuarantineBounded(t *testing.T) {
+	defer withMaxReorgDepth(10)()
+	bc, _ := mainChain(30)
+
+	// The blocks below the max reorg depth are dropped first
+	bc.quarantine(Uint256{1}, 5)
+	for i := 0; i < maxQuarantinedBlocks; i++ {
+		bc.quarantine(Uint256{2, byte(i), byte(i >> 8)}, 25)
+	}
+	if len(bc.quarantined) > maxQuarantinedBlocks {
+		t.Fatalf("%d blocks quarantined", len(bc.quarantined))
+	}
+	if bc.isQuarantined(Uint256{1}) {
+		t.Error("block below the max reorg depth kept in the quarantine")
+	}
+	bc.quarantine(Uint256{3}, 25)
+	if len(bc.quarantined) > maxQuarantinedBlocks || !bc.isQuarantined(Uint256{3}) {
+		t.Error("quarantine exceeds its bound or misses the latest block")
+	}
+}
@@ -0,0 +1,214 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+)
+
+const (
+	// VBTopBits is the top bits of the block version which signal the
+	// deployments with the lower bits, as described by BIP9.
+	VBTopBits = 0x20000000
+	VBTopMask = 0xe0000000
+	VBNumBits = 29
+)
+
+// ThresholdState is the BIP9 state of a deployment
+type ThresholdState byte
+
+const (
+	ThresholdDefined ThresholdState = iota
+	ThresholdStarted
+	ThresholdLockedIn
+	ThresholdActive
+	ThresholdFailed
+)
+
+func (t ThresholdState) String() string {
+	switch t {
+	case ThresholdDefined:
+		return "defined"
+	case ThresholdStarted:
+		return "started"
+	case ThresholdLockedIn:
+		return "locked_in"
+	case ThresholdActive:
+		return "active"
+	case ThresholdFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+func getDeployment(name string) (*config.ConsensusDeployment, error) {
+	for i, d := range config.Parameters.ChainParam.Deployments {
+		if d.Name == name {
+			return &config.Parameters.ChainParam.Deployments[i], nil
+		}
+	}
+	return nil, errors.New("unknown deployment " + name)
+}
+
+// isSignalling reports whether the block version signals the deployment
+func isSignalling(version uint32, d *config.ConsensusDeployment) bool {
+	return version&VBTopMask == VBTopBits && version&(uint32(1)<<d.BitNumber) != 0
+}
+
+// ancestor returns the node at height on the chain of node, the nodes pruned
+// from memory are loaded from the store.
+func (bc *Blockchain) ancestor(node *BlockNode, height uint32) (*BlockNode, error) {
+	var err error
+	for node != nil && node.Height > height {
+		node, err = bc.GetPrevNodeFromNode(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (bc *Blockchain) cachedState(name string, hash Uint256) (ThresholdState, bool) {
+	bc.deploymentLock.Lock()
+	defer bc.deploymentLock.Unlock()
+	state, ok := bc.deploymentStates[name][hash]
+	return state, ok
+}
+
+func (bc *Blockchain) cacheState(name string, hash Uint256, state ThresholdState) {
+	bc.deploymentLock.Lock()
+	defer bc.deploymentLock.Unlock()
+	if bc.deploymentStates[name] == nil {
+		bc.deploymentStates[name] = make(map[Uint256]ThresholdState)
+	}
+	bc.deploymentStates[name][hash] = state
+}
+
+// ThresholdState returns the state of the deployment for the block after
+// prevNode. The state only changes at the confirmation window boundaries, it
+// is cached by the last block of each window.
+func (bc *Blockchain) ThresholdState(prevNode *BlockNode, name string) (ThresholdState, error) {
+	d, err := getDeployment(name)
+	if err != nil {
+		return ThresholdDefined, err
+	}
+	params := config.Parameters.ChainParam
+	window := params.MinerConfirmationWindow
+
+	// The last block of the previous confirmation window
+	if prevNode == nil || (prevNode.Height+1)%window > prevNode.Height {
+		return ThresholdDefined, nil
+	}
+	prevNode, err = bc.ancestor(prevNode, prevNode.Height-(prevNode.Height+1)%window)
+	if err != nil {
+		return ThresholdDefined, err
+	}
+
+	// Walk back the windows until a known state
+	var neededStates []*BlockNode
+	state := ThresholdDefined
+	for prevNode != nil {
+		if cached, ok := bc.cachedState(name, *prevNode.Hash); ok {
+			state = cached
+			break
+		}
+		if CalcPastMedianTime(prevNode).Unix() < d.StartTime {
+			bc.cacheState(name, *prevNode.Hash, ThresholdDefined)
+			break
+		}
+		neededStates = append(neededStates, prevNode)
+		if prevNode.Height < window {
+			break
+		}
+		prevNode, err = bc.ancestor(prevNode, prevNode.Height-window)
+		if err != nil {
+			return ThresholdDefined, err
+		}
+	}
+
+	// Move the state forward window by window
+	for i := len(neededStates) - 1; i >= 0; i-- {
+		node := neededStates[i]
+		medianTime := CalcPastMedianTime(node).Unix()
+		switch state {
+		case ThresholdDefined:
+			if medianTime >= d.ExpireTime {
+				state = ThresholdFailed
+			} else if medianTime >= d.StartTime {
+				state = ThresholdStarted
+			}
+
+		case ThresholdStarted:
+			if medianTime >= d.ExpireTime {
+				state = ThresholdFailed
+				break
+			}
+			count := uint32(0)
+			countNode := node
+			for j := uint32(0); ; j++ {
+				if isSignalling(countNode.Version, d) {
+					count++
+				}
+				if j == window-1 {
+					break
+				}
+				countNode, err = bc.GetPrevNodeFromNode(countNode)
+				if err != nil {
+					return ThresholdDefined, err
+				}
+			}
+			if count >= params.RuleChangeActivationThreshold {
+				state = ThresholdLockedIn
+			}
+
+		case ThresholdLockedIn:
+			state = ThresholdActive
+		}
+		bc.cacheState(name, *node.Hash, state)
+	}
+	return state, nil
+}
+
+// IsDeploymentActive reports whether the rules of the deployment apply to
+// the block after prevNode.
+func (bc *Blockchain) IsDeploymentActive(prevNode *BlockNode, name string) (bool, error) {
+	state, err := bc.ThresholdState(prevNode, name)
+	if err != nil {
+		return false, err
+	}
+	return state == ThresholdActive, nil
+}
+
+// IsDeploymentActiveAtHeight reports whether the rules of the deployment
+// apply to the block at height of the best chain.
+func (bc *Blockchain) IsDeploymentActiveAtHeight(name string, height uint32) (bool, error) {
+	if height == 0 {
+		return false, nil
+	}
+	if height > bc.BestChain.Height+1 {
+		return false, fmt.Errorf("height %d is beyond the next block", height)
+	}
+	prevNode, err := bc.ancestor(bc.BestChain, height-1)
+	if err != nil {
+		return false, err
+	}
+	return bc.IsDeploymentActive(prevNode, name)
+}
+
+// ComputeBlockVersion returns the version of the block after prevNode, which
+// signals all the deployments in the started or locked in state.
+func (bc *Blockchain) ComputeBlockVersion(prevNode *BlockNode) (uint32, error) {
+	version := uint32(VBTopBits)
+	for _, d := range config.Parameters.ChainParam.Deployments {
+		state, err := bc.ThresholdState(prevNode, d.Name)
+		if err != nil {
+			return 0, err
+		}
+		if state == ThresholdStarted || state == ThresholdLockedIn {
+			version |= uint32(1) << d.BitNumber
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,149 @@
+package ledger
+
+import (
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+const testWindow = 10
+
+func withDeploymentParams(t *testing.T, d config.ConsensusDeployment) func() {
+	saved := config.Parameters.ChainParam
+	params := *saved
+	params.MinerConfirmationWindow = testWindow
+	params.RuleChangeActivationThreshold = 8
+	params.Deployments = []config.ConsensusDeployment{d}
+	config.Parameters.ChainParam = &params
+	return func() { config.Parameters.ChainParam = saved }
+}
+
+// versionChain links a chain from the genesis with the block versions, the
+// blocks are one minute apart from the time.
+type versionChain struct {
+	bc  *Blockchain
+	tip *BlockNode
+}
+
+func newVersionChain(startTime uint32) *versionChain {
+	genesis := &BlockNode{Hash: new(Uint256), ParentHash: new(Uint256), Timestamp: startTime}
+	bc := NewBlockchain(0, nil)
+	bc.GenesisHash = *genesis.Hash
+	return &versionChain{bc: bc, tip: genesis}
+}
+
+func (vc *versionChain) add(version uint32, n int) {
+	for i := 0; i < n; i++ {
+		node := &BlockNode{
+			Hash:       new(Uint256),
+			ParentHash: vc.tip.Hash,
+			Height:     vc.tip.Height + 1,
+			Version:    version,
+			Timestamp:  vc.tip.Timestamp + 60,
+			Parent:     vc.tip,
+		}
+		binary.LittleEndian.PutUint32(node.Hash[:], node.Height)
+		vc.tip = node
+	}
+}
+
+func (vc *versionChain) state(t *testing.T) ThresholdState {
+	state, err := vc.bc.ThresholdState(vc.tip, "testdummy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestThresholdStateActivation(t *testing.T) {
+	defer withDeploymentParams(t, config.ConsensusDeployment{
+		Name: "testdummy", BitNumber: 28, StartTime: 0, ExpireTime: math.MaxInt64,
+	})()
+	signal := uint32(VBTopBits | 1<<28)
+
+	vc := newVersionChain(1500000000)
+	vc.add(VBTopBits, testWindow-2)
+	if state := vc.state(t); state != ThresholdDefined {
+		t.Fatalf("first window: got %v, want defined", state)
+	}
+	vc.add(VBTopBits, 1)
+	if state := vc.state(t); state != ThresholdStarted {
+		t.Fatalf("after the first window: got %v, want started", state)
+	}
+	version, err := vc.bc.ComputeBlockVersion(vc.tip)
+	if err != nil || version != signal {
+		t.Fatalf("started block version %08x, want %08x", version, signal)
+	}
+
+	// 7 of 10 is below the threshold
+	vc.add(signal, 7)
+	vc.add(VBTopBits, 3)
+	if state := vc.state(t); state != ThresholdStarted {
+		t.Fatalf("7 signalling blocks: got %v, want started", state)
+	}
+
+	// Signalling without the top bits does not count
+	vc.add(1<<28, 10)
+	if state := vc.state(t); state != ThresholdStarted {
+		t.Fatalf("legacy versions: got %v, want started", state)
+	}
+
+	vc.add(VBTopBits, 2)
+	vc.add(signal, 8)
+	if state := vc.state(t); state != ThresholdLockedIn {
+		t.Fatalf("8 signalling blocks: got %v, want locked_in", state)
+	}
+
+	vc.add(VBTopBits, testWindow-1)
+	if state := vc.state(t); state != ThresholdLockedIn {
+		t.Fatalf("inside the locked in window: got %v, want locked_in", state)
+	}
+	vc.add(VBTopBits, 1)
+	if state := vc.state(t); state != ThresholdActive {
+		t.Fatalf("after the locked in window: got %v, want active", state)
+	}
+	version, err = vc.bc.ComputeBlockVersion(vc.tip)
+	if err != nil || version != VBTopBits {
+		t.Fatalf("active block version %08x, want %08x", version, uint32(VBTopBits))
+	}
+
+	// The state stays active without signalling, and is served from the
+	// cache for the previous windows.
+	vc.add(VBTopBits, 5*testWindow)
+	active, err := vc.bc.IsDeploymentActive(vc.tip, "testdummy")
+	if err != nil || !active {
+		t.Fatalf("deployment not active later: %v", err)
+	}
+}
+
+func TestThresholdStateTimeout(t *testing.T) {
+	const startTime = 1500000000
+	defer withDeploymentParams(t, config.ConsensusDeployment{
+		Name: "testdummy", BitNumber: 1, StartTime: startTime + 60*30, ExpireTime: startTime + 60*60,
+	})()
+	signal := uint32(VBTopBits | 1<<1)
+
+	vc := newVersionChain(startTime)
+	vc.add(signal, 2*testWindow-1)
+	if state := vc.state(t); state != ThresholdDefined {
+		t.Fatalf("before the start time: got %v, want defined", state)
+	}
+	vc.add(VBTopBits, 2*testWindow)
+	if state := vc.state(t); state != ThresholdStarted {
+		t.Fatalf("after the start time: got %v, want started", state)
+	}
+	vc.add(VBTopBits, 3*testWindow)
+	if state := vc.state(t); state != ThresholdFailed {
+		t.Fatalf("after the expire time: got %v, want failed", state)
+	}
+	vc.add(signal, 2*testWindow)
+	if state := vc.state(t); state != ThresholdFailed {
+		t.Fatalf("signalling after the expire time: got %v, want failed", state)
+	}
+
+	if _, err := vc.bc.ThresholdState(vc.tip, "unknown"); err == nil {
+		t.Error("expected an error for an unknown deployment")
+	}
+}
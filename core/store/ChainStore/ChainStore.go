@@ -3,6 +3,7 @@ package ChainStore
 import (
 	. "DNA_POW/common"
 	"DNA_POW/common/log"
+	"DNA_POW/common/metrics"
 	"DNA_POW/common/serialization"
 	. "DNA_POW/core/asset"
 	"DNA_POW/core/contract/program"
@@ -125,17 +126,20 @@ func (self *ChainStore) loop() {
 				self.handlePersistHeaderTask(task.header)
 				task.reply <- true
 				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
+				metrics.PersistDuration.Observe("header", tcall)
 				log.Debugf("handle header exetime: %g \n", tcall)
 
 			case *persistBlockTask:
 				self.handlePersistBlockTask(task.block, task.ledger)
 				task.reply <- true
 				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
+				metrics.PersistDuration.Observe("block", tcall)
 				log.Debugf("handle block exetime: %g num transactions:%d \n", tcall, len(task.block.Transactions))
 			case *rollbackBlockTask:
 				self.handleRollbackBlockTask(task.blockHash)
 				task.reply <- true
 				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
+				metrics.PersistDuration.Observe("rollback", tcall)
 				log.Debugf("handle block rollback exetime: %g \n", tcall)
 			}
 
@@ -252,8 +252,9 @@ func (tx *Transaction) DeserializeUnsignedWithoutType(r io.Reader) error {
 	if Len > uint64(0) {
 		for i := uint64(0); i < Len; i++ {
 			output := new(TxOutput)
-			output.Deserialize(r)
-
+			if err := output.Deserialize(r); err != nil {
+				return NewDetailErr(err, ErrNoCode, "transaction output Deserialize error")
+			}
 			tx.Outputs = append(tx.Outputs, output)
 		}
 	}
@@ -17,8 +17,12 @@ func (o *TxOutput) Serialize(w io.Writer) {
 	o.ProgramHash.Serialize(w)
 }
 
-func (o *TxOutput) Deserialize(r io.Reader) {
-	o.AssetID.Deserialize(r)
-	o.Value.Deserialize(r)
-	o.ProgramHash.Deserialize(r)
+func (o *TxOutput) Deserialize(r io.Reader) error {
+	if err := o.AssetID.Deserialize(r); err != nil {
+		return err
+	}
+	if err := o.Value.Deserialize(r); err != nil {
+		return err
+	}
+	return o.ProgramHash.Deserialize(r)
 }
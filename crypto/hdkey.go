@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the first index of the hardened child keys
+const HardenedKeyStart uint32 = 0x80000000
+
+// The HMAC key of the master key for the P-256 curve defined by SLIP-0010
+var hdMasterKey = []byte("Nist256p1 seed")
+
+// ExtendedKey is a P-256 private key with the chain code to derive its child
+// keys as specified by SLIP-0010.
+type ExtendedKey struct {
+	PrivateKey []byte
+	ChainCode  []byte
+	Depth      uint8
+	Index      uint32
+}
+
+func hdCurve() (elliptic.Curve, error) {
+	if AlgChoice != P256R1 {
+		return nil, errors.New("HD keys are only supported on the P256R1 curve")
+	}
+	return elliptic.P256(), nil
+}
+
+// NewMasterKey derives the master extended key from the seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	curve, err := hdCurve()
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+	data := seed
+	n := curve.Params().N
+	for {
+		mac := hmac.New(sha512.New, hdMasterKey)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		k := new(big.Int).SetBytes(sum[:32])
+		if k.Sign() != 0 && k.Cmp(n) < 0 {
+			return &ExtendedKey{PrivateKey: sum[:32], ChainCode: sum[32:]}, nil
+		}
+		data = sum
+	}
+}
+
+// Child derives the child key of the index, the hardened one from the index
+// HardenedKeyStart up.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	curve, err := hdCurve()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0x00}, k.PrivateKey...)
+	} else {
+		x, y := curve.ScalarBaseMult(k.PrivateKey)
+		data, _ = (&PubKey{X: x, Y: y}).EncodePoint(true)
+	}
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+
+	n := curve.Params().N
+	parent := new(big.Int).SetBytes(k.PrivateKey)
+	for {
+		mac := hmac.New(sha512.New, k.ChainCode)
+		mac.Write(data)
+		mac.Write(indexBytes)
+		sum := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) < 0 {
+			child := il.Add(il, parent)
+			child.Mod(child, n)
+			if child.Sign() != 0 {
+				privateKey := make([]byte, 32)
+				cb := child.Bytes()
+				copy(privateKey[32-len(cb):], cb)
+				return &ExtendedKey{
+					PrivateKey: privateKey,
+					ChainCode:  sum[32:],
+					Depth:      k.Depth + 1,
+					Index:      index,
+				}, nil
+			}
+		}
+		// The derived key is invalid, retry with the right half of the HMAC
+		data = append([]byte{0x01}, sum[32:]...)
+	}
+}
+
+// Derive derives the descendant key of the path like m/44'/2305'/0'/0/1 from
+// the master key.
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParseDerivationPath parses the child indexes of a path, a trailing ' or h
+// marks a hardened index.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	elems := strings.Split(strings.TrimSpace(path), "/")
+	if elems[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q does not start with m", path)
+	}
+	indexes := make([]uint32, 0, len(elems)-1)
+	for _, elem := range elems[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") {
+			offset = HardenedKeyStart
+			elem = elem[:len(elem)-1]
+		}
+		index, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", elem, path)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
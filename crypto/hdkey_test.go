@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMnemonic(t *testing.T) {
+	mnemonic, err := EntropyToMnemonic(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnemonic != strings.Repeat("abandon ", 11)+"about" {
+		t.Fatalf("unexpected mnemonic %q", mnemonic)
+	}
+	seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e5349553" +
+		"1f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != expected {
+		t.Errorf("unexpected seed %x", seed)
+	}
+
+	generated, err := NewMnemonic(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strings.Fields(generated)) != 24 {
+		t.Errorf("unexpected word count of %q", generated)
+	}
+	if _, err := MnemonicToEntropy(generated); err != nil {
+		t.Errorf("generated mnemonic rejected: %v", err)
+	}
+	if _, err := MnemonicToEntropy(strings.Repeat("abandon ", 12)); err == nil {
+		t.Error("mnemonic with a wrong checksum accepted")
+	}
+}
+
+func TestExtendedKey(t *testing.T) {
+	SetAlg("P256R1")
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(master.PrivateKey) != "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2" ||
+		hex.EncodeToString(master.ChainCode) != "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea" {
+		t.Fatalf("unexpected master key %x %x", master.PrivateKey, master.ChainCode)
+	}
+	child, err := master.Derive("m/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(child.PrivateKey) != "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c" ||
+		hex.EncodeToString(child.ChainCode) != "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11" {
+		t.Fatalf("unexpected child key %x %x", child.PrivateKey, child.ChainCode)
+	}
+
+	if _, err := ParseDerivationPath("44'/0"); err == nil {
+		t.Error("path without the master accepted")
+	}
+	indexes, err := ParseDerivationPath("m/44'/888h/0")
+	if err != nil || len(indexes) != 3 || indexes[0] != HardenedKeyStart+44 ||
+		indexes[1] != HardenedKeyStart+888 || indexes[2] != 0 {
+		t.Errorf("unexpected indexes %v %v", indexes, err)
+	}
+}
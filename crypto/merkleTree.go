@@ -109,3 +109,46 @@ func ComputeRoot(hashes []Uint256) (Uint256, error) {
 	tree, _ := NewMerkleTree(hashes)
 	return tree.Root.Hash, nil
 }
+
+//get the hashes of the siblings on the path from the leaf at index to the root
+func MerkleBranch(hashes []Uint256, index int) ([]Uint256, error) {
+	if index < 0 || index >= len(hashes) {
+		return nil, NewDetailErr(errors.New("MerkleBranch index out of range error."), ErrNoCode, "")
+	}
+	var branch []Uint256
+	level := hashes
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling >= len(level) {
+			// the last node of an odd level is paired with itself
+			sibling = index
+		}
+		branch = append(branch, level[sibling])
+
+		var next []Uint256
+		for i := 0; i < len(level); i += 2 {
+			right := i + 1
+			if right == len(level) {
+				right = i
+			}
+			next = append(next, DOUBLE_SHA256([]Uint256{level[i], level[right]}))
+		}
+		level = next
+		index /= 2
+	}
+	return branch, nil
+}
+
+//check the leaf at index is committed to the root by the branch
+func VerifyMerkleBranch(leaf Uint256, index uint32, branch []Uint256, root Uint256) bool {
+	hash := leaf
+	for _, sibling := range branch {
+		if index&1 == 0 {
+			hash = DOUBLE_SHA256([]Uint256{hash, sibling})
+		} else {
+			hash = DOUBLE_SHA256([]Uint256{sibling, hash})
+		}
+		index >>= 1
+	}
+	return index == 0 && hash == root
+}
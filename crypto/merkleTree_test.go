@@ -24,3 +24,25 @@ func TestHash(t *testing.T) {
 	fmt.Printf("[Root Hash]:%x\n", x)
 
 }
+
+func TestMerkleBranch(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var data []Uint256
+		for i := 0; i < n; i++ {
+			data = append(data, Uint256(sha256.Sum256([]byte{byte(i)})))
+		}
+		root, _ := ComputeRoot(data)
+		for i := 0; i < n; i++ {
+			branch, err := MerkleBranch(data, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyMerkleBranch(data[i], uint32(i), branch, root) {
+				t.Errorf("branch of leaf %d in %d leaves is not verified", i, n)
+			}
+			if VerifyMerkleBranch(Uint256(sha256.Sum256([]byte("x"))), uint32(i), branch, root) {
+				t.Errorf("branch of leaf %d in %d leaves verifies a wrong leaf", i, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"DNA_POW/crypto/util"
+
+	"github.com/golang/crypto/pbkdf2"
+)
+
+const (
+	MnemonicEntropyBits = 128 // 12 words
+	mnemonicSeedRounds  = 2048
+	mnemonicSeedLen     = 64
+)
+
+var wordIndex map[string]int
+
+func init() {
+	wordIndex = make(map[string]int, len(wordList))
+	for i, w := range wordList {
+		wordIndex[w] = i
+	}
+}
+
+// NewMnemonic returns a BIP-0039 mnemonic sentence encoding random entropy of
+// the bits, a multiple of 32 from 128 to 256.
+func NewMnemonic(entropyBits int) (string, error) {
+	if entropyBits < 128 || entropyBits > 256 || entropyBits%32 != 0 {
+		return "", fmt.Errorf("invalid mnemonic entropy length %d", entropyBits)
+	}
+	entropy, err := util.RandomNum(entropyBits / 8)
+	if err != nil {
+		return "", err
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes the entropy and the leading bits of its SHA-256 as
+// 11-bit word indexes.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	if entropyBits < 128 || entropyBits > 256 || entropyBits%32 != 0 {
+		return "", fmt.Errorf("invalid mnemonic entropy length %d", entropyBits)
+	}
+	checksumBits := uint(entropyBits / 32)
+	checksum := sha256.Sum256(entropy)
+
+	b := new(big.Int).SetBytes(entropy)
+	b.Lsh(b, checksumBits)
+	b.Or(b, big.NewInt(int64(checksum[0]>>(8-checksumBits))))
+
+	count := (entropyBits + int(checksumBits)) / 11
+	words := make([]string, count)
+	mask := big.NewInt(0x7ff)
+	for i := count - 1; i >= 0; i-- {
+		words[i] = wordList[new(big.Int).And(b, mask).Int64()]
+		b.Rsh(b, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes the mnemonic sentence and verifies its checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("invalid mnemonic word count %d", len(words))
+	}
+	b := new(big.Int)
+	for _, w := range words {
+		i, ok := wordIndex[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("invalid mnemonic word %q", w)
+		}
+		b.Lsh(b, 11)
+		b.Or(b, big.NewInt(int64(i)))
+	}
+
+	checksumBits := uint(len(words) * 11 / 33)
+	checksumValue := new(big.Int).And(b, big.NewInt(1<<checksumBits-1)).Int64()
+	b.Rsh(b, checksumBits)
+	entropy := make([]byte, int(checksumBits)*4)
+	eb := b.Bytes()
+	copy(entropy[len(entropy)-len(eb):], eb)
+
+	checksum := sha256.Sum256(entropy)
+	if int64(checksum[0]>>(8-checksumBits)) != checksumValue {
+		return nil, errors.New("invalid mnemonic checksum")
+	}
+	return entropy, nil
+}
+
+// MnemonicToSeed verifies the mnemonic sentence and stretches it with the
+// optional passphrase into the 64 bytes seed of the HD keys.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if _, err := MnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase),
+		mnemonicSeedRounds, mnemonicSeedLen, sha512.New), nil
+}
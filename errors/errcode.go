@@ -31,6 +31,8 @@ const (
 	ErrUnknownReferedTxn    ErrCode = 45016
 	ErrInvalidReferedTxn    ErrCode = 45017
 	ErrIneffectiveCoinbase  ErrCode = 45018
+	ErrInsufficientFee      ErrCode = 45019
+	ErrTxLockTime           ErrCode = 45020
 )
 
 func (err ErrCode) Error() string {
@@ -75,6 +77,10 @@ func (err ErrCode) Error() string {
 		return "invalid referenced transaction"
 	case ErrIneffectiveCoinbase:
 		return "ineffective coinbase"
+	case ErrInsufficientFee:
+		return "insufficient transaction fee"
+	case ErrTxLockTime:
+		return "transaction lock time is not reached"
 	}
 
 	return fmt.Sprintf("Unknown error? Error code = %d", err)
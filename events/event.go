@@ -67,6 +67,28 @@ func (e *Event) Notify(eventtype EventType,value interface{}) (err error){
 	return
 }
 
+// NotifyInOrder calls the subscribers of the event one after another on the
+// calling goroutine, so a caller notifying from a single goroutine has the
+// values handled in the order they are notified.
+func (e *Event) NotifyInOrder(eventtype EventType, value interface{}) error {
+	e.m.RLock()
+	subs, ok := e.subscribers[eventtype]
+	if !ok {
+		e.m.RUnlock()
+		return errors.New("No event type.")
+	}
+	funcs := make([]EventFunc, 0, len(subs))
+	for _, eventfunc := range subs {
+		funcs = append(funcs, eventfunc)
+	}
+	e.m.RUnlock()
+
+	for _, eventfunc := range funcs {
+		e.NotifySubscriber(eventfunc, value)
+	}
+	return nil
+}
+
 func (e *Event) NotifySubscriber(eventfunc EventFunc, value interface{}) {
 	if eventfunc == nil { return }
 
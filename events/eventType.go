@@ -10,4 +10,6 @@ const (
 	EventNodeDisconnect          EventType = 4
 	EventRollbackTransaction     EventType = 5
 	EventNewTransactionPutInPool EventType = 6
+	EventReorgRejected           EventType = 7
+	EventChainTipChanged         EventType = 8
 )
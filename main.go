@@ -15,6 +15,7 @@ import (
 	"DNA_POW/crypto"
 	"DNA_POW/net"
 	"DNA_POW/net/httpjsonrpc"
+	"DNA_POW/net/httpmetrics"
 	"DNA_POW/net/httpnodeinfo"
 	"DNA_POW/net/httprestful"
 	"DNA_POW/net/httpwebsocket"
@@ -139,6 +140,9 @@ func main() {
 	if config.Parameters.HttpInfoStart {
 		go httpnodeinfo.StartServer(noder)
 	}
+	if config.Parameters.HttpMetricsStart {
+		go httpmetrics.StartServer(noder)
+	}
 	select {}
 ERROR:
 	os.Exit(1)
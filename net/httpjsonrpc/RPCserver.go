@@ -3,47 +3,93 @@ package httpjsonrpc
 import (
 	. "DNA_POW/common/config"
 	"DNA_POW/common/log"
+	"DNA_POW/core/ledger"
+	"DNA_POW/events"
+	"net"
 	"net/http"
 	"strconv"
 )
 
 func StartRPCServer() {
 	http.HandleFunc("/", Handle)
+	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventChainTipChanged, onChainTipChanged)
 
 	// get interfaces
 	HandleFunc("getbestblockhash", getBestBlockHash)
 	HandleFunc("getblock", getBlock)
 	HandleFunc("getblockcount", getBlockCount)
+	HandleFunc("getblockchaininfo", getBlockchainInfo)
 	HandleFunc("getblockhash", getBlockHash)
+	HandleFunc("getchaintips", getChainTips)
 	HandleFunc("getconnectioncount", getConnectionCount)
 	HandleFunc("getrawmempool", getRawMemPool)
 	HandleFunc("getrawtransaction", getRawTransaction)
+	HandleFunc("getmerkleproof", getMerkleProof)
 	HandleFunc("getneighbor", getNeighbor)
 	HandleFunc("getnodestate", getNodeState)
+	HandleFunc("getorphanblocks", getOrphanBlocks)
+	HandleFunc("getrejects", getRejects)
+	HandleFunc("getreorgrejections", getReorgRejections)
+	HandleFunc("waitfornewblock", waitForNewBlock)
+	HandleFunc("getpeerinfo", getPeerInfo)
+	HandleFunc("getnettotals", getNetTotals)
 	HandleFunc("getversion", getVersion)
 
 	// set interfaces
 	HandleFunc("setdebuginfo", setDebugInfo)
-	HandleFunc("sendtoaddress", sendToAddress)
+	HandleWalletFunc("sendtoaddress", sendToAddress)
+	HandleWalletFunc("consolidate", consolidate)
 	HandleFunc("sendrawtransaction", sendRawTransaction)
 	HandleFunc("submitblock", submitBlock)
-	HandleFunc("createmultisigtransaction", createMultisigTransaction)
-	HandleFunc("signmultisigtransaction", signMultisigTransaction)
+	HandleWalletFunc("createmultisigtransaction", createMultisigTransaction)
+	HandleFunc("invalidateblock", invalidateBlock)
+	HandleFunc("reconsiderblock", reconsiderBlock)
+	HandleWalletFunc("signmultisigtransaction", signMultisigTransaction)
 
 	// mining interfaces
 	HandleFunc("getinfo", getInfo)
+	HandleFunc("getmininginfo", getMiningInfo)
 	HandleFunc("help", auxHelp)
 	HandleFunc("submitauxblock", submitAuxBlock)
 	HandleFunc("createauxblock", createAuxBlock)
+	HandleFunc("createmergedauxblock", createMergedAuxBlock)
+	HandleFunc("submitmergedauxblock", submitMergedAuxBlock)
 	HandleFunc("togglecpumining", toggleCpuMining)
 	HandleFunc("discretemining", discreteCpuMining)
+	HandleFunc("generatetoaddress", generateToAddress)
+	HandleFunc("generateblock", generateBlock)
+	HandleFunc("setmocktime", setMockTime)
 
 	// wallet interfaces
-	HandleFunc("addaccount", addAccount)
-	HandleFunc("deleteaccount", deleteAccount)
+	HandleWalletFunc("addaccount", addAccount)
+	HandleWalletFunc("deleteaccount", deleteAccount)
+	HandleWalletFunc("importwatchonly", importWatchOnly)
+	HandleWalletFunc("listwatchonly", listWatchOnly)
+	HandleWalletFunc("createwatchonlytransaction", createWatchOnlyTransaction)
+	HandleWalletFunc("createpartialtransaction", createPartialTransaction)
+	HandleWalletFunc("signpartialtransaction", signPartialTransaction)
+	HandleFunc("combinepartialtransactions", combinePartialTransactions)
+	HandleFunc("finalizepartialtransaction", finalizePartialTransaction)
+	HandleWalletFunc("listtransactions", listTransactions)
+	HandleWalletFunc("setlabel", setLabel)
+	HandleWalletFunc("sendtouri", sendToURI)
+	HandleWalletFunc("addcontact", addContact)
+	HandleWalletFunc("deletecontact", deleteContact)
+	HandleWalletFunc("listcontacts", listContacts)
+	HandleWalletFunc("createpaymentrequest", createPaymentRequest)
+	HandleWalletFunc("listpaymentrequests", listPaymentRequests)
+	HandleWalletFunc("rescanblockchain", rescanBlockchain)
+	HandleWalletFunc("abortrescan", abortRescan)
+	HandleWalletFunc("getrescanprogress", getRescanProgress)
+	HandleWalletFunc("dumpprivkey", dumpPrivKey)
+	HandleWalletFunc("importprivkey", importPrivKey)
+	HandleWalletFunc("dumpwallet", dumpWallet)
+	HandleWalletFunc("importwallet", importWallet)
+	HandleFunc("loadwallet", loadWallet)
+	HandleFunc("unloadwallet", unloadWallet)
+	HandleFunc("listwallets", listWallets)
 
-	// TODO: only listen to localhost
-	err := http.ListenAndServe(":"+strconv.Itoa(Parameters.HttpJsonPort), nil)
+	err := http.ListenAndServe(net.JoinHostPort(Parameters.HttpJsonHost, strconv.Itoa(Parameters.HttpJsonPort)), nil)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err.Error())
 	}
@@ -0,0 +1,267 @@
+package httpjsonrpc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/log"
+	"DNA_POW/core/ledger"
+)
+
+// MaxChainTipChanges is the number of the latest chain tip changes kept for
+// the waitfornewblock requests asking for the changes since a sequence
+const MaxChainTipChanges = 100
+
+// chainTip keeps the latest chain tip changes in the order of their sequence,
+// the changed channel is closed and replaced on every change to wake up the
+// waitfornewblock requests.
+var chainTip = struct {
+	sync.Mutex
+	changes []*ledger.ChainTipChange
+	changed chan struct{}
+}{changed: make(chan struct{})}
+
+// lastChainTipChange returns the latest change, nil if there is none. The
+// caller holds the chainTip lock.
+func lastChainTipChange() *ledger.ChainTipChange {
+	if len(chainTip.changes) == 0 {
+		return nil
+	}
+	return chainTip.changes[len(chainTip.changes)-1]
+}
+
+// chainTipChangesSince returns the changes after the sequence, it fails when
+// some of them are not kept any more or the sequence is of a change the node
+// did not make, like one before it restarted. The caller holds the chainTip
+// lock.
+func chainTipChangesSince(sequence uint64) ([]*ledger.ChainTipChange, error) {
+	var lastSequence uint64
+	if last := lastChainTipChange(); last != nil {
+		lastSequence = last.Sequence
+	}
+	if sequence > lastSequence {
+		return nil, fmt.Errorf("sequence %d is after the last change %d", sequence, lastSequence)
+	}
+	i := sort.Search(len(chainTip.changes), func(i int) bool {
+		return chainTip.changes[i].Sequence > sequence
+	})
+	if i == 0 && len(chainTip.changes) > 0 && chainTip.changes[0].Sequence > sequence+1 {
+		return nil, fmt.Errorf("the changes after sequence %d are not kept any more", sequence)
+	}
+	return chainTip.changes[i:], nil
+}
+
+func hashStrings(hashes []Uint256) []string {
+	strs := make([]string, len(hashes))
+	for i, hash := range hashes {
+		strs[i] = BytesToHexString(hash.ToArrayReverse())
+	}
+	return strs
+}
+
+func GetChainTipChangeInfo(change *ledger.ChainTipChange) *ChainTipChangeInfo {
+	return &ChainTipChangeInfo{
+		Sequence:   change.Sequence,
+		OldTip:     BytesToHexString(change.OldTip.ToArrayReverse()),
+		OldHeight:  change.OldHeight,
+		NewTip:     BytesToHexString(change.NewTip.ToArrayReverse()),
+		NewHeight:  change.NewHeight,
+		Fork:       BytesToHexString(change.Fork.ToArrayReverse()),
+		ForkHeight: change.ForkHeight,
+		Detached:   hashStrings(change.Detached),
+		Attached:   hashStrings(change.Attached),
+	}
+}
+
+func onChainTipChanged(v interface{}) {
+	change, ok := v.(*ledger.ChainTipChange)
+	if !ok {
+		return
+	}
+	chainTip.Lock()
+	defer chainTip.Unlock()
+	if len(chainTip.changes) == MaxChainTipChanges {
+		chainTip.changes[0] = nil
+		chainTip.changes = chainTip.changes[1:]
+	}
+	chainTip.changes = append(chainTip.changes, change)
+	close(chainTip.changed)
+	chainTip.changed = make(chan struct{})
+}
+
+// newBlockInfo returns the best block with the last of the changes, and all
+// of them when the changes since a sequence are asked.
+func newBlockInfo(changes []*ledger.ChainTipChange, since bool) *NewBlockInfo {
+	bestChain := ledger.DefaultLedger.Blockchain.BestChain
+	info := &NewBlockInfo{
+		Hash:   BytesToHexString(bestChain.Hash.ToArrayReverse()),
+		Height: bestChain.Height,
+	}
+	if len(changes) > 0 {
+		info.Change = GetChainTipChangeInfo(changes[len(changes)-1])
+	}
+	if since {
+		info.Changes = make([]*ChainTipChangeInfo, 0, len(changes))
+		for _, change := range changes {
+			info.Changes = append(info.Changes, GetChainTipChangeInfo(change))
+		}
+	}
+	return info
+}
+
+// waitfornewblock waits for the chain tip to change and returns the new tip
+// with the blocks detached and attached by the change. It returns at once
+// when the best block is not the optional tip hash, "" for any, any more.
+// With the optional sequence of the last change the caller handled, it
+// returns every change after it in Changes, at once if there are some. The
+// Change is null when the timeout in milliseconds, zero to wait forever,
+// expires.
+// A JSON example for waitfornewblock method as following:
+//   {"jsonrpc": "2.0", "method": "waitfornewblock", "params": [60000, "tip hash in hex", 42], "id": 0}
+func waitForNewBlock(params []interface{}) map[string]interface{} {
+	var timeout time.Duration
+	if len(params) > 0 {
+		ms, ok := params[0].(float64)
+		if !ok || ms < 0 {
+			return DnaRpcInvalidParameter
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	var sequence uint64
+	since := len(params) > 2
+	if since {
+		v, ok := params[2].(float64)
+		if !ok || v < 0 {
+			return DnaRpcInvalidParameter
+		}
+		sequence = uint64(v)
+	}
+
+	chainTip.Lock()
+	last, changed := lastChainTipChange(), chainTip.changed
+	changes, err := chainTipChangesSince(sequence)
+	chainTip.Unlock()
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	if since && len(changes) > 0 {
+		return DnaRpc(newBlockInfo(changes, true))
+	}
+
+	if len(params) > 1 && params[1] != "" {
+		tip, ok := parseBlockHashParam(params[1:])
+		if !ok {
+			return DnaRpcInvalidHash
+		}
+		if tip != *ledger.DefaultLedger.Blockchain.BestChain.Hash {
+			var lastChanges []*ledger.ChainTipChange
+			if last != nil {
+				lastChanges = []*ledger.ChainTipChange{last}
+			}
+			return DnaRpc(newBlockInfo(lastChanges, since))
+		}
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-changed:
+		chainTip.Lock()
+		if since {
+			changes, err = chainTipChangesSince(sequence)
+		} else {
+			changes = []*ledger.ChainTipChange{lastChainTipChange()}
+		}
+		chainTip.Unlock()
+		if err != nil {
+			return DnaRpc("error: " + err.Error())
+		}
+		return DnaRpc(newBlockInfo(changes, since))
+	case <-expired:
+		return DnaRpc(newBlockInfo(nil, since))
+	}
+}
+
+func getChainTips(params []interface{}) map[string]interface{} {
+	tips := ledger.DefaultLedger.Blockchain.ChainTips()
+	infos := make([]ChainTipInfo, 0, len(tips))
+	for _, tip := range tips {
+		infos = append(infos, ChainTipInfo{
+			Hash:      BytesToHexString(tip.Hash.ToArrayReverse()),
+			Height:    tip.Height,
+			BranchLen: tip.BranchLen,
+			Status:    tip.Status,
+		})
+	}
+	return DnaRpc(infos)
+}
+
+func getOrphanBlocks(params []interface{}) map[string]interface{} {
+	orphans := ledger.DefaultLedger.Blockchain.OrphanBlocks()
+	infos := make([]OrphanBlockInfo, 0, len(orphans))
+	for _, orphan := range orphans {
+		hash := orphan.Block.Hash()
+		infos = append(infos, OrphanBlockInfo{
+			Hash:          BytesToHexString(hash.ToArrayReverse()),
+			PrevBlockHash: BytesToHexString(orphan.Block.Blockdata.PrevBlockHash.ToArrayReverse()),
+			Height:        orphan.Block.Blockdata.Height,
+			Expiration:    orphan.Expiration.Unix(),
+		})
+	}
+	return DnaRpc(infos)
+}
+
+func parseBlockHashParam(params []interface{}) (Uint256, bool) {
+	if len(params) < 1 {
+		return Uint256{}, false
+	}
+	hashStr, ok := params[0].(string)
+	if !ok {
+		return Uint256{}, false
+	}
+	b, err := HexStringToBytesReverse(hashStr)
+	if err != nil {
+		return Uint256{}, false
+	}
+	hash, err := Uint256ParseFromBytes(b)
+	return hash, err == nil
+}
+
+// invalidateblock marks a block and its descendants as invalid and moves the
+// chain off them.
+// A JSON example for invalidateblock method as following:
+//   {"jsonrpc": "2.0", "method": "invalidateblock", "params": ["block hash in hex"], "id": 0}
+func invalidateBlock(params []interface{}) map[string]interface{} {
+	hash, ok := parseBlockHashParam(params)
+	if !ok {
+		return DnaRpcInvalidHash
+	}
+	if err := ledger.DefaultLedger.Blockchain.InvalidateBlock(hash); err != nil {
+		log.Warn("[json-rpc:invalidateBlock] ", err)
+		return DnaRpc(err.Error())
+	}
+	return DnaRpcSuccess
+}
+
+// reconsiderblock removes the invalid mark set by invalidateblock and moves
+// the chain back to the branch with the most work.
+// A JSON example for reconsiderblock method as following:
+//   {"jsonrpc": "2.0", "method": "reconsiderblock", "params": ["block hash in hex"], "id": 0}
+func reconsiderBlock(params []interface{}) map[string]interface{} {
+	hash, ok := parseBlockHashParam(params)
+	if !ok {
+		return DnaRpcInvalidHash
+	}
+	if err := ledger.DefaultLedger.Blockchain.ReconsiderBlock(hash); err != nil {
+		log.Warn("[json-rpc:reconsiderBlock] ", err)
+		return DnaRpc(err.Error())
+	}
+	return DnaRpcSuccess
+}
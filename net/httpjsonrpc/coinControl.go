@@ -0,0 +1,119 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+	"errors"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	tx "DNA_POW/core/transaction"
+	. "DNA_POW/errors"
+	"DNA_POW/sdk"
+)
+
+func parseOutpoints(param interface{}) ([]*tx.UTXOTxInput, error) {
+	list, ok := param.([]interface{})
+	if !ok {
+		return nil, errors.New("outpoints are not a list")
+	}
+	var outpoints []*tx.UTXOTxInput
+	for _, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, errors.New("outpoint is not a string")
+		}
+		outpoint, err := sdk.ParseOutpoint(str)
+		if err != nil {
+			return nil, err
+		}
+		outpoints = append(outpoints, outpoint)
+	}
+	return outpoints, nil
+}
+
+// parseCoinControl parses the optional coin selection, pinned and locked
+// outpoints parameters, nil if none is given.
+func parseCoinControl(params []interface{}) (*sdk.CoinControl, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	control := &sdk.CoinControl{}
+	name, ok := params[0].(string)
+	if !ok {
+		return nil, errors.New("coin selection is not a string")
+	}
+	selection, err := sdk.ParseCoinSelection(name)
+	if err != nil {
+		return nil, err
+	}
+	control.Selection = selection
+	if len(params) > 1 && params[1] != nil {
+		if control.Pinned, err = parseOutpoints(params[1]); err != nil {
+			return nil, err
+		}
+	}
+	if len(params) > 2 && params[2] != nil {
+		if control.Locked, err = parseOutpoints(params[2]); err != nil {
+			return nil, err
+		}
+	}
+	return control, nil
+}
+
+// consolidate merges the coins of the asset worth less than the dust value, all
+// of them if it is zero or not given, to the main account. The oldest are merged
+// first, as many as fit under the fee cap.
+// A JSON example for consolidate method as following:
+//   {"jsonrpc": "2.0", "method": "consolidate", "params": ["asset id", "fee cap", "dust value"], "id": 0}
+func consolidate(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	asset, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	str, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	feeCap, err := StringToFixed64(str)
+	if err != nil {
+		return DnaRpc("error: invalid fee cap")
+	}
+	var dust Fixed64
+	if len(params) > 2 {
+		if str, ok = params[2].(string); !ok {
+			return DnaRpcInvalidParameter
+		}
+		if dust, err = StringToFixed64(str); err != nil {
+			return DnaRpc("error: invalid dust value")
+		}
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	tmp, err := HexStringToBytesReverse(asset)
+	if err != nil {
+		return DnaRpc("error: invalid asset ID")
+	}
+	var assetID Uint256
+	if err := assetID.Deserialize(bytes.NewReader(tmp)); err != nil {
+		return DnaRpc("error: invalid asset hash")
+	}
+	txn, fee, err := sdk.MakeConsolidateTransaction(wallet, assetID, dust, feeCap)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	if errCode := VerifyAndSendTx(txn); errCode != ErrNoError {
+		return DnaRpc("error: " + errCode.Error())
+	}
+	txHash := txn.Hash()
+	return DnaRpc(ConsolidateInfo{
+		TxID:   BytesToHexString(txHash.ToArrayReverse()),
+		Inputs: len(txn.UTXOInputs),
+		Value:  txn.Outputs[0].Value.String(),
+		Fee:    fee.String(),
+	})
+}
@@ -1,8 +1,10 @@
 package httpjsonrpc
 
 import (
+	"DNA_POW/account"
 	. "DNA_POW/common"
 	"DNA_POW/common/log"
+	"DNA_POW/common/metrics"
 	"DNA_POW/consensus/pow"
 	. "DNA_POW/core/transaction"
 	tx "DNA_POW/core/transaction"
@@ -15,10 +17,12 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
 func init() {
 	mainMux.m = make(map[string]func([]interface{}) map[string]interface{})
+	mainMux.wallet = make(map[string]func(account.Client, []interface{}) map[string]interface{})
 }
 
 //an instance of the multiplexer
@@ -30,6 +34,7 @@ var Pow *pow.PowService
 type ServeMux struct {
 	sync.RWMutex
 	m               map[string]func([]interface{}) map[string]interface{}
+	wallet          map[string]func(account.Client, []interface{}) map[string]interface{}
 	defaultFunction func(http.ResponseWriter, *http.Request)
 }
 
@@ -137,6 +142,207 @@ type NodeInfo struct {
 	Height   uint64 // The node latest block height
 	TxnCnt   uint64 // The transactions be transmit by this node
 	RxTxnCnt uint64 // The transaction received by this node
+
+	Uptime         int64   // Seconds since the node started
+	HeaderHeight   uint32  // The latest header height
+	BestPeerHeight uint64  // The highest height of the neighbors
+	SyncProgress   float64 // Percentage of the blocks synchronized
+}
+
+type NetTotals struct {
+	TotalBytesRecv uint64
+	TotalBytesSent uint64
+	TotalMsgsRecv  uint64
+	TotalMsgsSent  uint64
+	TimeMillis     int64
+	Uptime         int64 // Seconds since the node started
+	RecvPerMsg     map[string]MsgCounter
+	SentPerMsg     map[string]MsgCounter
+}
+
+type PeerInfo struct {
+	ID         uint64
+	Addr       string
+	Port       uint16
+	State      uint
+	Version    uint32 // The negotiated network protocol
+	Services   uint64
+	Relay      bool
+	Height     uint64
+	ConnTime   int64 // The time the neighbor connected
+	Uptime     int64 // Seconds since the neighbor connected
+	LastRecv   int64
+	PingMillis int64 // The round trip time of the last ping
+	FeeFilter  Fixed64
+	SyncNode   bool
+	BytesRecv  uint64
+	BytesSent  uint64
+	MsgsRecv   uint64
+	MsgsSent   uint64
+	RecvPerMsg map[string]MsgCounter
+	SentPerMsg map[string]MsgCounter
+}
+
+type RejectMsgInfo struct {
+	Time    int64  // The time the reject message received
+	Addr    string // The neighbor sent the reject message
+	Command string // The rejected message type
+	Code    uint8
+	Reason  string
+	Hash    string // The rejected block or transaction
+}
+
+type MerkleProofInfo struct {
+	TxID      string
+	BlockHash string
+	Height    uint32
+	Index     uint32   // The position of the transaction in the block
+	Branch    []string // The sibling hashes from the transaction up to the root
+}
+
+type MergedAuxBlockInfo struct {
+	ChainId           int            `json:"chainid"`
+	Height            uint64         `json:"height"`
+	Bits              string         `json:"bits"`
+	Hash              string         `json:"hash"`
+	PreviousBlockHash string         `json:"previousblockhash"`
+	Commitment        string         `json:"commitment"` // To be included in the parent coinbase script
+	MerkleSize        uint32         `json:"merklesize"`
+	MerkleNonce       uint32         `json:"merklenonce"`
+	Slots             map[string]int `json:"slots"` // The aux merkle tree index by chain ID
+}
+
+type MergedAuxBlockResult struct {
+	AuxPows map[string]string `json:"auxpows"`         // The aux proofs by chain ID for the pool to submit
+	Error   string            `json:"error,omitempty"` // Why our block was rejected
+}
+
+type DeploymentInfo struct {
+	Status     string // defined, started, locked_in, active or failed
+	Bit        uint8
+	StartTime  int64
+	ExpireTime int64
+}
+
+type BlockchainInfo struct {
+	Chain         string
+	Blocks        uint32
+	Headers       uint32
+	BestBlockHash string
+	Difficulty    float64
+	MedianTime    int64
+	ChainWork     string
+	MaxReorgDepth uint32
+	Deployments   map[string]DeploymentInfo
+}
+
+type ChainTipChangeInfo struct {
+	Sequence   uint64
+	OldTip     string
+	OldHeight  uint32
+	NewTip     string
+	NewHeight  uint32
+	Fork       string
+	ForkHeight uint32
+	Detached   []string
+	Attached   []string
+}
+
+type NewBlockInfo struct {
+	Hash    string
+	Height  uint32
+	Change  *ChainTipChangeInfo
+	Changes []*ChainTipChangeInfo `json:",omitempty"` // Since the asked sequence
+}
+
+type ChainTipInfo struct {
+	Hash      string
+	Height    uint32
+	BranchLen uint32
+	Status    string
+}
+
+type OrphanBlockInfo struct {
+	Hash          string
+	PrevBlockHash string
+	Height        uint32
+	Expiration    int64
+}
+
+type ReorgRejectionInfo struct {
+	Hash       string
+	Height     uint32
+	ForkHeight uint32
+	BestHeight uint32
+	Depth      uint32
+	Time       int64
+}
+
+type AssetBalanceInfo struct {
+	AssetID string
+	Value   string
+}
+
+type WatchOnlyInfo struct {
+	Address     string
+	ProgramHash string
+	Type        string
+	Script      string
+	Balances    []AssetBalanceInfo
+}
+
+type PartialTransactionInfo struct {
+	Data     string
+	Missing  int
+	Complete bool
+}
+
+type ConsolidateInfo struct {
+	TxID   string
+	Inputs int
+	Value  string
+	Fee    string
+}
+
+type AddressLabelInfo struct {
+	Address string
+	Label   string
+}
+
+type WalletTransactionInfo struct {
+	TxID          string
+	Category      string
+	Amounts       []AssetBalanceInfo
+	Fee           string
+	Addresses     []AddressLabelInfo
+	Confirmations uint32
+	Height        uint32
+	BlockHash     string
+	Time          uint32
+	Label         string
+}
+
+type ContactInfo struct {
+	Name    string
+	Address string
+}
+
+type PaymentRequestInfo struct {
+	ID       string
+	Address  string
+	AssetID  string
+	Amount   string
+	Label    string
+	Time     int64
+	Paid     bool
+	PaidTxID string
+	URI      string
+}
+
+type RescanInfo struct {
+	StartHeight uint32
+	StopHeight  uint32
+	Height      uint32
 }
 
 type ConsensusInfo struct {
@@ -162,6 +368,14 @@ func HandleFunc(pattern string, handler func([]interface{}) map[string]interface
 	mainMux.m[pattern] = handler
 }
 
+// HandleWalletFunc registers a method acting on a wallet, which is the one
+// loaded with the name in the request path /wallet/<name>, or the node wallet.
+func HandleWalletFunc(pattern string, handler func(account.Client, []interface{}) map[string]interface{}) {
+	mainMux.Lock()
+	defer mainMux.Unlock()
+	mainMux.wallet[pattern] = handler
+}
+
 //a function to be called if the request is not a HTTP JSON RPC call
 func SetDefaultFunc(def func(http.ResponseWriter, *http.Request)) {
 	mainMux.defaultFunction = def
@@ -210,9 +424,22 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//get the corresponding function
-	function, ok := mainMux.m[request["method"].(string)]
+	method := request["method"].(string)
+	function, ok := mainMux.m[method]
+	if walletFunction, found := mainMux.wallet[method]; found {
+		ok = true
+		function = func(params []interface{}) map[string]interface{} {
+			wallet, err := requestWallet(r.URL.Path)
+			if err != nil {
+				return DnaRpc("error: " + err.Error())
+			}
+			return walletFunction(wallet, params)
+		}
+	}
 	if ok {
+		start := time.Now()
 		response := function(request["params"].([]interface{}))
+		metrics.RPCDuration.Observe(method, time.Since(start).Seconds())
 		data, err := json.Marshal(map[string]interface{}{
 			"jsonpc": "2.0",
 			"result": response["result"],
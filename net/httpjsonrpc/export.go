@@ -0,0 +1,158 @@
+package httpjsonrpc
+
+import (
+	"net"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+)
+
+// localOnly reports whether the RPC server listens to the loopback interface
+// only, as the requests are not authenticated.
+func localOnly() bool {
+	if config.Parameters.HttpJsonHost == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(config.Parameters.HttpJsonHost)
+	return ip != nil && ip.IsLoopback()
+}
+
+// dumpprivkey returns the private key of a wallet address, encoded with the
+// network and a checksum, once the wallet password is verified. It is refused
+// unless the RPC server listens to localhost only.
+// A JSON example for dumpprivkey method as following:
+//   {"jsonrpc": "2.0", "method": "dumpprivkey", "params": ["address", "password"], "id": 0}
+func dumpPrivKey(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	address, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	programHash, err := ToScriptHash(address)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	password, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if !localOnly() {
+		return DnaRpc("error: the private keys are dumped only when the RPC server listens to localhost")
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	key, err := wallet.ExportPrivateKey(programHash, []byte(password))
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpc(key)
+}
+
+// importprivkey adds an encoded private key to the wallet, which syncs again
+// from the height given, 0 if not, and returns its address.
+// A JSON example for importprivkey method as following:
+//   {"jsonrpc": "2.0", "method": "importprivkey", "params": ["private key", 1000], "id": 0}
+func importPrivKey(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	key, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	var height float64
+	if len(params) > 1 {
+		if height, ok = params[1].(float64); !ok || height < 0 {
+			return DnaRpcInvalidParameter
+		}
+	}
+	privateKey, err := account.DecodePrivateKey(key)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	defer ClearBytes(privateKey, len(privateKey))
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	ac, err := wallet.ImportPrivateKey(privateKey, uint32(height))
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	address, _ := ac.ProgramHash.ToAddress()
+	return DnaRpc(address)
+}
+
+// dumpwallet writes the keys, the contracts, the watch-only addresses, the
+// labels and the address book of the wallet to a new file in the node
+// directory encrypted with the password.
+// A JSON example for dumpwallet method as following:
+//   {"jsonrpc": "2.0", "method": "dumpwallet", "params": ["file", "password"], "id": 0}
+func dumpWallet(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok || !validWalletName(name) {
+		return DnaRpcInvalidParameter
+	}
+	password, ok := params[1].(string)
+	if !ok || password == "" {
+		return DnaRpcInvalidParameter
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	if err := wallet.DumpWallet(name, []byte(password)); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpc(true)
+}
+
+// importwallet adds the content of a wallet dump file of the node missing from
+// the wallet, which syncs again from the height given, the birthday of the
+// dump if not. It returns the number of keys added.
+// A JSON example for importwallet method as following:
+//   {"jsonrpc": "2.0", "method": "importwallet", "params": ["file", "password", 1000], "id": 0}
+func importWallet(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok || !validWalletName(name) {
+		return DnaRpcInvalidParameter
+	}
+	password, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	height := -1.0
+	if len(params) > 2 {
+		if height, ok = params[2].(float64); !ok || height < 0 {
+			return DnaRpcInvalidParameter
+		}
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	dump, err := account.ReadWalletDump(name, []byte(password))
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	birthday := dump.Birthday
+	if height >= 0 {
+		birthday = uint32(height)
+	}
+	added, err := wallet.ImportDump(dump, birthday)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpc(added)
+}
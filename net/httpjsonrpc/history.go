@@ -0,0 +1,118 @@
+package httpjsonrpc
+
+import (
+	"DNA_POW/account"
+	. "DNA_POW/common"
+)
+
+func GetWalletTransactionInfo(record *account.TxRecord, labels map[Uint160]string) WalletTransactionInfo {
+	info := WalletTransactionInfo{
+		TxID:          BytesToHexString(record.TxID.ToArrayReverse()),
+		Category:      record.Category,
+		Amounts:       []AssetBalanceInfo{},
+		Fee:           record.Fee.String(),
+		Addresses:     []AddressLabelInfo{},
+		Confirmations: record.Confirmations,
+		Height:        record.Height,
+		Time:          record.Time,
+		Label:         record.Label,
+	}
+	if record.Confirmed() {
+		info.BlockHash = BytesToHexString(record.BlockHash.ToArrayReverse())
+	}
+	for id, value := range record.Amounts {
+		info.Amounts = append(info.Amounts, AssetBalanceInfo{
+			AssetID: BytesToHexString(id.ToArrayReverse()),
+			Value:   value.String(),
+		})
+	}
+	for _, programHash := range record.ProgramHashes {
+		address, _ := programHash.ToAddress()
+		info.Addresses = append(info.Addresses, AddressLabelInfo{
+			Address: address,
+			Label:   labels[programHash],
+		})
+	}
+	return info
+}
+
+// listtransactions returns the latest transactions of the wallet from the
+// oldest, 10 if the count is not given, after skipping the latest ones.
+// A JSON example for listtransactions method as following:
+//   {"jsonrpc": "2.0", "method": "listtransactions", "params": [10, 0], "id": 0}
+func listTransactions(wallet account.Client, params []interface{}) map[string]interface{} {
+	count, skip := 10, 0
+	if len(params) > 0 {
+		n, ok := params[0].(float64)
+		if !ok || n < 0 {
+			return DnaRpcInvalidParameter
+		}
+		count = int(n)
+	}
+	if len(params) > 1 {
+		n, ok := params[1].(float64)
+		if !ok || n < 0 {
+			return DnaRpcInvalidParameter
+		}
+		skip = int(n)
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	history := wallet.GetHistory()
+	end := len(history) - skip
+	if end < 0 {
+		end = 0
+	}
+	start := end - count
+	if start < 0 {
+		start = 0
+	}
+	labels := wallet.GetAddressLabels()
+	infos := []WalletTransactionInfo{}
+	for _, record := range history[start:end] {
+		infos = append(infos, GetWalletTransactionInfo(record, labels))
+	}
+	return DnaRpc(infos)
+}
+
+// setlabel labels an address of the wallet or a transaction, an empty label
+// removes it.
+// A JSON example for setlabel method as following:
+//   {"jsonrpc": "2.0", "method": "setlabel", "params": ["address or transaction id", "label"], "id": 0}
+func setLabel(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	key, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	label, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	if programHash, err := ToScriptHash(key); err == nil {
+		if err := wallet.SetAddressLabel(programHash, label); err != nil {
+			return DnaRpc("error: " + err.Error())
+		}
+		return DnaRpcSuccess
+	}
+	hash, err := HexStringToBytesReverse(key)
+	if err != nil {
+		return DnaRpc("error: invalid address or transaction id")
+	}
+	txID, err := Uint256ParseFromBytes(hash)
+	if err != nil {
+		return DnaRpc("error: invalid address or transaction id")
+	}
+	if err := wallet.SetTransactionLabel(txID, label); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpcSuccess
+}
@@ -3,6 +3,7 @@ package httpjsonrpc
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"time"
 
 	"DNA_POW/account"
@@ -13,6 +14,7 @@ import (
 	"DNA_POW/core/signature"
 	tx "DNA_POW/core/transaction"
 	"DNA_POW/core/transaction/payload"
+	"DNA_POW/crypto"
 	. "DNA_POW/errors"
 	"DNA_POW/sdk"
 )
@@ -199,6 +201,56 @@ func getBlock(params []interface{}) map[string]interface{} {
 	return DnaRpc(b)
 }
 
+// getblockchaininfo returns the state of the best chain and of the soft fork
+// deployments for the next block.
+func getBlockchainInfo(params []interface{}) map[string]interface{} {
+	bc := ledger.DefaultLedger.Blockchain
+	bestChain := bc.BestChain
+	info := BlockchainInfo{
+		Chain:         config.Parameters.ChainParam.Name,
+		Blocks:        bestChain.Height,
+		Headers:       ledger.DefaultLedger.Store.GetHeaderHeight(),
+		BestBlockHash: BytesToHexString(bestChain.Hash.ToArrayReverse()),
+		Difficulty:    getDifficultyRatio(bestChain.Bits),
+		MedianTime:    ledger.CalcPastMedianTime(bestChain).Unix(),
+		ChainWork:     fmt.Sprintf("%064x", bestChain.WorkSum),
+		MaxReorgDepth: config.Parameters.ChainParam.MaxReorgDepth,
+		Deployments:   make(map[string]DeploymentInfo),
+	}
+	for _, d := range config.Parameters.ChainParam.Deployments {
+		state, err := bc.ThresholdState(bestChain, d.Name)
+		if err != nil {
+			log.Warn("[json-rpc:getBlockchainInfo] ", err)
+			return DnaRpcInternalError
+		}
+		info.Deployments[d.Name] = DeploymentInfo{
+			Status:     state.String(),
+			Bit:        d.BitNumber,
+			StartTime:  d.StartTime,
+			ExpireTime: d.ExpireTime,
+		}
+	}
+	return DnaRpc(info)
+}
+
+// getreorgrejections returns the latest blocks rejected for forking the best
+// chain deeper than the max reorg depth.
+func getReorgRejections(params []interface{}) map[string]interface{} {
+	rejections := ledger.DefaultLedger.Blockchain.ReorgRejections()
+	infos := make([]ReorgRejectionInfo, 0, len(rejections))
+	for _, r := range rejections {
+		infos = append(infos, ReorgRejectionInfo{
+			Hash:       BytesToHexString(r.Hash.ToArrayReverse()),
+			Height:     r.Height,
+			ForkHeight: r.ForkHeight,
+			BestHeight: r.BestHeight,
+			Depth:      r.Depth,
+			Time:       r.Time.Unix(),
+		})
+	}
+	return DnaRpc(infos)
+}
+
 func getBlockCount(params []interface{}) map[string]interface{} {
 	return DnaRpc(ledger.DefaultLedger.Blockchain.BlockHeight + 1)
 }
@@ -281,11 +333,138 @@ func getRawTransaction(params []interface{}) map[string]interface{} {
 	}
 }
 
+// getmerkleproof returns the proof that a transaction is included in a block,
+// which can be checked against the block header by light clients.
+// A JSON example for getmerkleproof method as following:
+//   {"jsonrpc": "2.0", "method": "getmerkleproof", "params": ["transactioin hash in hex"], "id": 0}
+func getMerkleProof(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	hex, err := HexStringToBytesReverse(str)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var hash Uint256
+	err = hash.Deserialize(bytes.NewReader(hex))
+	if err != nil {
+		return DnaRpcInvalidTransaction
+	}
+	_, height, err := ledger.DefaultLedger.Store.GetTransaction(hash)
+	if err != nil {
+		return DnaRpcUnknownTransaction
+	}
+	bHash, err := ledger.DefaultLedger.Store.GetBlockHash(height)
+	if err != nil {
+		return DnaRpcUnknownBlock
+	}
+	block, err := ledger.DefaultLedger.Store.GetBlock(bHash)
+	if err != nil {
+		return DnaRpcUnknownBlock
+	}
+	index := -1
+	txHashes := make([]Uint256, 0, len(block.Transactions))
+	for i, txn := range block.Transactions {
+		txHash := txn.Hash()
+		if txHash == hash {
+			index = i
+		}
+		txHashes = append(txHashes, txHash)
+	}
+	branch, err := crypto.MerkleBranch(txHashes, index)
+	if err != nil {
+		return DnaRpcUnknownTransaction
+	}
+
+	proof := MerkleProofInfo{
+		TxID:      str,
+		BlockHash: BytesToHexString(bHash.ToArrayReverse()),
+		Height:    height,
+		Index:     uint32(index),
+	}
+	for _, h := range branch {
+		proof.Branch = append(proof.Branch, BytesToHexString(h.ToArrayReverse()))
+	}
+	return DnaRpc(proof)
+}
+
 func getNeighbor(params []interface{}) map[string]interface{} {
+	if len(params) > 0 {
+		if verbose, ok := params[0].(bool); ok && verbose {
+			return getPeerInfo(params)
+		}
+	}
 	addr, _ := node.GetNeighborAddrs()
 	return DnaRpc(addr)
 }
 
+func getPeerInfo(params []interface{}) map[string]interface{} {
+	now := time.Now()
+	peers := []PeerInfo{}
+	for _, n := range node.GetNeighborNoder() {
+		stats := n.GetTrafficStats()
+		peers = append(peers, PeerInfo{
+			ID:         n.GetID(),
+			Addr:       n.GetAddr(),
+			Port:       n.GetPort(),
+			State:      uint(n.GetState()),
+			Version:    n.Version(),
+			Services:   n.Services(),
+			Relay:      n.GetRelay(),
+			Height:     n.GetHeight(),
+			ConnTime:   n.GetStartTime().Unix(),
+			Uptime:     int64(now.Sub(n.GetStartTime()).Seconds()),
+			LastRecv:   n.GetLastRXTime().Unix(),
+			PingMillis: int64(n.GetPingRTT() / time.Millisecond),
+			FeeFilter:  n.GetFeeFilter(),
+			SyncNode:   n.IsSyncHeaders(),
+			BytesRecv:  stats.BytesRecv,
+			BytesSent:  stats.BytesSent,
+			MsgsRecv:   stats.MsgsRecv,
+			MsgsSent:   stats.MsgsSent,
+			RecvPerMsg: stats.RecvPerMsg,
+			SentPerMsg: stats.SentPerMsg,
+		})
+	}
+	return DnaRpc(peers)
+}
+
+func getNetTotals(params []interface{}) map[string]interface{} {
+	now := time.Now()
+	stats := node.GetTrafficStats()
+	totals := NetTotals{
+		TotalBytesRecv: stats.BytesRecv,
+		TotalBytesSent: stats.BytesSent,
+		TotalMsgsRecv:  stats.MsgsRecv,
+		TotalMsgsSent:  stats.MsgsSent,
+		TimeMillis:     now.UnixNano() / int64(time.Millisecond),
+		Uptime:         int64(now.Sub(node.GetStartTime()).Seconds()),
+		RecvPerMsg:     stats.RecvPerMsg,
+		SentPerMsg:     stats.SentPerMsg,
+	}
+	return DnaRpc(totals)
+}
+
+func getRejects(params []interface{}) map[string]interface{} {
+	infos := node.GetRejectInfos()
+	rejects := make([]RejectMsgInfo, 0, len(infos))
+	for _, info := range infos {
+		rejects = append(rejects, RejectMsgInfo{
+			Time:    info.Time,
+			Addr:    info.Addr,
+			Command: info.Command,
+			Code:    info.Code,
+			Reason:  info.Reason,
+			Hash:    BytesToHexString(info.Hash.ToArrayReverse()),
+		})
+	}
+	return DnaRpc(rejects)
+}
+
 func getNodeState(params []interface{}) map[string]interface{} {
 	n := NodeInfo{
 		State:    uint(node.GetState()),
@@ -299,6 +478,20 @@ func getNodeState(params []interface{}) map[string]interface{} {
 		TxnCnt:   node.GetTxnCnt(),
 		RxTxnCnt: node.GetRxTxnCnt(),
 	}
+	n.Uptime = int64(time.Since(node.GetStartTime()).Seconds())
+	n.HeaderHeight = ledger.DefaultLedger.Store.GetHeaderHeight()
+	heights, _ := node.GetNeighborHeights()
+	for _, h := range heights {
+		if h > n.BestPeerHeight {
+			n.BestPeerHeight = h
+		}
+	}
+	localHeight := uint64(ledger.DefaultLedger.Blockchain.GetBestHeight())
+	if n.BestPeerHeight <= localHeight {
+		n.SyncProgress = 100
+	} else {
+		n.SyncProgress = float64(localHeight) * 100 / float64(n.BestPeerHeight)
+	}
 	return DnaRpc(n)
 }
 
@@ -417,7 +610,7 @@ func createAuxBlock(params []interface{}) map[string]interface{} {
 		preHashStr := BytesToHexString(preHash.ToArray())
 
 		SendToAux := AuxBlock{
-			ChainId:           1,
+			ChainId:           config.Parameters.ChainParam.AuxPowChainID,
 			Height:            node.GetHeight(),
 			CoinBaseValue:     1,                                          //transaction content
 			Bits:              fmt.Sprintf("%x", msgBlock.Blockdata.Bits), //difficulty
@@ -442,13 +635,14 @@ func getInfo(params []interface{}) map[string]interface{} {
 		Connections     uint   `json:"connections"`
 		Proxy           string `json:"proxy"`
 		//Difficulty      int    `json:"difficulty"`
-		Testnet        bool   `json:"testnet"`
-		Keypoololdest  int    `json:"keypoololdest"`
-		Keypoolsize    int    `json:"keypoolsize"`
-		Unlocked_until int    `json:"unlocked_until"`
-		Paytxfee       int    `json:"paytxfee"`
-		Relayfee       int    `json:"relayfee"`
-		Errors         string `json:"errors"`
+		Testnet        bool    `json:"testnet"`
+		Keypoololdest  int     `json:"keypoololdest"`
+		Keypoolsize    int     `json:"keypoolsize"`
+		Unlocked_until int     `json:"unlocked_until"`
+		Paytxfee       int     `json:"paytxfee"`
+		Relayfee       int     `json:"relayfee"`
+		Errors         string  `json:"errors"`
+		HashesPerSec   float64 `json:"hashespersec"`
 	}{
 		Version:         config.Parameters.Version,
 		ProtocolVersion: config.Parameters.PowConfiguration.ProtocolVersion,
@@ -466,6 +660,44 @@ func getInfo(params []interface{}) map[string]interface{} {
 		Paytxfee:       0,
 		Relayfee:       0,
 		Errors:         "Tobe written"}
+	if Pow != nil {
+		RetVal.HashesPerSec = Pow.HashesPerSecond()
+	}
+	return DnaRpc(&RetVal)
+}
+
+// getDifficultyRatio returns the difficulty of the bits as a multiple of the
+// minimum difficulty.
+func getDifficultyRatio(bits uint32) float64 {
+	target := ledger.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return 0
+	}
+	ratio, _ := new(big.Rat).SetFrac(config.Parameters.ChainParam.PowLimit, target).Float64()
+	return ratio
+}
+
+func getMiningInfo(params []interface{}) map[string]interface{} {
+	bestChain := ledger.DefaultLedger.Blockchain.BestChain
+	RetVal := struct {
+		Blocks       uint32  `json:"blocks"`
+		Difficulty   float64 `json:"difficulty"`
+		PooledTx     int     `json:"pooledtx"`
+		Testnet      bool    `json:"testnet"`
+		Generate     bool    `json:"generate"`
+		GenProcLimit uint32  `json:"genproclimit"`
+		HashesPerSec float64 `json:"hashespersec"`
+	}{
+		Blocks:     ledger.DefaultLedger.Blockchain.GetBestHeight(),
+		Difficulty: getDifficultyRatio(bestChain.Bits),
+		PooledTx:   len(node.GetTxnPool(false)),
+		Testnet:    config.Parameters.PowConfiguration.TestNet,
+	}
+	if Pow != nil {
+		RetVal.Generate = Pow.IsMining()
+		RetVal.GenProcLimit = Pow.NumWorkers()
+		RetVal.HashesPerSec = Pow.HashesPerSecond()
+	}
 	return DnaRpc(&RetVal)
 }
 
@@ -479,16 +711,16 @@ func getVersion(params []interface{}) map[string]interface{} {
 	return DnaRpc(config.Version)
 }
 
-func addAccount(params []interface{}) map[string]interface{} {
-	if Wallet == nil {
+func addAccount(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
 		return DnaRpc("open wallet first")
 	}
-	account, err := Wallet.CreateAccount()
+	account, err := wallet.CreateAccount()
 	if err != nil {
 		return DnaRpc("create account error:" + err.Error())
 	}
 
-	if err := Wallet.CreateContract(account); err != nil {
+	if err := wallet.CreateContract(account); err != nil {
 		return DnaRpc("create contract error:" + err.Error())
 	}
 
@@ -500,7 +732,7 @@ func addAccount(params []interface{}) map[string]interface{} {
 	return DnaRpc(address)
 }
 
-func deleteAccount(params []interface{}) map[string]interface{} {
+func deleteAccount(wallet account.Client, params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return DnaRpcNil
 	}
@@ -511,20 +743,20 @@ func deleteAccount(params []interface{}) map[string]interface{} {
 	default:
 		return DnaRpcInvalidParameter
 	}
-	if Wallet == nil {
+	if wallet == nil {
 		return DnaRpc("open wallet first")
 	}
 	programHash, err := ToScriptHash(address)
 	if err != nil {
 		return DnaRpc("invalid address:" + err.Error())
 	}
-	if err := Wallet.DeleteAccount(programHash); err != nil {
+	if err := wallet.DeleteAccount(programHash); err != nil {
 		return DnaRpc("Delete account error:" + err.Error())
 	}
-	if err := Wallet.DeleteContract(programHash); err != nil {
+	if err := wallet.DeleteContract(programHash); err != nil {
 		return DnaRpc("Delete contract error:" + err.Error())
 	}
-	if err := Wallet.DeleteCoinsData(programHash); err != nil {
+	if err := wallet.DeleteCoinsData(programHash); err != nil {
 		return DnaRpc("Delete coins error:" + err.Error())
 	}
 
@@ -580,7 +812,116 @@ func discreteCpuMining(params []interface{}) map[string]interface{} {
 	return DnaRpc(ret)
 }
 
-func sendToAddress(params []interface{}) map[string]interface{} {
+// generatetoaddress mines the blocks paying to the address. It is only
+// available on the RegNet, whose difficulty a CPU meets at once.
+// A JSON example for generatetoaddress method as following:
+//   {"jsonrpc": "2.0", "method": "generatetoaddress", "params": [10, "address"], "id": 0}
+func generateToAddress(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	if config.Parameters.ChainParam.Name != "RegNet" {
+		return DnaRpcUnsupported
+	}
+	n, ok := params[0].(float64)
+	if !ok || n < 1 {
+		return DnaRpcInvalidParameter
+	}
+	addr, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if _, err := ToScriptHash(addr); err != nil {
+		return DnaRpcInvalidParameter
+	}
+
+	blockHashes, err := Pow.GenerateToAddress(uint32(n), addr)
+	if err != nil {
+		log.Warn("[json-rpc:generateToAddress] ", err)
+		return DnaRpc(err.Error())
+	}
+	ret := make([]string, len(blockHashes))
+	for i, hash := range blockHashes {
+		ret[i] = BytesToHexString(hash.ToArrayReverse())
+	}
+	return DnaRpc(ret)
+}
+
+// generateblock mines a block including exactly the transactions of the pool
+// in order. It is only available on the RegNet.
+// A JSON example for generateblock method as following:
+//   {"jsonrpc": "2.0", "method": "generateblock", "params": ["address", ["txid in hex"]], "id": 0}
+func generateBlock(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	if config.Parameters.ChainParam.Name != "RegNet" {
+		return DnaRpcUnsupported
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if _, err := ToScriptHash(addr); err != nil {
+		return DnaRpcInvalidParameter
+	}
+	var txs []*tx.Transaction
+	if len(params) > 1 {
+		txids, ok := params[1].([]interface{})
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		for _, v := range txids {
+			txid, ok := parseBlockHashParam([]interface{}{v})
+			if !ok {
+				return DnaRpcInvalidHash
+			}
+			txn := node.GetTransaction(txid)
+			if txn == nil {
+				return DnaRpcUnknownTransaction
+			}
+			txs = append(txs, txn)
+		}
+	}
+
+	hash, err := Pow.MineBlockWithTxs(addr, txs)
+	if err != nil {
+		log.Warn("[json-rpc:generateBlock] ", err)
+		return DnaRpc(err.Error())
+	}
+	return DnaRpc(BytesToHexString(hash.ToArrayReverse()))
+}
+
+// setmocktime replaces the local clock of the node with the Unix time, zero
+// restores the local clock. It is only available on the RegNet.
+// A JSON example for setmocktime method as following:
+//   {"jsonrpc": "2.0", "method": "setmocktime", "params": [1500000000], "id": 0}
+func setMockTime(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	if config.Parameters.ChainParam.Name != "RegNet" {
+		return DnaRpcUnsupported
+	}
+	t, ok := params[0].(float64)
+	if !ok || t < 0 {
+		return DnaRpcInvalidParameter
+	}
+	if t == 0 {
+		ledger.SetMockTime(time.Time{})
+	} else {
+		ledger.SetMockTime(time.Unix(int64(t), 0))
+	}
+	return DnaRpcSuccess
+}
+
+// The address may be a name of the address book of the wallet.
+// The optional parameters choose the coins spent: the coin selection, which is
+// smallest, largest, exact or oldest, the outpoints to spend and those never
+// to spend.
+// A JSON example for sendtoaddress method as following:
+//   {"jsonrpc": "2.0", "method": "sendtoaddress", "params": ["asset id", "address", "value", "fee", "exact", ["txid:index"], ["txid:index"]], "id": 0}
+func sendToAddress(wallet account.Client, params []interface{}) map[string]interface{} {
 	if len(params) < 4 {
 		return DnaRpcNil
 	}
@@ -609,12 +950,12 @@ func sendToAddress(params []interface{}) map[string]interface{} {
 	default:
 		return DnaRpcInvalidParameter
 	}
-	if Wallet == nil {
+	if wallet == nil {
 		return DnaRpc("error : wallet is not opened")
 	}
 
 	batchOut := sdk.BatchOut{
-		Address: address,
+		Address: recipientAddress(wallet, address),
 		Value:   value,
 	}
 	tmp, err := HexStringToBytesReverse(asset)
@@ -625,7 +966,11 @@ func sendToAddress(params []interface{}) map[string]interface{} {
 	if err := assetID.Deserialize(bytes.NewReader(tmp)); err != nil {
 		return DnaRpc("error: invalid asset hash")
 	}
-	txn, err := sdk.MakeTransferTransaction(Wallet, assetID, fee, batchOut)
+	control, err := parseCoinControl(params[4:])
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	txn, err := sdk.MakeTransferTransactionWithCoinControl(wallet, assetID, fee, control, batchOut)
 	if err != nil {
 		return DnaRpc("error: " + err.Error())
 	}
@@ -691,7 +1036,7 @@ func submitBlock(params []interface{}) map[string]interface{} {
 	return DnaRpcSuccess
 }
 
-func signMultisigTransaction(params []interface{}) map[string]interface{} {
+func signMultisigTransaction(wallet account.Client, params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return DnaRpcNil
 	}
@@ -713,7 +1058,7 @@ func signMultisigTransaction(params []interface{}) map[string]interface{} {
 	found := false
 	programHashes := txn.ParseTransactionCode()
 	for _, hash := range programHashes {
-		acct := Wallet.GetAccountByProgramHash(hash)
+		acct := wallet.GetAccountByProgramHash(hash)
 		if acct != nil {
 			found = true
 			sig, _ := signature.SignBySigner(&txn, acct)
@@ -741,7 +1086,7 @@ func signMultisigTransaction(params []interface{}) map[string]interface{} {
 	}
 }
 
-func createMultisigTransaction(params []interface{}) map[string]interface{} {
+func createMultisigTransaction(wallet account.Client, params []interface{}) map[string]interface{} {
 	if len(params) < 4 {
 		return DnaRpcNil
 	}
@@ -776,7 +1121,7 @@ func createMultisigTransaction(params []interface{}) map[string]interface{} {
 	default:
 		return DnaRpcInvalidParameter
 	}
-	if Wallet == nil {
+	if wallet == nil {
 		return DnaRpc("error : wallet is not opened")
 	}
 
@@ -792,7 +1137,7 @@ func createMultisigTransaction(params []interface{}) map[string]interface{} {
 	if err := assetID.Deserialize(bytes.NewReader(tmp)); err != nil {
 		return DnaRpc("error: invalid asset hash")
 	}
-	txn, err := sdk.MakeMultisigTransferTransaction(Wallet, assetID, from, fee, batchOut)
+	txn, err := sdk.MakeMultisigTransferTransaction(wallet, assetID, from, fee, batchOut)
 	if err != nil {
 		return DnaRpc("error:" + err.Error())
 	}
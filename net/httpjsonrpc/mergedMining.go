@@ -0,0 +1,222 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"DNA_POW/core/auxpow"
+	"DNA_POW/core/ledger"
+)
+
+// mergedMiningTree is an aux chain merkle tree handed out to the pools
+type mergedMiningTree struct {
+	mm       *auxpow.MergedMining
+	prevHash Uint256 // The tip our aux block builds on
+}
+
+// mergedMining keeps the aux chain merkle trees handed out to the pools by
+// the hash of our aux block.
+var mergedMining = struct {
+	sync.Mutex
+	trees map[string]mergedMiningTree
+}{trees: make(map[string]mergedMiningTree)}
+
+// pruneMergedMiningTrees removes the trees of the aux blocks which do not
+// build on the tip any more. The caller holds the mergedMining lock.
+func pruneMergedMiningTrees(tip Uint256) {
+	for key, tree := range mergedMining.trees {
+		if tree.prevHash != tip {
+			delete(mergedMining.trees, key)
+		}
+	}
+}
+
+func parseUint256(hashStr string) (Uint256, error) {
+	var hash Uint256
+	b, err := HexStringToBytes(hashStr)
+	if err != nil {
+		return hash, err
+	}
+	if len(b) != UINT256SIZE {
+		return hash, fmt.Errorf("invalid hash length %d", len(b))
+	}
+	copy(hash[:], b)
+	return hash, nil
+}
+
+// A JSON example for createmergedauxblock method as following:
+//   {"jsonrpc": "2.0", "method": "createmergedauxblock", "params": ["address", {"2": "aux block hash in hex"}], "id": 0}
+func createMergedAuxBlock(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	addr, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	auxChains, ok := params[1].(map[string]interface{})
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	chains := make(map[int]Uint256)
+	for idStr, v := range auxChains {
+		chainID, err := strconv.Atoi(idStr)
+		if err != nil || chainID == config.Parameters.ChainParam.AuxPowChainID {
+			return DnaRpcInvalidParameter
+		}
+		hashStr, ok := v.(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		hash, err := parseUint256(hashStr)
+		if err != nil {
+			return DnaRpcInvalidHash
+		}
+		chains[chainID] = hash
+	}
+
+	Pow.PayToAddr = addr
+	msgBlock, curHashStr, _ := generateAuxBlock(addr)
+	if nil == msgBlock {
+		return DnaRpcNil
+	}
+	chains[config.Parameters.ChainParam.AuxPowChainID] = msgBlock.Hash()
+	mm, err := auxpow.NewMergedMining(chains)
+	if err != nil {
+		log.Warn("[json-rpc:createMergedAuxBlock] ", err)
+		return DnaRpcInternalError
+	}
+	preHash := msgBlock.Blockdata.PrevBlockHash
+	mergedMining.Lock()
+	pruneMergedMiningTrees(preHash)
+	mergedMining.trees[curHashStr] = mergedMiningTree{mm: mm, prevHash: preHash}
+	mergedMining.Unlock()
+
+	slots := make(map[string]int, len(chains))
+	for chainID := range chains {
+		slots[strconv.Itoa(chainID)], _ = mm.Index(chainID)
+	}
+	return DnaRpc(&MergedAuxBlockInfo{
+		ChainId:           config.Parameters.ChainParam.AuxPowChainID,
+		Height:            node.GetHeight(),
+		Bits:              fmt.Sprintf("%x", msgBlock.Blockdata.Bits),
+		Hash:              curHashStr,
+		PreviousBlockHash: BytesToHexString(preHash.ToArray()),
+		Commitment:        BytesToHexString(mm.Commitment()),
+		MerkleSize:        mm.MerkleSize(),
+		MerkleNonce:       mm.Nonce(),
+		Slots:             slots,
+	})
+}
+
+// submitmergedauxblock splits the solved parent block into the proofs of the
+// merge mined chains. Our block is added to the chain and the proofs of the
+// other chains are returned for the pool to submit, also when our block is
+// rejected, which is reported in the error of the result.
+// A JSON example for submitmergedauxblock method as following:
+//   {"jsonrpc": "2.0", "method": "submitmergedauxblock", "params": ["block hash", "parent coinbase", "parent header", ["coinbase branch"], 0], "id": 0}
+func submitMergedAuxBlock(params []interface{}) map[string]interface{} {
+	if len(params) < 3 {
+		return DnaRpcNil
+	}
+	blockHash, ok1 := params[0].(string)
+	coinbaseStr, ok2 := params[1].(string)
+	headerStr, ok3 := params[2].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return DnaRpcInvalidParameter
+	}
+	var branch []Uint256
+	var index int
+	if len(params) > 3 {
+		hashes, ok := params[3].([]interface{})
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		for _, v := range hashes {
+			hashStr, ok := v.(string)
+			if !ok {
+				return DnaRpcInvalidParameter
+			}
+			hash, err := parseUint256(hashStr)
+			if err != nil {
+				return DnaRpcInvalidHash
+			}
+			branch = append(branch, hash)
+		}
+	}
+	if len(params) > 4 {
+		v, ok := params[4].(float64)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		index = int(v)
+	}
+
+	mergedMining.Lock()
+	tree, ok := mergedMining.trees[blockHash]
+	mergedMining.Unlock()
+	Pow.MsgBlock.Mutex.Lock()
+	msgBlock, ok2 := Pow.MsgBlock.BlockData[blockHash]
+	Pow.MsgBlock.Mutex.Unlock()
+	if !ok || !ok2 {
+		log.Trace("[json-rpc:submitMergedAuxBlock] receive invalid block hash value:", blockHash)
+		return DnaRpcInvalidHash
+	}
+
+	var coinbase auxpow.BtcTx
+	var header auxpow.BtcBlockHeader
+	b, err := HexStringToBytes(coinbaseStr)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	if err := coinbase.Deserialize(bytes.NewReader(b)); err != nil {
+		return DnaRpcInvalidParameter
+	}
+	b, err = HexStringToBytes(headerStr)
+	if err != nil {
+		return DnaRpcInvalidParameter
+	}
+	if err := header.Deserialize(bytes.NewReader(b)); err != nil {
+		return DnaRpcInvalidParameter
+	}
+
+	auxPows, err := tree.mm.AuxPows(coinbase, branch, index, header)
+	if err != nil {
+		log.Trace("[json-rpc:submitMergedAuxBlock] ", err)
+		return DnaRpc(err.Error())
+	}
+
+	var result MergedAuxBlockResult
+	msgBlock.Blockdata.AuxPow = *auxPows[config.Parameters.ChainParam.AuxPowChainID]
+	if _, _, err := ledger.DefaultLedger.Blockchain.AddBlock(msgBlock); err != nil {
+		log.Trace(err)
+		result.Error = err.Error()
+	} else {
+		Pow.MsgBlock.Mutex.Lock()
+		for key := range Pow.MsgBlock.BlockData {
+			delete(Pow.MsgBlock.BlockData, key)
+		}
+		Pow.MsgBlock.Mutex.Unlock()
+		mergedMining.Lock()
+		pruneMergedMiningTrees(ledger.DefaultLedger.Blockchain.CurrentBlockHash())
+		mergedMining.Unlock()
+	}
+
+	result.AuxPows = make(map[string]string, len(auxPows)-1)
+	for chainID, ap := range auxPows {
+		if chainID == config.Parameters.ChainParam.AuxPowChainID {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := ap.Serialize(buf); err != nil {
+			return DnaRpcInternalError
+		}
+		result.AuxPows[strconv.Itoa(chainID)] = BytesToHexString(buf.Bytes())
+	}
+	return DnaRpc(result)
+}
@@ -0,0 +1,154 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	. "DNA_POW/errors"
+	"DNA_POW/sdk"
+)
+
+func getPartialTransactionInfo(pt *sdk.PartialTransaction) map[string]interface{} {
+	data, err := pt.ToHexString()
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	missing := pt.Missing()
+	return DnaRpc(PartialTransactionInfo{
+		Data:     data,
+		Missing:  missing,
+		Complete: missing == 0,
+	})
+}
+
+func parsePartialTransactionParam(param interface{}) (*sdk.PartialTransaction, bool) {
+	str, ok := param.(string)
+	if !ok {
+		return nil, false
+	}
+	pt, err := sdk.PartialTransactionFromHexString(str)
+	return pt, err == nil
+}
+
+// createpartialtransaction spends the coins of a wallet address, which may be
+// watch-only, to a partial transaction carrying what the signers need.
+// A JSON example for createpartialtransaction method as following:
+//   {"jsonrpc": "2.0", "method": "createpartialtransaction", "params": ["asset id", "from address", "to address", "value", "fee"], "id": 0}
+func createPartialTransaction(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 5 {
+		return DnaRpcNil
+	}
+	var strs [5]string
+	for i := range strs {
+		str, ok := params[i].(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		strs[i] = str
+	}
+	asset, from, address, value, fee := strs[0], strs[1], strs[2], strs[3], strs[4]
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	batchOut := sdk.BatchOut{
+		Address: address,
+		Value:   value,
+	}
+	tmp, err := HexStringToBytesReverse(asset)
+	if err != nil {
+		return DnaRpc("error: invalid asset ID")
+	}
+	var assetID Uint256
+	if err := assetID.Deserialize(bytes.NewReader(tmp)); err != nil {
+		return DnaRpc("error: invalid asset hash")
+	}
+	pt, err := sdk.MakePartialTransaction(wallet, assetID, from, fee, batchOut)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return getPartialTransactionInfo(pt)
+}
+
+// signpartialtransaction adds the signatures of the wallet accounts.
+// A JSON example for signpartialtransaction method as following:
+//   {"jsonrpc": "2.0", "method": "signpartialtransaction", "params": ["partial transaction in hex"], "id": 0}
+func signPartialTransaction(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	pt, ok := parsePartialTransactionParam(params[0])
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	if _, err := pt.Sign(wallet); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return getPartialTransactionInfo(pt)
+}
+
+// combinepartialtransactions merges the signatures of partial transactions of
+// the same transaction signed apart.
+// A JSON example for combinepartialtransactions method as following:
+//   {"jsonrpc": "2.0", "method": "combinepartialtransactions", "params": [["partial transaction in hex", "partial transaction in hex"]], "id": 0}
+func combinePartialTransactions(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	list, ok := params[0].([]interface{})
+	if !ok || len(list) == 0 {
+		return DnaRpcInvalidParameter
+	}
+	var combined *sdk.PartialTransaction
+	for _, param := range list {
+		pt, ok := parsePartialTransactionParam(param)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		if combined == nil {
+			combined = pt
+		} else if err := combined.Combine(pt); err != nil {
+			return DnaRpc("error: " + err.Error())
+		}
+	}
+	return getPartialTransactionInfo(combined)
+}
+
+// finalizepartialtransaction returns the raw transaction of a partial
+// transaction with all the signatures, and sends it when the optional second
+// parameter is true.
+// A JSON example for finalizepartialtransaction method as following:
+//   {"jsonrpc": "2.0", "method": "finalizepartialtransaction", "params": ["partial transaction in hex", true], "id": 0}
+func finalizePartialTransaction(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	pt, ok := parsePartialTransactionParam(params[0])
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	send := false
+	if len(params) > 1 {
+		if send, ok = params[1].(bool); !ok {
+			return DnaRpcInvalidParameter
+		}
+	}
+	txn, err := pt.Finalize()
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	if send {
+		if errCode := VerifyAndSendTx(txn); errCode != ErrNoError {
+			return DnaRpc(errCode.Error())
+		}
+		txnHash := txn.Hash()
+		return DnaRpc(BytesToHexString(txnHash.ToArrayReverse()))
+	}
+	var buffer bytes.Buffer
+	txn.Serialize(&buffer)
+	return DnaRpc(BytesToHexString(buffer.Bytes()))
+}
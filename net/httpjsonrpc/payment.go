@@ -0,0 +1,226 @@
+package httpjsonrpc
+
+import (
+	"sort"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	. "DNA_POW/errors"
+	"DNA_POW/sdk"
+)
+
+// recipientAddress returns the address of the recipient named in the address
+// book of the wallet, or the address given.
+func recipientAddress(wallet account.Client, recipient string) string {
+	if _, err := ToScriptHash(recipient); err == nil {
+		return recipient
+	}
+	if programHash, ok := wallet.GetContacts()[recipient]; ok {
+		if address, err := programHash.ToAddress(); err == nil {
+			return address
+		}
+	}
+	return recipient
+}
+
+func parseAssetID(asset string) (Uint256, error) {
+	b, err := HexStringToBytesReverse(asset)
+	if err != nil {
+		return Uint256{}, err
+	}
+	return Uint256ParseFromBytes(b)
+}
+
+func GetPaymentRequestInfo(request *account.PaymentRequest) PaymentRequestInfo {
+	address, _ := request.ProgramHash.ToAddress()
+	uri := sdk.PaymentURI{
+		Address: address,
+		AssetID: request.AssetID,
+		Amount:  request.Amount,
+		Label:   request.Label,
+		ID:      request.ID,
+	}
+	info := PaymentRequestInfo{
+		ID:      request.ID,
+		Address: address,
+		AssetID: BytesToHexString(request.AssetID.ToArrayReverse()),
+		Amount:  request.Amount.String(),
+		Label:   request.Label,
+		Time:    request.Time,
+		Paid:    request.Paid(),
+		URI:     uri.String(),
+	}
+	if request.Paid() {
+		info.PaidTxID = BytesToHexString(request.PaidTxID.ToArrayReverse())
+	}
+	return info
+}
+
+// sendtouri pays a payment URI, the amount and the asset ID are required when
+// the URI has none. The transaction is labeled with the label of the URI.
+// A JSON example for sendtouri method as following:
+//   {"jsonrpc": "2.0", "method": "sendtouri", "params": ["dna:address?asset=id&amount=1", "fee", "amount", "asset id"], "id": 0}
+func sendToURI(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	var strs [4]string
+	for i := 0; i < len(params) && i < len(strs); i++ {
+		str, ok := params[i].(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		strs[i] = str
+	}
+	fee, amount, asset := strs[1], strs[2], strs[3]
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	uri, err := sdk.ParsePaymentURI(strs[0])
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	assetID := uri.AssetID
+	if assetID == (Uint256{}) {
+		if asset == "" {
+			return DnaRpc("error: the payment URI has no asset ID")
+		}
+		if assetID, err = parseAssetID(asset); err != nil {
+			return DnaRpc("error: invalid asset ID")
+		}
+	}
+	if uri.Amount > 0 {
+		amount = uri.Amount.String()
+	} else if amount == "" {
+		return DnaRpc("error: the payment URI has no amount")
+	}
+
+	txn, err := sdk.MakeTransferTransaction(wallet, assetID, fee, sdk.BatchOut{
+		Address: uri.Address,
+		Value:   amount,
+	})
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	if errCode := VerifyAndSendTx(txn); errCode != ErrNoError {
+		return DnaRpc("error: " + errCode.Error())
+	}
+	txHash := txn.Hash()
+	if uri.Label != "" {
+		wallet.SetTransactionLabel(txHash, uri.Label)
+	}
+	return DnaRpc(BytesToHexString(txHash.ToArrayReverse()))
+}
+
+// addcontact names a recipient in the address book, which the wallet methods
+// accept in place of the address.
+// A JSON example for addcontact method as following:
+//   {"jsonrpc": "2.0", "method": "addcontact", "params": ["name", "address"], "id": 0}
+func addContact(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	address, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	programHash, err := ToScriptHash(address)
+	if err != nil {
+		return DnaRpc("error: invalid address")
+	}
+	if err := wallet.AddContact(name, programHash); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpcSuccess
+}
+
+// A JSON example for deletecontact method as following:
+//   {"jsonrpc": "2.0", "method": "deletecontact", "params": ["name"], "id": 0}
+func deleteContact(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	if err := wallet.DeleteContact(name); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpcSuccess
+}
+
+// A JSON example for listcontacts method as following:
+//   {"jsonrpc": "2.0", "method": "listcontacts", "params": [], "id": 0}
+func listContacts(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	infos := []ContactInfo{}
+	for name, programHash := range wallet.GetContacts() {
+		address, _ := programHash.ToAddress()
+		infos = append(infos, ContactInfo{Name: name, Address: address})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return DnaRpc(infos)
+}
+
+// createpaymentrequest requests a payment of the amount of the asset to a new
+// address of the wallet, and returns the request with its payment URI.
+// A JSON example for createpaymentrequest method as following:
+//   {"jsonrpc": "2.0", "method": "createpaymentrequest", "params": ["asset id", "amount", "label"], "id": 0}
+func createPaymentRequest(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	var strs [3]string
+	for i := 0; i < len(params) && i < len(strs); i++ {
+		str, ok := params[i].(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		strs[i] = str
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	assetID, err := parseAssetID(strs[0])
+	if err != nil {
+		return DnaRpc("error: invalid asset ID")
+	}
+	amount, err := StringToFixed64(strs[1])
+	if err != nil {
+		return DnaRpc("error: invalid amount")
+	}
+	request, err := wallet.CreatePaymentRequest(assetID, amount, strs[2])
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	return DnaRpc(GetPaymentRequestInfo(request))
+}
+
+// listpaymentrequests returns the payment requests of the wallet, the paid
+// ones with the transaction paying them.
+// A JSON example for listpaymentrequests method as following:
+//   {"jsonrpc": "2.0", "method": "listpaymentrequests", "params": [], "id": 0}
+func listPaymentRequests(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	infos := []PaymentRequestInfo{}
+	for _, request := range wallet.GetPaymentRequests() {
+		infos = append(infos, GetPaymentRequestInfo(request))
+	}
+	return DnaRpc(infos)
+}
@@ -0,0 +1,70 @@
+package httpjsonrpc
+
+import (
+	"DNA_POW/account"
+)
+
+// rescanblockchain finds the coins of the wallet in the blocks from the start
+// height to the stop height, the wallet height if not given. It returns when
+// the rescan is finished or aborted.
+// A JSON example for rescanblockchain method as following:
+//   {"jsonrpc": "2.0", "method": "rescanblockchain", "params": [1000, 2000], "id": 0}
+func rescanBlockchain(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	start, ok := params[0].(float64)
+	if !ok || start < 0 {
+		return DnaRpcInvalidParameter
+	}
+	stop := float64(^uint32(0))
+	if len(params) > 1 {
+		if stop, ok = params[1].(float64); !ok || stop < 0 {
+			return DnaRpcInvalidParameter
+		}
+	}
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	height, err := wallet.Rescan(uint32(start), uint32(stop))
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+
+	return DnaRpc(RescanInfo{
+		StartHeight: uint32(start),
+		StopHeight:  height,
+		Height:      height,
+	})
+}
+
+// abortrescan stops the rescan of the wallet in progress, and returns whether
+// there is one.
+// A JSON example for abortrescan method as following:
+//   {"jsonrpc": "2.0", "method": "abortrescan", "params": [], "id": 0}
+func abortRescan(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	return DnaRpc(wallet.AbortRescan())
+}
+
+// getrescanprogress returns the range of blocks being rescanned and the height
+// reached, or null if the wallet is not rescanning.
+// A JSON example for getrescanprogress method as following:
+//   {"jsonrpc": "2.0", "method": "getrescanprogress", "params": [], "id": 0}
+func getRescanProgress(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+	progress := wallet.GetRescanProgress()
+	if progress == nil {
+		return DnaRpc(nil)
+	}
+	return DnaRpc(RescanInfo{
+		StartHeight: progress.StartHeight,
+		StopHeight:  progress.StopHeight,
+		Height:      progress.Height,
+	})
+}
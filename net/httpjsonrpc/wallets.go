@@ -0,0 +1,128 @@
+package httpjsonrpc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+)
+
+// the request path prefix of the methods acting on a loaded wallet
+const walletPathPrefix = "/wallet/"
+
+// The wallets loaded besides the node wallet by name, each syncs its own coins.
+// The wallets being opened are loading, the scrypt key derivation of a wallet
+// does not hold the lock.
+var wallets = struct {
+	sync.RWMutex
+	m       map[string]account.Client
+	loading map[string]bool
+}{m: make(map[string]account.Client), loading: make(map[string]bool)}
+
+// requestWallet returns the loaded wallet named in the request path, or the
+// node wallet when the path names none.
+func requestWallet(path string) (account.Client, error) {
+	if !strings.HasPrefix(path, walletPathPrefix) {
+		return Wallet, nil
+	}
+	name := strings.TrimPrefix(path, walletPathPrefix)
+	wallets.RLock()
+	defer wallets.RUnlock()
+	wallet, ok := wallets.m[name]
+	if !ok {
+		return nil, fmt.Errorf("wallet %s is not loaded", name)
+	}
+	return wallet, nil
+}
+
+// validWalletName reports whether the name is a file in the node directory
+// other than the node wallet, as the wallet files and the wallet dumps are.
+func validWalletName(name string) bool {
+	return name != "" && name != account.WalletFileName && filepath.Base(name) == name
+}
+
+// loadwallet opens the wallet file in the node directory, then the wallet
+// methods requested with the path /wallet/<name> act on it.
+// A JSON example for loadwallet method as following:
+//   {"jsonrpc": "2.0", "method": "loadwallet", "params": ["wallet name", "password"], "id": 0}
+func loadWallet(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok || !validWalletName(name) {
+		return DnaRpcInvalidParameter
+	}
+	password, ok := params[1].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	if !FileExisted(name) {
+		return DnaRpcWalletNotExists
+	}
+
+	wallets.Lock()
+	if _, ok := wallets.m[name]; ok || wallets.loading[name] {
+		wallets.Unlock()
+		return DnaRpcWalletAlreadyExists
+	}
+	wallets.loading[name] = true
+	wallets.Unlock()
+
+	wallet, err := account.Open(name, []byte(password))
+
+	wallets.Lock()
+	defer wallets.Unlock()
+	delete(wallets.loading, name)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	wallets.m[name] = wallet
+
+	return DnaRpc(name)
+}
+
+// unloadwallet stops syncing a loaded wallet and closes it.
+// A JSON example for unloadwallet method as following:
+//   {"jsonrpc": "2.0", "method": "unloadwallet", "params": ["wallet name"], "id": 0}
+func unloadWallet(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	name, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+
+	wallets.Lock()
+	wallet, ok := wallets.m[name]
+	delete(wallets.m, name)
+	wallets.Unlock()
+	if !ok {
+		return DnaRpc("error: wallet " + name + " is not loaded")
+	}
+	wallet.Close()
+
+	return DnaRpcSuccess
+}
+
+// listwallets returns the node wallet, if opened, and the loaded wallets.
+// A JSON example for listwallets method as following:
+//   {"jsonrpc": "2.0", "method": "listwallets", "params": [], "id": 0}
+func listWallets(params []interface{}) map[string]interface{} {
+	names := []string{}
+	wallets.RLock()
+	for name := range wallets.m {
+		names = append(names, name)
+	}
+	wallets.RUnlock()
+	sort.Strings(names)
+	if Wallet != nil {
+		names = append([]string{account.WalletFileName}, names...)
+	}
+	return DnaRpc(names)
+}
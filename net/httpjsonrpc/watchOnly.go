@@ -0,0 +1,144 @@
+package httpjsonrpc
+
+import (
+	"bytes"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	ct "DNA_POW/core/contract"
+	tx "DNA_POW/core/transaction"
+	"DNA_POW/sdk"
+)
+
+// The types of the watch-only addresses
+const (
+	WatchOnlyAddress   = "address"
+	WatchOnlySignature = "signature"
+	WatchOnlyMultiSig  = "multisig"
+)
+
+func watchOnlyType(contract *ct.Contract) string {
+	switch {
+	case contract == nil:
+		return WatchOnlyAddress
+	case contract.IsMultiSigContract():
+		return WatchOnlyMultiSig
+	}
+	return WatchOnlySignature
+}
+
+func GetWatchOnlyInfo(w *account.WatchOnlyAccount, coins map[*tx.UTXOTxInput]*account.Coin) WatchOnlyInfo {
+	address, _ := w.ProgramHash.ToAddress()
+	info := WatchOnlyInfo{
+		Address:     address,
+		ProgramHash: BytesToHexString(w.ProgramHash.ToArrayReverse()),
+		Type:        watchOnlyType(w.Contract),
+		Balances:    []AssetBalanceInfo{},
+	}
+	if w.Contract != nil {
+		info.Script = BytesToHexString(w.Contract.Code)
+	}
+	assets := make(map[Uint256]Fixed64)
+	for _, coin := range coins {
+		if coin.AddressType == account.WatchOnly && coin.Output.ProgramHash == w.ProgramHash {
+			assets[coin.Output.AssetID] += coin.Output.Value
+		}
+	}
+	for id, value := range assets {
+		info.Balances = append(info.Balances, AssetBalanceInfo{
+			AssetID: BytesToHexString(id.ToArrayReverse()),
+			Value:   value.String(),
+		})
+	}
+	return info
+}
+
+// importwatchonly adds an address, a public key or a signature or multisig
+// redeem script in hex whose coins the wallet tracks without the keys. The
+// wallet syncs again from the height given, 0 if not.
+// A JSON example for importwatchonly method as following:
+//   {"jsonrpc": "2.0", "method": "importwatchonly", "params": ["address, public key or script", 1000], "id": 0}
+func importWatchOnly(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return DnaRpcNil
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return DnaRpcInvalidParameter
+	}
+	var height float64
+	if len(params) > 1 {
+		if height, ok = params[1].(float64); !ok || height < 0 {
+			return DnaRpcInvalidParameter
+		}
+	}
+	if wallet == nil {
+		return DnaRpc("open wallet first")
+	}
+	programHash, contract, err := account.ParseWatchOnly(str)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	if err := wallet.AddWatchOnly(programHash, contract, uint32(height)); err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+	address, _ := programHash.ToAddress()
+	return DnaRpc(address)
+}
+
+// A JSON example for listwatchonly method as following:
+//   {"jsonrpc": "2.0", "method": "listwatchonly", "params": [], "id": 0}
+func listWatchOnly(wallet account.Client, params []interface{}) map[string]interface{} {
+	if wallet == nil {
+		return DnaRpc("open wallet first")
+	}
+	coins := wallet.GetCoins()
+	infos := []WatchOnlyInfo{}
+	for _, w := range wallet.GetWatchOnly() {
+		infos = append(infos, GetWatchOnlyInfo(w, coins))
+	}
+	return DnaRpc(infos)
+}
+
+// createwatchonlytransaction spends the coins of a watch-only address and
+// returns the raw transaction without signatures for offline signing.
+// A JSON example for createwatchonlytransaction method as following:
+//   {"jsonrpc": "2.0", "method": "createwatchonlytransaction", "params": ["asset id", "from address", "to address", "value", "fee"], "id": 0}
+func createWatchOnlyTransaction(wallet account.Client, params []interface{}) map[string]interface{} {
+	if len(params) < 5 {
+		return DnaRpcNil
+	}
+	var strs [5]string
+	for i := range strs {
+		str, ok := params[i].(string)
+		if !ok {
+			return DnaRpcInvalidParameter
+		}
+		strs[i] = str
+	}
+	asset, from, address, value, fee := strs[0], strs[1], strs[2], strs[3], strs[4]
+	if wallet == nil {
+		return DnaRpc("error : wallet is not opened")
+	}
+
+	batchOut := sdk.BatchOut{
+		Address: address,
+		Value:   value,
+	}
+	tmp, err := HexStringToBytesReverse(asset)
+	if err != nil {
+		return DnaRpc("error: invalid asset ID")
+	}
+	var assetID Uint256
+	if err := assetID.Deserialize(bytes.NewReader(tmp)); err != nil {
+		return DnaRpc("error: invalid asset hash")
+	}
+	txn, err := sdk.MakeUnsignedTransferTransaction(wallet, assetID, from, fee, batchOut)
+	if err != nil {
+		return DnaRpc("error: " + err.Error())
+	}
+
+	var buffer bytes.Buffer
+	txn.Serialize(&buffer)
+	return DnaRpc(BytesToHexString(buffer.Bytes()))
+}
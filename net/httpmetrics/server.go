@@ -0,0 +1,77 @@
+package httpmetrics
+
+import (
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"DNA_POW/common/metrics"
+	"DNA_POW/core/ledger"
+	. "DNA_POW/net/protocol"
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+var node Noder
+
+// writeNodeMetrics writes the metrics which are read from the ledger and the
+// network when scraped.
+func writeNodeMetrics(buf *bytes.Buffer) {
+	bc := ledger.DefaultLedger.Blockchain
+	metrics.WriteGauge(buf, "dna_chain_best_height",
+		"Height of the best block.", float64(bc.GetBestHeight()))
+	metrics.WriteGauge(buf, "dna_chain_header_height",
+		"Height of the best header.", float64(ledger.DefaultLedger.Store.GetHeaderHeight()))
+	metrics.WriteGauge(buf, "dna_chain_orphan_blocks",
+		"Number of orphan blocks.", float64(bc.GetOrphanCount()))
+
+	txns := node.GetTxnPool(false)
+	var txnBytes int
+	for _, txn := range txns {
+		txnBytes += txn.GetSize()
+	}
+	metrics.WriteGauge(buf, "dna_txnpool_transactions",
+		"Number of transactions in the transaction pool.", float64(len(txns)))
+	metrics.WriteGauge(buf, "dna_txnpool_bytes",
+		"Size of the transactions in the transaction pool.", float64(txnBytes))
+
+	heights, _ := node.GetNeighborHeights()
+	var bestPeerHeight uint64
+	for _, h := range heights {
+		if h > bestPeerHeight {
+			bestPeerHeight = h
+		}
+	}
+	metrics.WriteGauge(buf, "dna_peers_connected",
+		"Number of established neighbors.", float64(node.GetNbrNodeCnt()))
+	metrics.WriteGauge(buf, "dna_peers_best_height",
+		"Best height announced by the neighbors.", float64(bestPeerHeight))
+
+	stats := node.GetTrafficStats()
+	metrics.WriteCounter(buf, "dna_net_received_bytes_total",
+		"Bytes received from the neighbors.", stats.BytesRecv)
+	metrics.WriteCounter(buf, "dna_net_sent_bytes_total",
+		"Bytes sent to the neighbors.", stats.BytesSent)
+	metrics.WriteCounter(buf, "dna_net_received_messages_total",
+		"Messages received from the neighbors.", stats.MsgsRecv)
+	metrics.WriteCounter(buf, "dna_net_sent_messages_total",
+		"Messages sent to the neighbors.", stats.MsgsSent)
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	buf := new(bytes.Buffer)
+	writeNodeMetrics(buf)
+	metrics.WritePrometheus(buf)
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.Write(buf.Bytes())
+}
+
+func StartServer(n Noder) {
+	node = n
+	port := config.Parameters.HttpMetricsPort
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", metricsHandler)
+	err := http.ListenAndServe(":"+strconv.Itoa(port), mux)
+	if err != nil {
+		log.Error("Metrics server error: ", err.Error())
+	}
+}
@@ -9,6 +9,11 @@ type NgbNodeInfo struct {
 	HttpInfoAddr  string
 	HttpInfoPort  int
 	HttpInfoStart bool
+	Height        uint64
+	PingTime      string
+	BytesRecv     uint64
+	BytesSent     uint64
+	ConnTime      string
 }
 
 type NgbNodeInfoSlice []NgbNodeInfo
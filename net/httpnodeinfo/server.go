@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 )
 
 type Info struct {
@@ -23,6 +24,12 @@ type Info struct {
 	NodePort      int
 	NodeId        string
 	NodeType      string
+	Uptime        string
+	SyncProgress  string
+	BytesRecv     uint64
+	BytesSent     uint64
+	MsgsRecv      uint64
+	MsgsSent      uint64
 }
 
 const (
@@ -41,13 +48,36 @@ func newNgbNodeInfo(ngbId string, ngbType string, ngbAddr string, httpInfoAddr s
 
 func initPageInfo(blockHeight uint32, curNodeType string, ngbrCnt int, ngbrsInfo []NgbNodeInfo) (*Info, error) {
 	id := fmt.Sprintf("0x%x", node.GetID())
-	return &Info{NodeVersion: config.Version, BlockHeight: blockHeight,
+	info := &Info{NodeVersion: config.Version, BlockHeight: blockHeight,
 		NeighborCnt: ngbrCnt, Neighbors: ngbrsInfo,
 		HttpRestPort:  config.Parameters.HttpRestPort,
 		HttpWsPort:    config.Parameters.HttpWsPort,
 		HttpJsonPort:  config.Parameters.HttpJsonPort,
 		NodePort:      config.Parameters.NodePort,
-		NodeId:        id, NodeType: curNodeType}, nil
+		NodeId:        id, NodeType: curNodeType}
+
+	stats := node.GetTrafficStats()
+	info.Uptime = time.Since(node.GetStartTime()).Truncate(time.Second).String()
+	info.SyncProgress = syncProgress(blockHeight)
+	info.BytesRecv = stats.BytesRecv
+	info.BytesSent = stats.BytesSent
+	info.MsgsRecv = stats.MsgsRecv
+	info.MsgsSent = stats.MsgsSent
+	return info, nil
+}
+
+func syncProgress(blockHeight uint32) string {
+	heights, _ := node.GetNeighborHeights()
+	var best uint64
+	for _, h := range heights {
+		if h > best {
+			best = h
+		}
+	}
+	if best <= uint64(blockHeight) {
+		return "100%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(blockHeight)*100/float64(best))
 }
 
 func viewHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +117,12 @@ func viewHandler(w http.ResponseWriter, r *http.Request) {
 		ngbId = fmt.Sprintf("0x%x", ngbrNoders[i].GetID())
 
 		ngbrInfo := newNgbNodeInfo(ngbId, ngbType, ngbAddr, ngbHttpInfoAddr, ngbInfoPort, ngbInfoState)
+		stats := ngbrNoders[i].GetTrafficStats()
+		ngbrInfo.Height = ngbrNoders[i].GetHeight()
+		ngbrInfo.PingTime = ngbrNoders[i].GetPingRTT().String()
+		ngbrInfo.BytesRecv = stats.BytesRecv
+		ngbrInfo.BytesSent = stats.BytesSent
+		ngbrInfo.ConnTime = time.Since(ngbrNoders[i].GetStartTime()).Truncate(time.Second).String()
 		ngbrNodersInfo = append(ngbrNodersInfo, *ngbrInfo)
 	}
 	sort.Sort(NgbNodeInfoSlice(ngbrNodersInfo))
@@ -42,7 +42,10 @@ const page = `
 	<tr><td colspan="1" width="25%">Node Version:</td><td width="25%">{{.NodeVersion}}</td><td width="25%">NodeID:</td><td width="25%">{{.NodeId}}</td></tr>
 	<tr><td width="25%">NodeType:</td><td width="25%">{{.NodeType}}</td><td width="25%">NodePort:</td><td width="25%">{{.NodePort}}</td></tr>
 	<tr><td width="25%">HttpRestPort:</td><td width="25%">{{.HttpRestPort}}</td><td width="25%">HttpWsPort:</td><td width="25%">{{.HttpWsPort}}</td></tr>
-	<tr><td width="25%">HttpJsonPort:</td><td width="25%">{{.HttpJsonPort}}</td></tr>
+	<tr><td width="25%">HttpJsonPort:</td><td width="25%">{{.HttpJsonPort}}</td><td width="25%">Uptime:</td><td width="25%">{{.Uptime}}</td></tr>
+	<tr><td width="25%">SyncProgress:</td><td width="25%">{{.SyncProgress}}</td></tr>
+	<tr><td width="25%">BytesRecv:</td><td width="25%">{{.BytesRecv}}</td><td width="25%">BytesSent:</td><td width="25%">{{.BytesSent}}</td></tr>
+	<tr><td width="25%">MsgsRecv:</td><td width="25%">{{.MsgsRecv}}</td><td width="25%">MsgsSent:</td><td width="25%">{{.MsgsSent}}</td></tr>
 	</table>
 </td>
 </tr>
@@ -64,12 +67,12 @@ const page = `
 </td>
 <td width="80%">
 	<table class="font" width="100%">
-	<tr><th>Neighbor IP</th><th>Neighbor Id</th><th>Neighbor Type</th></tr>
+	<tr><th>Neighbor IP</th><th>Neighbor Id</th><th>Neighbor Type</th><th>Height</th><th>Ping</th><th>Recv/Sent Bytes</th><th>Connected</th></tr>
 	{{range .Neighbors}}
 	{{if .HttpInfoStart}}
-	<tr><td align="center">{{.NgbAddr}}</td><td align="center"><a href="http://{{.HttpInfoAddr}}/info" style="cursor:hand">{{.NgbId}}</a></td><td align="center">{{.NgbType}}</td></tr>
+	<tr><td align="center">{{.NgbAddr}}</td><td align="center"><a href="http://{{.HttpInfoAddr}}/info" style="cursor:hand">{{.NgbId}}</a></td><td align="center">{{.NgbType}}</td><td align="center">{{.Height}}</td><td align="center">{{.PingTime}}</td><td align="center">{{.BytesRecv}}/{{.BytesSent}}</td><td align="center">{{.ConnTime}}</td></tr>
 	{{else}}
-	<tr><td align="center">{{.NgbAddr}}</td><td align="center">{{.NgbId}}</td><td align="center">{{.NgbType}}</td></tr>
+	<tr><td align="center">{{.NgbAddr}}</td><td align="center">{{.NgbId}}</td><td align="center">{{.NgbType}}</td><td align="center">{{.Height}}</td><td align="center">{{.PingTime}}</td><td align="center">{{.BytesRecv}}/{{.BytesSent}}</td><td align="center">{{.ConnTime}}</td></tr>
 	{{end}}
 	{{end}}
 	</table>
@@ -67,4 +67,5 @@ var ErrMap = map[int64]string{
 	int64(ErrUnknownReferedTxn):    "INTERNAL ERROR, ErrUnknownReferedTxn",
 	int64(ErrInvalidReferedTxn):    "INTERNAL ERROR, ErrInvalidReferedTxn",
 	int64(ErrIneffectiveCoinbase):  "INTERNAL ERROR, ErrIneffectiveCoinbase",
+	int64(ErrInsufficientFee):      "INTERNAL ERROR, ErrInsufficientFee",
 }
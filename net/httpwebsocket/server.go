@@ -20,12 +20,14 @@ var (
 	pushRawBlockFlag bool = false
 	pushBlockTxsFlag bool = false
 	pushNewTxsFlag   bool = true
+	pushChainTipFlag bool = true
 )
 
 func StartServer(n Noder) {
 	common.SetNode(n)
 	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventBlockPersistCompleted, SendBlock2WSclient)
 	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventNewTransactionPutInPool, SendTransaction2WSclient)
+	ledger.DefaultLedger.Blockchain.BCEvents.Subscribe(events.EventChainTipChanged, SendChainTipChange2WSclient)
 	go func() {
 		ws = websocket.InitWsServer(common.CheckAccessToken)
 		ws.Start()
@@ -52,6 +54,14 @@ func SendBlock2WSclient(v interface{}) {
 		}()
 	}
 }
+
+// SendChainTipChange2WSclient pushes the change on the goroutine delivering
+// it, so the clients get the changes in order.
+func SendChainTipChange2WSclient(v interface{}) {
+	if Parameters.HttpWsPort != 0 && pushChainTipFlag {
+		PushChainTipChange(v)
+	}
+}
 func Stop() {
 	if ws == nil {
 		return
@@ -87,6 +97,9 @@ func SetPushBlockTxsFlag(b bool) {
 func SetPushNewTxsFlag(b bool) {
 	pushNewTxsFlag = b
 }
+func SetPushChainTipFlag(b bool) {
+	pushChainTipFlag = b
+}
 func SetTxHashMap(txhash string, sessionid string) {
 	if ws == nil {
 		return
@@ -154,3 +167,15 @@ func PushBlockTransactions(v interface{}) {
 		ws.PushResult(resp)
 	}
 }
+
+func PushChainTipChange(v interface{}) {
+	if ws == nil {
+		return
+	}
+	resp := common.ResponsePack(Err.SUCCESS)
+	if change, ok := v.(*ledger.ChainTipChange); ok {
+		resp["Result"] = GetChainTipChangeInfo(change)
+		resp["Action"] = "sendchaintipchanged"
+		ws.PushResult(resp)
+	}
+}
@@ -71,6 +71,7 @@ func (msg block) Handle(node Noder) error {
 
 	if err != nil {
 		log.Warn("Block add failed: ", err, " ,block hash is ", hash.ToArrayReverse())
+		SendReject(node, "block", REJECTINVALID, err.Error(), hash)
 		return err
 	}
 	//relay
@@ -168,9 +169,15 @@ func (msg dataReq) Handle(node Noder) error {
 		node.Tx(buf)
 
 	case common.TRANSACTION:
-		txn, err := NewTxnFromHash(hash)
-		if err != nil {
-			return err
+		txn := node.LocalNode().GetTransaction(hash)
+		if txn == nil {
+			var err error
+			txn, err = NewTxnFromHash(hash)
+			if err != nil {
+				b, _ := NewNotFound(hash)
+				go node.Tx(b)
+				return err
+			}
 		}
 		buf, err := NewTxn(txn)
 		if err != nil {
@@ -0,0 +1,96 @@
+package message
+
+import (
+	"DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	. "DNA_POW/net/protocol"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// The feefilter message tells the peer not to announce the transactions
+// whose FeePerKB is lower than feeRate
+type feeFilter struct {
+	msgHdr
+	feeRate int64
+}
+
+func NewFeeFilter(feeRate common.Fixed64) ([]byte, error) {
+	log.Debug()
+	var msg feeFilter
+	msg.feeRate = int64(feeRate)
+	msg.msgHdr.Magic = config.Parameters.Magic
+	cmd := "feefilter"
+	copy(msg.msgHdr.CMD[0:len(cmd)], cmd)
+	p := new(bytes.Buffer)
+	err := binary.Write(p, binary.LittleEndian, msg.feeRate)
+	if err != nil {
+		log.Error("Binary Write failed at new feefilter Msg")
+		return nil, err
+	}
+	s := sha256.Sum256(p.Bytes())
+	s2 := s[:]
+	s = sha256.Sum256(s2)
+	buf := bytes.NewBuffer(s[:4])
+	binary.Read(buf, binary.LittleEndian, &(msg.msgHdr.Checksum))
+	msg.msgHdr.Length = uint32(len(p.Bytes()))
+	log.Debug("The message payload length is ", msg.msgHdr.Length)
+
+	m, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (msg feeFilter) Verify(buf []byte) error {
+	err := msg.msgHdr.Verify(buf)
+	// TODO verify the message Content
+	return err
+}
+
+func (msg feeFilter) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	err = binary.Write(buf, binary.LittleEndian, msg.feeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), err
+}
+
+func (msg *feeFilter) Deserialization(p []byte) error {
+	buf := bytes.NewBuffer(p)
+
+	err := binary.Read(buf, binary.LittleEndian, &(msg.msgHdr))
+	if err != nil {
+		log.Warn("Parse feefilter message hdr error")
+		return errors.New("Parse feefilter message hdr error")
+	}
+
+	err = binary.Read(buf, binary.LittleEndian, &(msg.feeRate))
+	if err != nil {
+		log.Warn("Parse feefilter message error")
+		return errors.New("Parse feefilter message error")
+	}
+
+	return err
+}
+
+func (msg feeFilter) Handle(node Noder) error {
+	log.Debug("RX feefilter message, fee rate is ", msg.feeRate)
+	if msg.feeRate < 0 {
+		return errors.New("Invalid fee rate in feefilter message")
+	}
+	node.SetFeeFilter(common.Fixed64(msg.feeRate))
+	return nil
+}
@@ -204,13 +204,22 @@ func (msg Inv) Handle(node Noder) error {
 	log.Debug(fmt.Sprintf("The inv type: 0x%x block len: %d, %s\n",
 		msg.P.InvType, len(msg.P.Blk), str))
 
+	// the hashes are read up to the count
+	if uint64(msg.P.Cnt)*HASHLEN > uint64(len(msg.P.Blk)) {
+		return errors.New("inv count exceeds the hashes in the message")
+	}
 	invType := InventoryType(msg.P.InvType)
 	switch invType {
 	case TRANSACTION:
 		log.Debug("RX TRX message")
-		// TODO check the ID queue
-		id.Deserialize(bytes.NewReader(msg.P.Blk[:32]))
-		if !node.ExistedID(id) {
+		var i uint32
+		for i = 0; i < msg.P.Cnt; i++ {
+			id.Deserialize(bytes.NewReader(msg.P.Blk[HASHLEN*i:]))
+			if node.LocalNode().GetTransaction(id) != nil ||
+				node.LocalNode().ExistOrphanTransaction(id) ||
+				ledger.DefaultLedger.Store.IsTxHashDuplicate(id) {
+				continue
+			}
 			reqTxnData(node, id)
 		}
 	case BLOCK:
@@ -257,6 +266,9 @@ func (msg Inv) Handle(node Noder) error {
 		}
 	case CONSENSUS:
 		log.Debug("RX consensus message")
+		if len(msg.P.Blk) < HASHLEN {
+			return errors.New("consensus inv without a hash")
+		}
 		id.Deserialize(bytes.NewReader(msg.P.Blk[:32]))
 		reqConsensusData(node, id)
 	default:
@@ -293,6 +305,10 @@ func (msg *Inv) Deserialization(p []byte) error {
 		return err
 	}
 
+	// a peer may claim more hashes than the message carries
+	if uint64(msg.P.Cnt)*HASHLEN > uint64(buf.Len()) {
+		return errors.New("inv count exceeds the message length")
+	}
 	msg.P.Blk = make([]byte, msg.P.Cnt*HASHLEN)
 	err = binary.Read(buf, binary.LittleEndian, &(msg.P.Blk))
 
@@ -155,9 +155,14 @@ func AllocMsg(t string, length int) Messager {
 		var msg pong
 		copy(msg.msgHdr.CMD[0:len(t)], t)
 		return &msg
+	case "feefilter":
+		var msg feeFilter
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
 	case "reject":
-		log.Warn("Not supported message type - reject")
-		return nil
+		var msg reject
+		copy(msg.msgHdr.CMD[0:len(t)], t)
+		return &msg
 	default:
 		log.Warn("Unknown message type")
 		return nil
@@ -174,6 +179,32 @@ func MsgType(buf []byte) (string, error) {
 	return s, nil
 }
 
+// MsgVersion returns the protocol version since which the message type is
+// supported, the messages of the original protocol return 0
+func MsgVersion(t string) uint32 {
+	switch t {
+	case "feefilter":
+		return FEEFILTERVERSION
+	case "reject":
+		return REJECTVERSION
+	default:
+		return 0
+	}
+}
+
+// KnownMsgType reports whether the message type is one AllocMsg allocates
+func KnownMsgType(t string) bool {
+	switch t {
+	case "version", "verack", "getheaders", "headers", "getaddr", "addr",
+		"inv", "getdata", "block", "tx", "consensus", "filteradd",
+		"filterclear", "filterload", "getblocks", "txnpool", "alert",
+		"merkleblock", "notfound", "ping", "pong", "feefilter", "reject":
+		return true
+	default:
+		return false
+	}
+}
+
 // TODO combine all of message alloc in one function via interface
 func NewMsg(t string, n Noder) ([]byte, error) {
 	switch t {
@@ -204,6 +235,11 @@ func HandleNodeMsg(node Noder, buf []byte, len int) error {
 		return err
 	}
 
+	if node.Version() < MsgVersion(s) {
+		log.Warn(fmt.Sprintf("Message %s is not supported by protocol version %d", s, node.Version()))
+		return errors.New("Message not supported by the negotiated protocol version")
+	}
+
 	if s == "inv" || s == "block" {
 		node.LocalNode().AcqSyncBlkReqSem()
 		msg := AllocMsg(s, len)
@@ -0,0 +1,184 @@
+package message
+
+import (
+	"DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"DNA_POW/common/serialization"
+	. "DNA_POW/errors"
+	. "DNA_POW/net/protocol"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The reject codes
+const (
+	REJECTMALFORMED       = 0x01
+	REJECTINVALID         = 0x10
+	REJECTOBSOLETE        = 0x11
+	REJECTDUPLICATE       = 0x12
+	REJECTNONSTANDARD     = 0x40
+	REJECTDUST            = 0x41
+	REJECTINSUFFICIENTFEE = 0x42
+	REJECTCHECKPOINT      = 0x43
+)
+
+// Max length of the reject reason string
+const MAXREJECTREASONLEN = 256
+
+// The reject message tells the peer why its message is refused
+type reject struct {
+	msgHdr
+	cmd    string         // The command of the rejected message
+	code   uint8          // The reject code
+	reason string         // The human readable reason
+	hash   common.Uint256 // The hash of the rejected block or transaction
+}
+
+func NewReject(cmd string, code uint8, reason string, hash common.Uint256) ([]byte, error) {
+	log.Debug()
+	var msg reject
+	if len(reason) > MAXREJECTREASONLEN {
+		reason = reason[:MAXREJECTREASONLEN]
+	}
+	msg.cmd = cmd
+	msg.code = code
+	msg.reason = reason
+	msg.hash = hash
+	msg.msgHdr.Magic = config.Parameters.Magic
+	c := "reject"
+	copy(msg.msgHdr.CMD[0:len(c)], c)
+	p := new(bytes.Buffer)
+	err := msg.serializePayload(p)
+	if err != nil {
+		log.Error("Binary Write failed at new reject Msg")
+		return nil, err
+	}
+	s := sha256.Sum256(p.Bytes())
+	s2 := s[:]
+	s = sha256.Sum256(s2)
+	buf := bytes.NewBuffer(s[:4])
+	binary.Read(buf, binary.LittleEndian, &(msg.msgHdr.Checksum))
+	msg.msgHdr.Length = uint32(len(p.Bytes()))
+	log.Debug("The message payload length is ", msg.msgHdr.Length)
+
+	m, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// SendReject sends a reject message to the node if its protocol version
+// supports it.
+func SendReject(node Noder, cmd string, code uint8, reason string, hash common.Uint256) {
+	if node.Version() < REJECTVERSION {
+		return
+	}
+	buf, err := NewReject(cmd, code, reason, hash)
+	if err != nil {
+		return
+	}
+	go node.Tx(buf)
+}
+
+// RejectCode maps the transaction verification error to the reject code
+func RejectCode(errCode ErrCode) uint8 {
+	switch errCode {
+	case ErrDoubleSpend, ErrTxHashDuplicate, ErrDuplicatedTx:
+		return REJECTDUPLICATE
+	case ErrInsufficientFee:
+		return REJECTINSUFFICIENTFEE
+	case ErrTransactionSize:
+		return REJECTNONSTANDARD
+	default:
+		return REJECTINVALID
+	}
+}
+
+func (msg reject) serializePayload(buf *bytes.Buffer) error {
+	err := serialization.WriteVarString(buf, msg.cmd)
+	if err != nil {
+		return err
+	}
+	err = serialization.WriteUint8(buf, msg.code)
+	if err != nil {
+		return err
+	}
+	err = serialization.WriteVarString(buf, msg.reason)
+	if err != nil {
+		return err
+	}
+	_, err = msg.hash.Serialize(buf)
+	return err
+}
+
+func (msg reject) Verify(buf []byte) error {
+	err := msg.msgHdr.Verify(buf)
+	// TODO verify the message Content
+	return err
+}
+
+func (msg reject) Serialization() ([]byte, error) {
+	hdrBuf, err := msg.msgHdr.Serialization()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(hdrBuf)
+	err = msg.serializePayload(buf)
+
+	return buf.Bytes(), err
+}
+
+func (msg *reject) Deserialization(p []byte) error {
+	buf := bytes.NewBuffer(p)
+
+	err := binary.Read(buf, binary.LittleEndian, &(msg.msgHdr))
+	if err != nil {
+		log.Warn("Parse reject message hdr error")
+		return errors.New("Parse reject message hdr error")
+	}
+
+	msg.cmd, err = serialization.ReadVarString(buf)
+	if err != nil {
+		log.Warn("Parse reject message command error")
+		return errors.New("Parse reject message command error")
+	}
+	msg.code, err = serialization.ReadUint8(buf)
+	if err != nil {
+		log.Warn("Parse reject message code error")
+		return errors.New("Parse reject message code error")
+	}
+	msg.reason, err = serialization.ReadVarString(buf)
+	if err != nil {
+		log.Warn("Parse reject message reason error")
+		return errors.New("Parse reject message reason error")
+	}
+	err = msg.hash.Deserialize(buf)
+	if err != nil {
+		log.Warn("Parse reject message hash error")
+		return errors.New("Parse reject message hash error")
+	}
+
+	return nil
+}
+
+func (msg reject) Handle(node Noder) error {
+	log.Warn(fmt.Sprintf("RX reject message from %s: %s code 0x%02x reason \"%s\" hash %x",
+		node.GetAddr(), msg.cmd, msg.code, msg.reason, msg.hash.ToArrayReverse()))
+	node.LocalNode().AddRejectInfo(RejectInfo{
+		Time:    time.Now().Unix(),
+		Addr:    node.GetAddr(),
+		Command: msg.cmd,
+		Code:    msg.code,
+		Reason:  msg.reason,
+		Hash:    msg.hash,
+	})
+	return nil
+}
@@ -34,7 +34,13 @@ func (msg trn) Handle(node Noder) error {
 	log.Debug("RX Transaction message")
 	tx := &msg.txn
 	if !node.LocalNode().ExistedID(tx.Hash()) {
-		if errCode := node.LocalNode().AppendTxnPool(&(msg.txn)); errCode != ErrNoError {
+		errCode := node.LocalNode().AppendTxnPool(&(msg.txn))
+		if errCode == ErrUnknownReferedTxn {
+			log.Debug("Hold orphan transaction ", tx.Hash())
+			return nil
+		}
+		if errCode != ErrNoError {
+			SendReject(node, "tx", RejectCode(errCode), errCode.Error(), tx.Hash())
 			return errors.New("[message] VerifyTransaction failed when AppendTxnPool.")
 		}
 		node.LocalNode().Relay(node, tx)
@@ -50,11 +56,31 @@ func (msg trn) Handle(node Noder) error {
 func reqTxnData(node Noder, hash common.Uint256) error {
 	var msg dataReq
 	msg.dataType = common.TRANSACTION
-	// TODO handle the hash array case
-	//msg.hash = hash
+	msg.hash = hash
 
-	buf, _ := msg.Serialization()
-	go node.Tx(buf)
+	msg.msgHdr.Magic = config.Parameters.Magic
+	copy(msg.msgHdr.CMD[0:7], "getdata")
+	p := bytes.NewBuffer([]byte{})
+	err := binary.Write(p, binary.LittleEndian, &(msg.dataType))
+	msg.hash.Serialize(p)
+	if err != nil {
+		log.Error("Binary Write failed at new getdata Msg")
+		return err
+	}
+	s := sha256.Sum256(p.Bytes())
+	s2 := s[:]
+	s = sha256.Sum256(s2)
+	buf := bytes.NewBuffer(s[:4])
+	binary.Read(buf, binary.LittleEndian, &(msg.msgHdr.Checksum))
+	msg.msgHdr.Length = uint32(len(p.Bytes()))
+
+	sendBuf, err := msg.Serialization()
+	if err != nil {
+		log.Error("Error Convert net message ", err.Error())
+		return err
+	}
+
+	go node.Tx(sendBuf)
 	return nil
 }
 
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"DNA_POW/common"
+	"DNA_POW/common/config"
 	"DNA_POW/common/log"
 	. "DNA_POW/net/protocol"
 	"encoding/hex"
@@ -65,6 +67,15 @@ func (msg verACK) Handle(node Noder) error {
 		node.Tx(buf)
 	}
 
+	// Tell the peer the lowest fee rate of transactions we accept
+	feeRate := config.Parameters.PowConfiguration.MinTxFeePerKB
+	if feeRate > 0 && node.Version() >= FEEFILTERVERSION {
+		buf, err := NewFeeFilter(common.Fixed64(feeRate))
+		if err == nil {
+			go node.Tx(buf)
+		}
+	}
+
 	//node.DumpInfo()
 	// Fixme, there is a race condition here,
 	// but it doesn't matter to access the invalid
@@ -67,6 +67,7 @@ func unpackNodeBuf(node *node, buf []byte) {
 	msgLen = node.rxBuf.len
 	if len(buf) == msgLen {
 		msgBuf = append(node.rxBuf.p, buf[:]...)
+		node.countRecv(msgBuf)
 		go msg.HandleNodeMsg(node, msgBuf, len(msgBuf))
 		node.rxBuf.p = nil
 		node.rxBuf.len = 0
@@ -75,6 +76,7 @@ func unpackNodeBuf(node *node, buf []byte) {
 		node.rxBuf.len = msgLen - len(buf)
 	} else {
 		msgBuf = append(node.rxBuf.p, buf[0:msgLen]...)
+		node.countRecv(msgBuf)
 		go msg.HandleNodeMsg(node, msgBuf, len(msgBuf))
 		node.rxBuf.p = nil
 		node.rxBuf.len = 0
@@ -313,5 +315,7 @@ func (node *node) Tx(buf []byte) {
 	if err != nil {
 		log.Error("Error sending messge to peer node ", err.Error())
 		node.local.eventQueue.GetEvent("disconnect").Notify(events.EventNodeDisconnect, node)
+		return
 	}
+	node.countSent(buf)
 }
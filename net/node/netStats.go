@@ -0,0 +1,159 @@
+package node
+
+import (
+	msg "DNA_POW/net/message"
+	. "DNA_POW/net/protocol"
+	"sync"
+	"time"
+)
+
+// trafficStats counts the messages and bytes sent to and received from a
+// neighbor. The counters of the local node are the totals of all neighbors.
+type trafficStats struct {
+	statsLock  sync.RWMutex
+	startTime  time.Time // The time the node started or the neighbor connected
+	bytesSent  uint64
+	bytesRecv  uint64
+	msgsSent   uint64
+	msgsRecv   uint64
+	sentPerMsg map[string]*MsgCounter
+	recvPerMsg map[string]*MsgCounter
+	pingSent   time.Time     // The time the last unanswered ping was sent
+	pingRTT    time.Duration // The round trip time of the last ping
+}
+
+func (ts *trafficStats) initStats() {
+	ts.statsLock.Lock()
+	defer ts.statsLock.Unlock()
+	ts.startTime = time.Now()
+	ts.sentPerMsg = make(map[string]*MsgCounter)
+	ts.recvPerMsg = make(map[string]*MsgCounter)
+}
+
+// otherMsgs is the counter of the messages of an unknown type, the peer
+// chooses the type so it is not a key of its own.
+const otherMsgs = "other"
+
+func addMsgCounter(counters map[string]*MsgCounter, cmd string, n int) {
+	if !msg.KnownMsgType(cmd) {
+		cmd = otherMsgs
+	}
+	c, ok := counters[cmd]
+	if !ok {
+		c = new(MsgCounter)
+		counters[cmd] = c
+	}
+	c.Msgs++
+	c.Bytes += uint64(n)
+}
+
+func (ts *trafficStats) addSent(cmd string, n int) {
+	ts.statsLock.Lock()
+	defer ts.statsLock.Unlock()
+	if ts.sentPerMsg == nil {
+		ts.sentPerMsg = make(map[string]*MsgCounter)
+	}
+	ts.bytesSent += uint64(n)
+	ts.msgsSent++
+	addMsgCounter(ts.sentPerMsg, cmd, n)
+}
+
+func (ts *trafficStats) addRecv(cmd string, n int) {
+	ts.statsLock.Lock()
+	defer ts.statsLock.Unlock()
+	if ts.recvPerMsg == nil {
+		ts.recvPerMsg = make(map[string]*MsgCounter)
+	}
+	ts.bytesRecv += uint64(n)
+	ts.msgsRecv++
+	addMsgCounter(ts.recvPerMsg, cmd, n)
+}
+
+// The ping and pong messages carry no nonce, so the RTT is measured from the
+// first unanswered ping to the next pong.
+func (ts *trafficStats) pingStarted() {
+	ts.statsLock.Lock()
+	defer ts.statsLock.Unlock()
+	if ts.pingSent.IsZero() {
+		ts.pingSent = time.Now()
+	}
+}
+
+func (ts *trafficStats) pongReceived() {
+	ts.statsLock.Lock()
+	defer ts.statsLock.Unlock()
+	if !ts.pingSent.IsZero() {
+		ts.pingRTT = time.Since(ts.pingSent)
+		ts.pingSent = time.Time{}
+	}
+}
+
+func (ts *trafficStats) GetTrafficStats() TrafficStats {
+	ts.statsLock.RLock()
+	defer ts.statsLock.RUnlock()
+	stats := TrafficStats{
+		BytesSent:  ts.bytesSent,
+		BytesRecv:  ts.bytesRecv,
+		MsgsSent:   ts.msgsSent,
+		MsgsRecv:   ts.msgsRecv,
+		SentPerMsg: make(map[string]MsgCounter, len(ts.sentPerMsg)),
+		RecvPerMsg: make(map[string]MsgCounter, len(ts.recvPerMsg)),
+	}
+	for cmd, c := range ts.sentPerMsg {
+		stats.SentPerMsg[cmd] = *c
+	}
+	for cmd, c := range ts.recvPerMsg {
+		stats.RecvPerMsg[cmd] = *c
+	}
+	return stats
+}
+
+func (ts *trafficStats) GetPingRTT() time.Duration {
+	ts.statsLock.RLock()
+	defer ts.statsLock.RUnlock()
+	return ts.pingRTT
+}
+
+func (ts *trafficStats) GetStartTime() time.Time {
+	ts.statsLock.RLock()
+	defer ts.statsLock.RUnlock()
+	return ts.startTime
+}
+
+// countSent records the message sent to the neighbor in the neighbor and the
+// local node counters.
+func (node *node) countSent(buf []byte) {
+	if len(buf) < MSGHDRLEN {
+		return
+	}
+	cmd, err := msg.MsgType(buf)
+	if err != nil {
+		return
+	}
+	node.addSent(cmd, len(buf))
+	if cmd == "ping" {
+		node.pingStarted()
+	}
+	if node.local != nil && node.local != node {
+		node.local.addSent(cmd, len(buf))
+	}
+}
+
+// countRecv records the message received from the neighbor in the neighbor
+// and the local node counters.
+func (node *node) countRecv(buf []byte) {
+	if len(buf) < MSGHDRLEN {
+		return
+	}
+	cmd, err := msg.MsgType(buf)
+	if err != nil {
+		return
+	}
+	node.addRecv(cmd, len(buf))
+	if cmd == "pong" {
+		node.pongReceived()
+	}
+	if node.local != nil && node.local != node {
+		node.local.addRecv(cmd, len(buf))
+	}
+}
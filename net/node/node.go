@@ -85,6 +85,11 @@ type node struct {
 	SyncHdrReqSem  Semaphore
 	StartHash      Uint256
 	StopHash       Uint256
+	feeFilter      int64 // The minimum FeePerKB of transactions announced to this node
+	txnTrickle           // The transaction hashes waiting to be announced to this node
+	rejectLock     sync.RWMutex
+	rejectInfos    []RejectInfo // The reject messages received from neighbors
+	trafficStats                // The messages and bytes sent and received
 }
 
 type RetryConnAddrs struct {
@@ -157,6 +162,10 @@ func (node *node) UpdateInfo(t time.Time, version uint32, services uint64,
 
 	node.UpdateRXTime(t)
 	node.id = nonce
+	// Use the lower protocol version of both sides
+	if version > PROTOCOLVERSION {
+		version = PROTOCOLVERSION
+	}
 	node.version = version
 	node.services = services
 	node.port = port
@@ -173,6 +182,7 @@ func NewNode() *node {
 		state: INIT,
 		chF:   make(chan func() error),
 	}
+	n.initStats()
 	runtime.SetFinalizer(&n, rmNode)
 	go n.backend()
 	return &n
@@ -227,6 +237,7 @@ func InitNode(pubKey *crypto.PubKey) Noder {
 	go n.initConnection()
 	go n.updateConnection()
 	go n.updateNodeInfo()
+	go n.trickleTxnInv()
 
 	return n
 }
@@ -387,12 +398,8 @@ func (node *node) Xmit(message interface{}) error {
 	case *transaction.Transaction:
 		log.Debug("TX transaction message")
 		txn := message.(*transaction.Transaction)
-		buffer, err = NewTxn(txn)
-		if err != nil {
-			log.Error("Error New Tx message: ", err)
-			return err
-		}
-		node.txnCnt++
+		node.queueTxnToNbrs(node.id, txn)
+		return nil
 	case *ledger.Block:
 		log.Debug("TX block message")
 		block := message.(*ledger.Block)
@@ -595,12 +602,8 @@ func (node *node) Relay(frmnode Noder, message interface{}) error {
 	case *transaction.Transaction:
 		log.Debug("TX transaction message")
 		txn := message.(*transaction.Transaction)
-		buffer, err = NewTxn(txn)
-		if err != nil {
-			log.Error("Error New Tx message: ", err)
-			return err
-		}
-		node.txnCnt++
+		node.queueTxnToNbrs(frmnode.GetID(), txn)
+		return nil
 	case *ConsensusPayload:
 		log.Debug("TX consensus message")
 		consensusPayload := message.(*ConsensusPayload)
@@ -649,6 +652,41 @@ func (node *node) Relay(frmnode Noder, message interface{}) error {
 	return nil
 }
 
+func (node *node) SetFeeFilter(feeRate Fixed64) {
+	atomic.StoreInt64(&node.feeFilter, int64(feeRate))
+}
+
+func (node *node) GetFeeFilter() Fixed64 {
+	return Fixed64(atomic.LoadInt64(&node.feeFilter))
+}
+
+func (node *node) AddRejectInfo(info RejectInfo) {
+	node.rejectLock.Lock()
+	defer node.rejectLock.Unlock()
+	node.rejectInfos = append(node.rejectInfos, info)
+	if len(node.rejectInfos) > MAXREJECTINFO {
+		node.rejectInfos = append(node.rejectInfos[:0], node.rejectInfos[1:]...)
+	}
+}
+
+func (node *node) GetRejectInfos() []RejectInfo {
+	node.rejectLock.RLock()
+	defer node.rejectLock.RUnlock()
+	infos := make([]RejectInfo, len(node.rejectInfos))
+	copy(infos, node.rejectInfos)
+	return infos
+}
+
+// CleanSubmittedTransactions cleans the transaction pool with the committed
+// block and relays the orphan transactions accepted after it.
+func (node *node) CleanSubmittedTransactions(block *ledger.Block) error {
+	err := node.TXNPool.CleanSubmittedTransactions(block)
+	for _, txn := range node.TXNPool.ProcessOrphans(block.Transactions) {
+		node.Relay(node, txn)
+	}
+	return err
+}
+
 func (node *node) CacheHash(hash Uint256) {
 	node.cachelock.Lock()
 	defer node.cachelock.Unlock()
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"DNA_POW/common"
+	"DNA_POW/common/log"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+	. "DNA_POW/net/protocol"
+	"sync"
+	"time"
+)
+
+type orphanTxn struct {
+	txn     *transaction.Transaction
+	parents []common.Uint256
+	expire  time.Time
+}
+
+// orphanPool holds the transactions which spend outputs of transactions not
+// yet in the ledger. They are retried when the missing parents get committed.
+type orphanPool struct {
+	sync.RWMutex
+	orphans     map[common.Uint256]*orphanTxn
+	prevOrphans map[common.Uint256]map[common.Uint256]*transaction.Transaction // parent hash -> orphans
+	nextExpire  time.Time
+}
+
+func (op *orphanPool) init() {
+	op.Lock()
+	defer op.Unlock()
+	op.orphans = make(map[common.Uint256]*orphanTxn)
+	op.prevOrphans = make(map[common.Uint256]map[common.Uint256]*transaction.Transaction)
+	op.nextExpire = time.Now().Add(time.Second * ORPHANTXNSCANTIME)
+}
+
+// missingParents returns the referenced transactions of txn which can not
+// be found in the ledger.
+func missingParents(txn *transaction.Transaction) []common.Uint256 {
+	var parents []common.Uint256
+	for _, input := range txn.UTXOInputs {
+		if _, _, err := ledger.DefaultLedger.Store.GetTransaction(input.ReferTxID); err != nil {
+			parents = append(parents, input.ReferTxID)
+		}
+	}
+	return parents
+}
+
+func (op *orphanPool) ExistOrphanTransaction(hash common.Uint256) bool {
+	op.RLock()
+	defer op.RUnlock()
+	_, ok := op.orphans[hash]
+	return ok
+}
+
+func (op *orphanPool) GetOrphanCount() int {
+	op.RLock()
+	defer op.RUnlock()
+	return len(op.orphans)
+}
+
+func (op *orphanPool) addOrphan(txn *transaction.Transaction, parents []common.Uint256) {
+	if txn.GetSize() > MAXORPHANTXNSIZE {
+		log.Info("Drop oversize orphan transaction ", txn.Hash())
+		return
+	}
+
+	op.Lock()
+	defer op.Unlock()
+	hash := txn.Hash()
+	if _, ok := op.orphans[hash]; ok {
+		return
+	}
+	op.limitOrphans()
+	op.orphans[hash] = &orphanTxn{
+		txn:     txn,
+		parents: parents,
+		expire:  time.Now().Add(time.Second * ORPHANTXNEXPIRE),
+	}
+	for _, parent := range parents {
+		if _, ok := op.prevOrphans[parent]; !ok {
+			op.prevOrphans[parent] = make(map[common.Uint256]*transaction.Transaction)
+		}
+		op.prevOrphans[parent][hash] = txn
+	}
+	log.Debug("Stored orphan transaction ", hash, ", total orphans ", len(op.orphans))
+}
+
+// limitOrphans removes the expired orphans and evicts a random one when the
+// pool is full. It should be called with the lock held.
+func (op *orphanPool) limitOrphans() {
+	now := time.Now()
+	if now.After(op.nextExpire) {
+		for hash, otx := range op.orphans {
+			if now.After(otx.expire) {
+				op.delOrphan(hash)
+			}
+		}
+		op.nextExpire = now.Add(time.Second * ORPHANTXNSCANTIME)
+	}
+
+	if len(op.orphans) < MAXORPHANTXNS {
+		return
+	}
+	// map iteration order is random
+	for hash := range op.orphans {
+		op.delOrphan(hash)
+		break
+	}
+}
+
+// delOrphan should be called with the lock held.
+func (op *orphanPool) delOrphan(hash common.Uint256) {
+	otx, ok := op.orphans[hash]
+	if !ok {
+		return
+	}
+	for _, parent := range otx.parents {
+		if orphans, ok := op.prevOrphans[parent]; ok {
+			delete(orphans, hash)
+			if len(orphans) == 0 {
+				delete(op.prevOrphans, parent)
+			}
+		}
+	}
+	delete(op.orphans, hash)
+}
+
+// takeOrphans removes the orphans which are committed in txns or spend their
+// outputs from the pool and returns the latter.
+func (op *orphanPool) takeOrphans(txns []*transaction.Transaction) []*transaction.Transaction {
+	op.Lock()
+	defer op.Unlock()
+	var children []*transaction.Transaction
+	for _, txn := range txns {
+		hash := txn.Hash()
+		op.delOrphan(hash)
+		for childHash, child := range op.prevOrphans[hash] {
+			op.delOrphan(childHash)
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -25,6 +25,7 @@ type TXNPool struct {
 	txnList map[common.Uint256]*transaction.Transaction // transaction which have been verifyed will put into this map
 	//issueSummary  map[common.Uint256]common.Fixed64           // transaction which pass the verify will summary the amout to this map
 	inputUTXOList map[string]*transaction.Transaction // transaction which pass the verify will add the UTXO to this map
+	orphanPool                                        // transaction whose referenced transactions are not in ledger yet
 }
 
 func (this *TXNPool) init() {
@@ -34,11 +35,20 @@ func (this *TXNPool) init() {
 	this.inputUTXOList = make(map[string]*transaction.Transaction)
 	//this.issueSummary = make(map[common.Uint256]common.Fixed64)
 	this.txnList = make(map[common.Uint256]*transaction.Transaction)
+	this.orphanPool.init()
 }
 
 //append transaction to txnpool when check ok.
 //1.check transaction. 2.check with ledger(db) 3.check with pool
+//transaction referencing unknown transactions is kept in the orphan pool.
 func (this *TXNPool) AppendTxnPool(txn *transaction.Transaction) ErrCode {
+	if !txn.IsCoinBaseTx() {
+		if parents := missingParents(txn); len(parents) > 0 {
+			log.Info("Orphan transaction ", txn.Hash(), " missing ", len(parents), " referenced transactions")
+			this.addOrphan(txn, parents)
+			return ErrUnknownReferedTxn
+		}
+	}
 	//verify transaction with Concurrency
 	if errCode := ledger.CheckTransactionSanity(txn); errCode != ErrNoError {
 		log.Info("Transaction verification failed", txn.Hash())
@@ -48,15 +58,28 @@ func (this *TXNPool) AppendTxnPool(txn *transaction.Transaction) ErrCode {
 		log.Info("Transaction verification with ledger failed", txn.Hash())
 		return errCode
 	}
-	//verify transaction by pool with lock
-	if ok := this.verifyTransactionWithTxnPool(txn); !ok {
-		return ErrDoubleSpend
+	// The transaction has to be allowed in the next block
+	if !txn.IsCoinBaseTx() {
+		if err := ledger.DefaultLedger.Blockchain.CheckTransactionLocks(txn, ledger.DefaultLedger.Blockchain.BestChain); err != nil {
+			log.Info("Transaction lock time check failed", txn.Hash(), err)
+			return ErrTxLockTime
+		}
 	}
 
 	txn.Fee = common.Fixed64(txn.GetFee(ledger.DefaultLedger.Blockchain.AssetID))
 	b_buf := new(bytes.Buffer)
 	txn.Serialize(b_buf)
 	txn.FeePerKB = txn.Fee * 1000 / common.Fixed64(len(b_buf.Bytes()))
+	if txn.FeePerKB < common.Fixed64(config.Parameters.PowConfiguration.MinTxFeePerKB) {
+		log.Info("Transaction fee rate ", txn.FeePerKB, " is lower than the minimum")
+		return ErrInsufficientFee
+	}
+
+	//verify transaction by pool with lock
+	if ok := this.verifyTransactionWithTxnPool(txn); !ok {
+		return ErrDoubleSpend
+	}
+
 	//add the transaction to process scope
 	this.addtxnList(txn)
 	return ErrNoError
@@ -93,6 +116,19 @@ func (this *TXNPool) CleanSubmittedTransactions(block *ledger.Block) error {
 	return nil
 }
 
+//retry the orphans whose referenced transactions are committed, return the accepted ones.
+func (this *TXNPool) ProcessOrphans(txns []*transaction.Transaction) []*transaction.Transaction {
+	var accepted []*transaction.Transaction
+	for _, orphan := range this.takeOrphans(txns) {
+		if errCode := this.AppendTxnPool(orphan); errCode != ErrNoError {
+			log.Debug("Orphan transaction ", orphan.Hash(), " not accepted: ", errCode)
+			continue
+		}
+		accepted = append(accepted, orphan)
+	}
+	return accepted
+}
+
 //get the transaction by hash
 func (this *TXNPool) GetTransaction(hash common.Uint256) *transaction.Transaction {
 	this.RLock()
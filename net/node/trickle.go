@@ -0,0 +1,111 @@
+package node
+
+import (
+	. "DNA_POW/common"
+	"DNA_POW/common/log"
+	"DNA_POW/core/transaction"
+	. "DNA_POW/net/message"
+	. "DNA_POW/net/protocol"
+	"bytes"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Transactions are not relayed to the neighbors at once, the hashes are
+// queued for each neighbor and announced in batched inv messages at a
+// randomized interval, which makes it harder to find out the origin of a
+// transaction by timing.
+type txnTrickle struct {
+	trickleLock sync.Mutex
+	txnInvQueue map[Uint256]struct{}
+	nextTrickle time.Time
+}
+
+func (t *txnTrickle) queueTxnInv(hash Uint256) {
+	t.trickleLock.Lock()
+	defer t.trickleLock.Unlock()
+	if t.txnInvQueue == nil {
+		t.txnInvQueue = make(map[Uint256]struct{})
+	}
+	t.txnInvQueue[hash] = struct{}{}
+}
+
+// takeTxnInv returns the queued hashes in random order if the trickle time
+// of the node is reached.
+func (t *txnTrickle) takeTxnInv(now time.Time) []Uint256 {
+	t.trickleLock.Lock()
+	defer t.trickleLock.Unlock()
+	if now.Before(t.nextTrickle) {
+		return nil
+	}
+	// Poisson distributed announcement
+	delay := rand.ExpFloat64() * TXNTRICKLEINTERVAL
+	t.nextTrickle = now.Add(time.Duration(delay) * time.Millisecond)
+
+	if len(t.txnInvQueue) == 0 {
+		return nil
+	}
+	hashes := make([]Uint256, 0, len(t.txnInvQueue))
+	for hash := range t.txnInvQueue {
+		hashes = append(hashes, hash)
+		delete(t.txnInvQueue, hash)
+		if len(hashes) >= MAXINVTXNCNT {
+			break
+		}
+	}
+	for i := range hashes {
+		j := rand.Intn(i + 1)
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	return hashes
+}
+
+// queueTxnToNbrs queues the transaction hash for the established neighbors
+// except the one it comes from and the ones whose fee filter is above it.
+func (node *node) queueTxnToNbrs(frmID uint64, txn *transaction.Transaction) {
+	hash := txn.Hash()
+	node.nbrNodes.RLock()
+	defer node.nbrNodes.RUnlock()
+	for _, n := range node.nbrNodes.List {
+		if n.state != ESTABLISH || n.relay == false || n.id == frmID {
+			continue
+		}
+		if txn.FeePerKB < n.GetFeeFilter() {
+			log.Debug("Transaction fee rate below the filter of node ", n.id)
+			continue
+		}
+		n.queueTxnInv(hash)
+	}
+	node.txnCnt++
+}
+
+func (n *node) trickleTxnInv() {
+	ticker := time.NewTicker(time.Millisecond * TXNTRICKLETICK)
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, noder := range n.GetNeighborNoder() {
+				nbr, ok := noder.(*node)
+				if !ok {
+					continue
+				}
+				hashes := nbr.takeTxnInv(now)
+				if len(hashes) == 0 {
+					continue
+				}
+				buf := bytes.NewBuffer([]byte{})
+				for _, hash := range hashes {
+					hash.Serialize(buf)
+				}
+				invPayload := NewInvPayload(TRANSACTION, uint32(len(hashes)), buf.Bytes())
+				buffer, err := NewInv(invPayload)
+				if err != nil {
+					log.Error("Error New inv message")
+					continue
+				}
+				nbr.Tx(buffer)
+			}
+		}
+	}
+}
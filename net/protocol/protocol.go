@@ -46,6 +46,9 @@ const (
 	MAXREQBLKONCE     = 16
 	TIMESOFUPDATETIME = 2
 	MAXCACHEHASH      = 16
+	MAXINVTXNCNT      = 500 // Max transaction hashes announced in one inv
+	MAXORPHANTXNS     = 100
+	MAXORPHANTXNSIZE  = 5000
 )
 
 const (
@@ -53,7 +56,7 @@ const (
 	MAXHELLORETYR        = 3
 	MAXBUFLEN            = 1024 * 16 // Fixme The maximum buffer to receive message
 	MAXCHANBUF           = 512
-	PROTOCOLVERSION      = 0
+	PROTOCOLVERSION      = 1
 	PERIODUPDATETIME     = 3 // Time to update and sync information with other nodes
 	HEARTBEAT            = 2
 	KEEPALIVETIMEOUT     = 3
@@ -69,6 +72,17 @@ const (
 	MAXIDCACHED          = 5000
 	MAXINVCACHEHASH      = 50000
 	MinInFlightBlocks    = 10
+	TXNTRICKLEINTERVAL   = 5000 // Milliseconds, mean interval to announce transactions to a peer
+	TXNTRICKLETICK       = 100  // Milliseconds
+	ORPHANTXNEXPIRE      = 900  // Seconds
+	ORPHANTXNSCANTIME    = 300  // Seconds
+	MAXREJECTINFO        = 100
+)
+
+// The protocol version since which the message is supported
+const (
+	FEEFILTERVERSION = 1
+	REJECTVERSION    = 1
 )
 
 // The node state
@@ -83,6 +97,32 @@ const (
 
 var ReceiveDuplicateBlockCnt uint64 //an index to detecting networking status
 
+// MsgCounter counts the messages and bytes of one message type
+type MsgCounter struct {
+	Msgs  uint64
+	Bytes uint64
+}
+
+// TrafficStats is the network traffic of a node
+type TrafficStats struct {
+	BytesSent  uint64
+	BytesRecv  uint64
+	MsgsSent   uint64
+	MsgsRecv   uint64
+	SentPerMsg map[string]MsgCounter
+	RecvPerMsg map[string]MsgCounter
+}
+
+// RejectInfo records a reject message received from a neighbor
+type RejectInfo struct {
+	Time    int64
+	Addr    string
+	Command string
+	Code    uint8
+	Reason  string
+	Hash    common.Uint256
+}
+
 type Noder interface {
 	Version() uint32
 	GetID() uint64
@@ -135,6 +175,14 @@ type Noder interface {
 	CleanSubmittedTransactions(block *ledger.Block) error
 	MaybeAcceptTransaction(txn *transaction.Transaction) error
 	RemoveTransaction(txn *transaction.Transaction)
+	ExistOrphanTransaction(hash common.Uint256) bool
+	SetFeeFilter(feeRate common.Fixed64)
+	GetFeeFilter() common.Fixed64
+	AddRejectInfo(info RejectInfo)
+	GetRejectInfos() []RejectInfo
+	GetTrafficStats() TrafficStats
+	GetPingRTT() time.Duration
+	GetStartTime() time.Time
 
 	GetNeighborNoder() []Noder
 	GetNbrNodeCnt() uint32
@@ -0,0 +1,165 @@
+package spv
+
+import (
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/core/ledger"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOrphanHeader    = errors.New("the previous header is unknown")
+	ErrDuplicateHeader = errors.New("the header is already known")
+)
+
+// headerChain keeps all the validated headers in memory and selects the
+// chain with the most proof of work as the best chain.
+type headerChain struct {
+	sync.RWMutex
+	index     map[Uint256]*ledger.BlockNode
+	headers   map[Uint256]*ledger.Blockdata
+	mainChain []*ledger.BlockNode // The best chain indexed by height
+}
+
+func newHeaderChain(genesis *ledger.Blockdata) *headerChain {
+	hash := genesis.Hash()
+	node := ledger.NewBlockNode(genesis, &hash)
+	node.InMainChain = true
+	return &headerChain{
+		index:     map[Uint256]*ledger.BlockNode{hash: node},
+		headers:   map[Uint256]*ledger.Blockdata{hash: genesis},
+		mainChain: []*ledger.BlockNode{node},
+	}
+}
+
+func (hc *headerChain) best() *ledger.BlockNode {
+	return hc.mainChain[len(hc.mainChain)-1]
+}
+
+func (hc *headerChain) BestNode() *ledger.BlockNode {
+	hc.RLock()
+	defer hc.RUnlock()
+	return hc.best()
+}
+
+func (hc *headerChain) GetHeader(hash Uint256) (*ledger.Blockdata, bool) {
+	hc.RLock()
+	defer hc.RUnlock()
+	header, ok := hc.headers[hash]
+	return header, ok
+}
+
+// GetMainChainHash returns the hash of the best chain header at height
+func (hc *headerChain) GetMainChainHash(height uint32) (Uint256, bool) {
+	hc.RLock()
+	defer hc.RUnlock()
+	if int(height) >= len(hc.mainChain) {
+		return Uint256{}, false
+	}
+	return *hc.mainChain[height].Hash, true
+}
+
+// Locator returns the hashes of the best chain from the tip back to the
+// genesis, dense at the tip and exponentially sparse further back.
+func (hc *headerChain) Locator() []Uint256 {
+	hc.RLock()
+	defer hc.RUnlock()
+	var locator []Uint256
+	step := 1
+	for height := len(hc.mainChain) - 1; height > 0; height -= step {
+		locator = append(locator, *hc.mainChain[height].Hash)
+		if len(locator) >= 10 {
+			step *= 2
+		}
+	}
+	return append(locator, *hc.mainChain[0].Hash)
+}
+
+// checkHeader does the same header checks as PowCheckBlockSanity and
+// PowCheckBlockContext of a full node.
+func checkHeader(header *ledger.Blockdata, hash Uint256, parent *ledger.BlockNode) error {
+	isAuxPow, err := ledger.CheckAuxPow(header)
+	if err != nil {
+		return err
+	}
+	if err := ledger.CheckProofOfWork(header, config.Parameters.ChainParam.PowLimit, isAuxPow); err != nil {
+		return errors.New("header proof of work check failed")
+	}
+
+	timestamp := time.Unix(int64(header.Timestamp), 0)
+	if timestamp.After(time.Now().Add(time.Second * ledger.MaxTimeOffsetSeconds)) {
+		return errors.New("header timestamp is too far in the future")
+	}
+	if !timestamp.After(ledger.CalcPastMedianTime(parent)) {
+		return errors.New("header timestamp is not after the median time")
+	}
+
+	if header.Height != parent.Height+1 {
+		return fmt.Errorf("header height %d does not follow %d", header.Height, parent.Height)
+	}
+	bits, err := ledger.CalcNextRequiredDifficulty(parent, timestamp)
+	if err != nil {
+		return err
+	}
+	if header.Bits != bits {
+		return fmt.Errorf("header difficulty %08x is not the expected %08x", header.Bits, bits)
+	}
+	return nil
+}
+
+// AddHeader validates the header and connects it to the chain. It returns
+// whether the header becomes the best tip and how many headers of the old
+// best chain are disconnected by a reorganization.
+func (hc *headerChain) AddHeader(header *ledger.Blockdata) (bool, int, error) {
+	hash := header.Hash()
+
+	hc.Lock()
+	defer hc.Unlock()
+	if _, ok := hc.index[hash]; ok {
+		return false, 0, ErrDuplicateHeader
+	}
+	parent, ok := hc.index[header.PrevBlockHash]
+	if !ok {
+		return false, 0, ErrOrphanHeader
+	}
+	if err := checkHeader(header, hash, parent); err != nil {
+		return false, 0, err
+	}
+
+	node := ledger.NewBlockNode(header, &hash)
+	node.Parent = parent
+	node.WorkSum.Add(node.WorkSum, parent.WorkSum)
+	parent.Children = append(parent.Children, node)
+	hc.index[hash] = node
+	hc.headers[hash] = header
+
+	if node.WorkSum.Cmp(hc.best().WorkSum) <= 0 {
+		return false, 0, nil
+	}
+	return true, hc.setBest(node), nil
+}
+
+// setBest makes node the tip of the best chain and returns the number of
+// disconnected nodes. It should be called with the lock held.
+func (hc *headerChain) setBest(node *ledger.BlockNode) int {
+	var attach []*ledger.BlockNode
+	fork := node
+	for ; !fork.InMainChain; fork = fork.Parent {
+		attach = append(attach, fork)
+	}
+
+	detached := 0
+	for _, n := range hc.mainChain[fork.Height+1:] {
+		n.InMainChain = false
+		detached++
+	}
+	hc.mainChain = hc.mainChain[:fork.Height+1]
+	for i := len(attach) - 1; i >= 0; i-- {
+		attach[i].InMainChain = true
+		hc.mainChain = append(hc.mainChain, attach[i])
+	}
+	return detached
+}
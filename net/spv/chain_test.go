@@ -0,0 +1,241 @@
+package spv
+
+import (
+	"math"
+	"testing"
+
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/core/ledger"
+)
+
+const testBits = 0x207fffff
+
+// setTestParams selects the easiest difficulty without retargeting, so the
+// test headers are mined in a few tries, and returns the restore function.
+func setTestParams() func() {
+	saved := config.Parameters.ChainParam
+	params := *saved
+	params.PowLimitBits = testBits
+	params.PowNoRetargeting = true
+	params.DifficultyAlgorithm = ""
+	params.AuxPowMandatoryHeight = math.MaxUint32
+	config.Parameters.ChainParam = &params
+	return func() { config.Parameters.ChainParam = saved }
+}
+
+func testGenesis() *ledger.Blockdata {
+	return &ledger.Blockdata{Version: 1, Timestamp: 1500000000, Bits: testBits}
+}
+
+// mine searches the nonce giving the header a valid proof of work
+func mine(t *testing.T, header *ledger.Blockdata) *ledger.Blockdata {
+	for ; ; header.Nonce++ {
+		if ledger.CheckProofOfWork(header, config.Parameters.ChainParam.PowLimit, false) == nil {
+			return header
+		}
+		if header.Nonce == math.MaxUint32 {
+			t.Fatal("no nonce found")
+		}
+	}
+}
+
+// nextHeader returns a mined header following parent, tag tells the headers
+// of different branches apart.
+func nextHeader(t *testing.T, parent *ledger.Blockdata, tag byte) *ledger.Blockdata {
+	header := &ledger.Blockdata{
+		Version:       1,
+		PrevBlockHash: parent.Hash(),
+		Timestamp:     parent.Timestamp + 60,
+		Bits:          testBits,
+		Height:        parent.Height + 1,
+	}
+	header.TransactionsRoot[0] = tag
+	return mine(t, header)
+}
+
+func branch(t *testing.T, parent *ledger.Blockdata, tag byte, n int) []*ledger.Blockdata {
+	headers := make([]*ledger.Blockdata, n)
+	for i := range headers {
+		parent = nextHeader(t, parent, tag)
+		headers[i] = parent
+	}
+	return headers
+}
+
+func TestAddHeader(t *testing.T) {
+	defer setTestParams()()
+
+	genesis := testGenesis()
+	tip := nextHeader(t, genesis, 0)
+
+	tests := []struct {
+		name   string
+		header func() *ledger.Blockdata
+		valid  bool
+		err    error
+	}{
+		{"next", func() *ledger.Blockdata { return nextHeader(t, tip, 0) }, true, nil},
+		{"duplicate", func() *ledger.Blockdata { return tip }, false, ErrDuplicateHeader},
+		{"orphan", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			header.PrevBlockHash[0] ^= 1
+			return mine(t, header)
+		}, false, ErrOrphanHeader},
+		{"wrong height", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			header.Height++
+			return mine(t, header)
+		}, false, nil},
+		{"wrong bits", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			header.Bits = 0x1f7fffff
+			return mine(t, header)
+		}, false, nil},
+		{"before the median time", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			header.Timestamp = genesis.Timestamp
+			return mine(t, header)
+		}, false, nil},
+		{"in the future", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			header.Timestamp = uint32(math.MaxUint32)
+			return mine(t, header)
+		}, false, nil},
+		{"no proof of work", func() *ledger.Blockdata {
+			header := nextHeader(t, tip, 0)
+			for ledger.CheckProofOfWork(header, config.Parameters.ChainParam.PowLimit, false) == nil {
+				header.Nonce++
+			}
+			return header
+		}, false, nil},
+	}
+	for _, test := range tests {
+		hc := newHeaderChain(genesis)
+		if _, _, err := hc.AddHeader(tip); err != nil {
+			t.Fatal(err)
+		}
+		best, _, err := hc.AddHeader(test.header())
+		switch {
+		case test.valid && (err != nil || !best):
+			t.Errorf("%s: got best %v, error %v, want the new tip", test.name, best, err)
+		case !test.valid && err == nil:
+			t.Errorf("%s: the header is connected", test.name)
+		case test.err != nil && err != test.err:
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+		if !test.valid && hc.BestNode().Height != tip.Height {
+			t.Errorf("%s: best height %d changed", test.name, hc.BestNode().Height)
+		}
+	}
+}
+
+func TestReorganize(t *testing.T) {
+	defer setTestParams()()
+
+	genesis := testGenesis()
+	a := branch(t, genesis, 'a', 2)
+	b := branch(t, genesis, 'b', 3)
+	c := branch(t, a[0], 'c', 3)
+
+	hc := newHeaderChain(genesis)
+	tests := []struct {
+		name     string
+		header   *ledger.Blockdata
+		best     bool
+		detached int
+		tip      *ledger.Blockdata
+	}{
+		{"a1", a[0], true, 0, a[0]},
+		{"a2", a[1], true, 0, a[1]},
+		{"b1 is behind", b[0], false, 0, a[1]},
+		{"b2 has the same work", b[1], false, 0, a[1]},
+		{"b3 reorganizes", b[2], true, 2, b[2]},
+		{"c1 is behind", c[0], false, 0, b[2]},
+		{"c2 has the same work", c[1], false, 0, b[2]},
+		{"c3 reorganizes back past a1", c[2], true, 3, c[2]},
+	}
+	for _, test := range tests {
+		best, detached, err := hc.AddHeader(test.header)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if best != test.best || detached != test.detached {
+			t.Errorf("%s: got best %v detached %d, want %v %d", test.name, best, detached, test.best, test.detached)
+		}
+		if hash := test.tip.Hash(); *hc.BestNode().Hash != hash {
+			t.Errorf("%s: got tip height %d, want %d", test.name, hc.BestNode().Height, test.tip.Height)
+		}
+	}
+
+	// The main chain is genesis, a1 and the c branch
+	want := append([]*ledger.Blockdata{genesis, a[0]}, c...)
+	for height, header := range want {
+		hash, ok := hc.GetMainChainHash(uint32(height))
+		if !ok || hash != header.Hash() {
+			t.Errorf("height %d is not on the main chain", height)
+		}
+		if node := hc.index[header.Hash()]; !node.InMainChain {
+			t.Errorf("height %d is not marked in the main chain", height)
+		}
+	}
+	if _, ok := hc.GetMainChainHash(uint32(len(want))); ok {
+		t.Error("got a main chain hash above the tip")
+	}
+	for _, header := range append(a[1:], b...) {
+		if hc.index[header.Hash()].InMainChain {
+			t.Errorf("detached height %d is still marked in the main chain", header.Height)
+		}
+	}
+}
+
+func TestLocator(t *testing.T) {
+	tests := []struct {
+		length  int
+		heights []uint32
+	}{
+		{1, []uint32{0}},
+		{5, []uint32{4, 3, 2, 1, 0}},
+		{11, []uint32{10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0}},
+		{12, []uint32{11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 0}},
+		{20, []uint32{19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 8, 4, 0}},
+		{100, []uint32{99, 98, 97, 96, 95, 94, 93, 92, 91, 90, 88, 84, 76, 60, 28, 0}},
+	}
+	for _, test := range tests {
+		hc := newHeaderChain(testGenesis())
+		for height := 1; height < test.length; height++ {
+			header := &ledger.Blockdata{Height: uint32(height)}
+			hash := header.Hash()
+			hc.mainChain = append(hc.mainChain, ledger.NewBlockNode(header, &hash))
+		}
+		locator := hc.Locator()
+		if len(locator) != len(test.heights) {
+			t.Errorf("length %d: got %d hashes, want %d", test.length, len(locator), len(test.heights))
+			continue
+		}
+		for i, height := range test.heights {
+			if want, _ := hc.GetMainChainHash(height); locator[i] != want {
+				t.Errorf("length %d: hash %d is not the one of height %d", test.length, i, height)
+			}
+		}
+	}
+}
+
+func TestGetHeader(t *testing.T) {
+	defer setTestParams()()
+
+	genesis := testGenesis()
+	hc := newHeaderChain(genesis)
+	header := nextHeader(t, genesis, 0)
+	if _, _, err := hc.AddHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		hash Uint256
+		ok   bool
+	}{{genesis.Hash(), true}, {header.Hash(), true}, {Uint256{}, false}} {
+		if _, ok := hc.GetHeader(test.hash); ok != test.ok {
+			t.Errorf("header %x: got %v, want %v", test.hash, ok, test.ok)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package spv
+
+import (
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/log"
+	"DNA_POW/core/ledger"
+	tx "DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+	"DNA_POW/net/node"
+	. "DNA_POW/net/protocol"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	HEADERSYNCINTERVAL = 30 // Seconds, interval to ask the peer for new headers
+	PEERRETRYINTERVAL  = 5  // Seconds, wait before connecting to the next peer
+	HANDSHAKETIMEOUT   = 10 // Seconds
+)
+
+// Client is a light client which only syncs and validates the block headers
+// from full nodes. Transactions of the watched addresses are verified by
+// their Merkle proofs against the synced headers.
+type Client struct {
+	chain  *headerChain
+	watch  watchList
+	peers  []string
+	nonce  uint64
+	pubKey *crypto.PubKey
+
+	mutex  sync.Mutex
+	synced bool
+	conn   net.Conn
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewClient creates a light client connecting to the full nodes in peers,
+// each in the "host:port" format.
+func NewClient(peers []string) (*Client, error) {
+	if len(peers) == 0 {
+		return nil, errors.New("no peer to connect")
+	}
+	crypto.SetAlg(config.Parameters.EncryptAlg)
+	genesisBlock, err := ledger.GenesisBlockInit()
+	if err != nil {
+		return nil, err
+	}
+	genesisBlock.RebuildMerkleRoot()
+
+	_, pubKey, err := crypto.GenKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	var nonce [8]byte
+	if _, err := crand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		chain:  newHeaderChain(genesisBlock.Blockdata),
+		peers:  peers,
+		nonce:  binary.LittleEndian.Uint64(nonce[:]),
+		pubKey: &pubKey,
+	}, nil
+}
+
+func (c *Client) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.quit != nil {
+		return
+	}
+	c.quit = make(chan struct{})
+	c.wg.Add(1)
+	go c.run(c.quit)
+}
+
+func (c *Client) Stop() {
+	c.mutex.Lock()
+	if c.quit == nil {
+		c.mutex.Unlock()
+		return
+	}
+	close(c.quit)
+	c.quit = nil
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.mutex.Unlock()
+	c.wg.Wait()
+}
+
+// IsSynced reports whether the connected peer has no more headers to send
+func (c *Client) IsSynced() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.synced
+}
+
+func (c *Client) setSynced(synced bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.synced = synced
+}
+
+func (c *Client) BestHeight() uint32 {
+	return c.chain.BestNode().Height
+}
+
+func (c *Client) BestHash() Uint256 {
+	return *c.chain.BestNode().Hash
+}
+
+func (c *Client) GetHeader(hash Uint256) (*ledger.Header, error) {
+	header, ok := c.chain.GetHeader(hash)
+	if !ok {
+		return nil, errors.New("unknown header")
+	}
+	return &ledger.Header{Blockdata: header}, nil
+}
+
+func (c *Client) GetHeaderByHeight(height uint32) (*ledger.Header, error) {
+	hash, ok := c.chain.GetMainChainHash(height)
+	if !ok {
+		return nil, errors.New("height is above the best header")
+	}
+	return c.GetHeader(hash)
+}
+
+// Watch adds the address to the watched addresses
+func (c *Client) Watch(address string) error {
+	programHash, err := ToScriptHash(address)
+	if err != nil {
+		return err
+	}
+	c.watch.add(programHash)
+	return nil
+}
+
+func (c *Client) Unwatch(address string) error {
+	programHash, err := ToScriptHash(address)
+	if err != nil {
+		return err
+	}
+	c.watch.remove(programHash)
+	return nil
+}
+
+func (c *Client) WatchedAddresses() []string {
+	var addrs []string
+	for _, programHash := range c.watch.list() {
+		addr, err := programHash.ToAddress()
+		if err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// VerifyTransaction checks the transaction involves a watched address and
+// is included in the best chain, it returns the number of confirmations.
+func (c *Client) VerifyTransaction(txn *tx.Transaction, proof *MerkleProof) (uint32, error) {
+	if !c.watch.isWatched(txn) {
+		return 0, errors.New("the transaction does not involve a watched address")
+	}
+	if err := c.verifyMerkleProof(txn.Hash(), proof); err != nil {
+		return 0, err
+	}
+	return c.BestHeight() - proof.Height + 1, nil
+}
+
+// run connects to the peers in turn until the client is stopped
+func (c *Client) run(quit chan struct{}) {
+	defer c.wg.Done()
+	for i := 0; ; i = (i + 1) % len(c.peers) {
+		addr := c.peers[i]
+		err := c.session(addr, quit)
+		c.setSynced(false)
+		select {
+		case <-quit:
+			return
+		default:
+		}
+		log.Warn("SPV peer ", addr, " disconnected: ", err)
+
+		select {
+		case <-quit:
+			return
+		case <-time.After(time.Second * PEERRETRYINTERVAL):
+		}
+	}
+}
+
+func dial(addr string) (net.Conn, error) {
+	if config.Parameters.IsTLS {
+		return node.TLSDial(addr)
+	}
+	return node.NonTLSDial(addr)
+}
+
+type inMsg struct {
+	cmd     string
+	payload []byte
+	err     error
+}
+
+// session handshakes with the peer and syncs the headers from it until an
+// error happens.
+func (c *Client) session(addr string, quit chan struct{}) error {
+	conn, err := dial(addr)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.conn = nil
+		c.mutex.Unlock()
+		conn.Close()
+	}()
+
+	msgs := make(chan inMsg)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			cmd, payload, err := readMsg(conn)
+			select {
+			case msgs <- inMsg{cmd, payload, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(buf []byte, err error) error {
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(buf)
+		return err
+	}
+	getHeaders := func() error {
+		return send(newGetHeaders(c.chain.Locator(), Uint256{}))
+	}
+
+	if err := send(newVersion(c.nonce, c.pubKey)); err != nil {
+		return err
+	}
+	established := false
+	handshakeTimer := time.NewTimer(time.Second * HANDSHAKETIMEOUT)
+	defer handshakeTimer.Stop()
+	ticker := time.NewTicker(time.Second * HEADERSYNCINTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return nil
+		case <-handshakeTimer.C:
+			if !established {
+				return errors.New("handshake timeout")
+			}
+		case <-ticker.C:
+			if established {
+				if err := getHeaders(); err != nil {
+					return err
+				}
+			}
+		case m := <-msgs:
+			if m.err != nil {
+				return m.err
+			}
+			switch m.cmd {
+			case "version":
+				v, err := parseVersion(m.payload)
+				if err != nil {
+					return err
+				}
+				log.Info("SPV peer ", addr, " version ", v.Version, " height ", v.StartHeight)
+				if err := send(newVerack()); err != nil {
+					return err
+				}
+			case "verack":
+				established = true
+				if err := getHeaders(); err != nil {
+					return err
+				}
+			case "headers":
+				headers, err := parseHeaders(m.payload)
+				if err != nil {
+					return err
+				}
+				if err := c.addHeaders(headers); err != nil {
+					return err
+				}
+				if len(headers) < MAXBLKHDRCNT {
+					c.setSynced(true)
+				} else if err := getHeaders(); err != nil {
+					return err
+				}
+			case "ping":
+				if err := send(newPong()); err != nil {
+					return err
+				}
+			case "inv":
+				if established && isBlockInv(m.payload) {
+					if err := getHeaders(); err != nil {
+						return err
+					}
+				}
+			case "block":
+				if established {
+					if err := getHeaders(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+}
+
+// addHeaders connects the headers to the chain, an invalid header means the
+// peer is misbehaving.
+func (c *Client) addHeaders(headers []*ledger.Blockdata) error {
+	for _, header := range headers {
+		isBest, detached, err := c.chain.AddHeader(header)
+		if err == ErrDuplicateHeader {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if detached > 0 {
+			log.Infof("SPV reorganize: %d headers disconnected, new best height %d", detached, header.Height)
+		}
+		if isBest {
+			log.Tracef("SPV best header height %d", header.Height)
+		}
+	}
+	return nil
+}
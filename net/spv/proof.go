@@ -0,0 +1,86 @@
+package spv
+
+import (
+	. "DNA_POW/common"
+	tx "DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+	"errors"
+	"sync"
+)
+
+// MerkleProof proves a transaction is included in a block, full nodes
+// return it by the getmerkleproof RPC.
+type MerkleProof struct {
+	BlockHash Uint256
+	Height    uint32
+	Index     uint32    // The position of the transaction in the block
+	Branch    []Uint256 // The sibling hashes from the transaction up to the root
+}
+
+// watchList holds the program hashes of the addresses the client cares about
+type watchList struct {
+	sync.RWMutex
+	addrs map[Uint160]struct{}
+}
+
+func (wl *watchList) add(programHash Uint160) {
+	wl.Lock()
+	defer wl.Unlock()
+	if wl.addrs == nil {
+		wl.addrs = make(map[Uint160]struct{})
+	}
+	wl.addrs[programHash] = struct{}{}
+}
+
+func (wl *watchList) remove(programHash Uint160) {
+	wl.Lock()
+	defer wl.Unlock()
+	delete(wl.addrs, programHash)
+}
+
+func (wl *watchList) contains(programHash Uint160) bool {
+	wl.RLock()
+	defer wl.RUnlock()
+	_, ok := wl.addrs[programHash]
+	return ok
+}
+
+func (wl *watchList) list() []Uint160 {
+	wl.RLock()
+	defer wl.RUnlock()
+	hashes := make([]Uint160, 0, len(wl.addrs))
+	for hash := range wl.addrs {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// isWatched reports whether the transaction pays to or is signed by one of
+// the watched addresses.
+func (wl *watchList) isWatched(txn *tx.Transaction) bool {
+	for _, output := range txn.Outputs {
+		if wl.contains(output.ProgramHash) {
+			return true
+		}
+	}
+	for _, program := range txn.Programs {
+		hash, err := ToCodeHash(program.Code)
+		if err == nil && wl.contains(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyMerkleProof checks the proof against the header in the best chain
+func (c *Client) verifyMerkleProof(txHash Uint256, proof *MerkleProof) error {
+	hash, ok := c.chain.GetMainChainHash(proof.Height)
+	if !ok || hash != proof.BlockHash {
+		return errors.New("the block of the proof is not in the best chain")
+	}
+	header, _ := c.chain.GetHeader(hash)
+	if !crypto.VerifyMerkleBranch(txHash, proof.Index, proof.Branch, header.TransactionsRoot) {
+		return errors.New("merkle proof does not match the block transactions root")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package spv
+
+import (
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/common/serialization"
+	"DNA_POW/core/ledger"
+	"DNA_POW/crypto"
+	. "DNA_POW/net/protocol"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The light client only speaks the part of the protocol needed to sync the
+// headers, the messages are framed the same way as net/message does.
+
+// Max payload accepted from a peer, a full headers message is well below it
+const MAXSPVPAYLOAD = 8 * 1024 * 1024
+
+type msgHeader struct {
+	Magic    uint32
+	CMD      [MSGCMDLEN]byte
+	Length   uint32
+	Checksum [CHECKSUMLEN]byte
+}
+
+// versionPayload has the same layout as the version message of a full node
+type versionPayload struct {
+	Version      uint32
+	Services     uint64
+	TimeStamp    uint32
+	Port         uint16
+	HttpInfoPort uint16
+	Cap          [32]byte
+	Nonce        uint64
+	UserAgent    uint8
+	StartHeight  uint64
+	Relay        uint8
+}
+
+func checksum(payload []byte) [CHECKSUMLEN]byte {
+	var sum [CHECKSUMLEN]byte
+	s := sha256.Sum256(payload)
+	s = sha256.Sum256(s[:])
+	copy(sum[:], s[:CHECKSUMLEN])
+	return sum
+}
+
+func buildMsg(cmd string, payload []byte) ([]byte, error) {
+	hdr := msgHeader{
+		Magic:    config.Parameters.Magic,
+		Length:   uint32(len(payload)),
+		Checksum: checksum(payload),
+	}
+	copy(hdr.CMD[:], cmd)
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, hdr); err != nil {
+		return nil, err
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+func readMsg(r io.Reader) (string, []byte, error) {
+	var hdr msgHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return "", nil, err
+	}
+	if hdr.Magic != config.Parameters.Magic {
+		return "", nil, fmt.Errorf("unmatched magic number 0x%x", hdr.Magic)
+	}
+	if hdr.Length > MAXSPVPAYLOAD {
+		return "", nil, fmt.Errorf("message payload too large: %d", hdr.Length)
+	}
+	payload := make([]byte, hdr.Length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", nil, err
+	}
+	if checksum(payload) != hdr.Checksum {
+		return "", nil, errors.New("message checksum error")
+	}
+	n := bytes.IndexByte(hdr.CMD[:], 0)
+	if n < 0 {
+		n = MSGCMDLEN
+	}
+	return string(hdr.CMD[:n]), payload, nil
+}
+
+// newVersion announces the light client with height 0 and relay off, so
+// full nodes neither sync blocks from it nor relay transactions to it.
+func newVersion(nonce uint64, pk *crypto.PubKey) ([]byte, error) {
+	p := versionPayload{
+		Version:   PROTOCOLVERSION,
+		TimeStamp: uint32(time.Now().UTC().UnixNano()),
+		Nonce:     nonce,
+	}
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, p); err != nil {
+		return nil, err
+	}
+	if err := pk.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return buildMsg("version", buf.Bytes())
+}
+
+func parseVersion(payload []byte) (*versionPayload, error) {
+	p := new(versionPayload)
+	err := binary.Read(bytes.NewReader(payload), binary.LittleEndian, p)
+	return p, err
+}
+
+func newGetHeaders(locator []Uint256, stopHash Uint256) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	serialization.WriteUint32(buf, uint32(len(locator)))
+	for _, hash := range locator {
+		if _, err := hash.Serialize(buf); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := stopHash.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return buildMsg("getheaders", buf.Bytes())
+}
+
+func parseHeaders(payload []byte) ([]*ledger.Blockdata, error) {
+	buf := bytes.NewReader(payload)
+	cnt, err := serialization.ReadUint32(buf)
+	if err != nil {
+		return nil, err
+	}
+	if cnt > MAXBLKHDRCNT {
+		return nil, fmt.Errorf("too many headers: %d", cnt)
+	}
+	headers := make([]*ledger.Blockdata, 0, cnt)
+	for i := uint32(0); i < cnt; i++ {
+		var header ledger.Header
+		if err := header.Deserialize(buf); err != nil {
+			return nil, err
+		}
+		headers = append(headers, header.Blockdata)
+	}
+	return headers, nil
+}
+
+func newVerack() ([]byte, error) {
+	return buildMsg("verack", nil)
+}
+
+func newPong() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	serialization.WriteUint64(buf, 0)
+	return buildMsg("pong", buf.Bytes())
+}
+
+// isBlockInv reports whether the inv message announces blocks
+func isBlockInv(payload []byte) bool {
+	return len(payload) > 0 && InventoryType(payload[0]) == BLOCK
+}
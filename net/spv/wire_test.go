@@ -0,0 +1,130 @@
+package spv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"DNA_POW/common/serialization"
+	"DNA_POW/core/ledger"
+	. "DNA_POW/net/protocol"
+)
+
+const (
+	lengthOffset   = 4 + MSGCMDLEN
+	checksumOffset = lengthOffset + 4
+)
+
+func TestReadMsg(t *testing.T) {
+	payload := []byte("payload")
+	tests := []struct {
+		name   string
+		modify func(msg []byte) []byte
+		valid  bool
+	}{
+		{"valid", func(msg []byte) []byte { return msg }, true},
+		{"bad magic", func(msg []byte) []byte {
+			msg[0] ^= 1
+			return msg
+		}, false},
+		{"too large", func(msg []byte) []byte {
+			binary.LittleEndian.PutUint32(msg[lengthOffset:], MAXSPVPAYLOAD+1)
+			return msg
+		}, false},
+		{"bad checksum", func(msg []byte) []byte {
+			msg[checksumOffset] ^= 1
+			return msg
+		}, false},
+		{"short header", func(msg []byte) []byte { return msg[:checksumOffset] }, false},
+		{"short payload", func(msg []byte) []byte { return msg[:len(msg)-1] }, false},
+		{"empty", func(msg []byte) []byte { return nil }, false},
+	}
+	for _, test := range tests {
+		msg, err := buildMsg("headers", payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, p, err := readMsg(bytes.NewReader(test.modify(msg)))
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: the message is read", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if cmd != "headers" || !bytes.Equal(p, payload) {
+			t.Errorf("%s: got %q %q, want %q %q", test.name, cmd, p, "headers", payload)
+		}
+	}
+}
+
+func headersPayload(cnt uint32, headers ...*ledger.Blockdata) []byte {
+	buf := new(bytes.Buffer)
+	serialization.WriteUint32(buf, cnt)
+	for _, header := range headers {
+		(&ledger.Header{Blockdata: header}).Serialize(buf)
+	}
+	return buf.Bytes()
+}
+
+func TestParseHeaders(t *testing.T) {
+	header := &ledger.Blockdata{Version: 1, Height: 1, Bits: testBits}
+	full := headersPayload(2, header, header)
+	tests := []struct {
+		name    string
+		payload []byte
+		cnt     int
+	}{
+		{"two headers", full, 2},
+		{"no header", headersPayload(0), 0},
+		{"empty", nil, -1},
+		{"short count", full[:3], -1},
+		{"count above the headers", headersPayload(3, header, header), -1},
+		{"too many headers", headersPayload(MAXBLKHDRCNT + 1), -1},
+		{"truncated header", full[:len(full)-1], -1},
+	}
+	for _, test := range tests {
+		headers, err := parseHeaders(test.payload)
+		if test.cnt < 0 {
+			if err == nil {
+				t.Errorf("%s: the headers are parsed", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(headers) != test.cnt {
+			t.Errorf("%s: got %d headers, want %d", test.name, len(headers), test.cnt)
+		}
+		for _, h := range headers {
+			if h.Hash() != header.Hash() {
+				t.Errorf("%s: got header %x, want %x", test.name, h.Hash(), header.Hash())
+			}
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	p := versionPayload{Version: PROTOCOLVERSION, Nonce: 42}
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, p)
+	for _, test := range []struct {
+		name    string
+		payload []byte
+		valid   bool
+	}{
+		{"valid", buf.Bytes(), true},
+		{"short", buf.Bytes()[:buf.Len()-1], false},
+		{"empty", nil, false},
+	} {
+		v, err := parseVersion(test.payload)
+		if test.valid != (err == nil) {
+			t.Errorf("%s: got error %v", test.name, err)
+		} else if test.valid && *v != p {
+			t.Errorf("%s: got %+v, want %+v", test.name, *v, p)
+		}
+	}
+}
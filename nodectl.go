@@ -6,12 +6,15 @@ import (
 
 	_ "DNA_POW/cli"
 	"DNA_POW/cli/asset"
+	"DNA_POW/cli/consolidate"
 	"DNA_POW/cli/debug"
 	"DNA_POW/cli/dnatst"
 	"DNA_POW/cli/info"
 	"DNA_POW/cli/mining"
 	"DNA_POW/cli/multisig"
+	"DNA_POW/cli/partial"
 	"DNA_POW/cli/recover"
+	"DNA_POW/cli/rescan"
 	"DNA_POW/cli/wallet"
 
 	"github.com/urfave/cli"
@@ -34,10 +37,13 @@ func main() {
 		*info.NewCommand(),
 		*wallet.NewCommand(),
 		*asset.NewCommand(),
+		*consolidate.NewCommand(),
 		*recover.NewCommand(),
+		*rescan.NewCommand(),
 		*mining.NewCommand(),
 		*dnatst.NewCommand(),
 		*multisig.NewCommand(),
+		*partial.NewCommand(),
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 	sort.Sort(cli.FlagsByName(app.Flags))
@@ -0,0 +1,248 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/transaction"
+)
+
+// CoinSelection is the strategy to choose the coins spent by a transaction
+type CoinSelection byte
+
+const (
+	// SmallestFirst spends the minor coins first
+	SmallestFirst CoinSelection = iota
+	// LargestFirst spends the fewest coins
+	LargestFirst
+	// ExactMatch searches the coins adding up to the spent value, which leaves
+	// no changes, with branch and bound. It spends the minor coins first when
+	// no match is found.
+	ExactMatch
+	// OldestFirst spends the coins received earliest first
+	OldestFirst
+)
+
+// The names of the coin selection strategies
+var coinSelectionNames = map[string]CoinSelection{
+	"smallest": SmallestFirst,
+	"largest":  LargestFirst,
+	"exact":    ExactMatch,
+	"oldest":   OldestFirst,
+}
+
+// the maximum number of branches the exact match search tries
+const maxExactMatchTries = 100000
+
+func ParseCoinSelection(name string) (CoinSelection, error) {
+	if name == "" {
+		return SmallestFirst, nil
+	}
+	selection, ok := coinSelectionNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown coin selection %q", name)
+	}
+	return selection, nil
+}
+
+// CoinControl tells which coins a transaction spends. A nil CoinControl spends
+// the minor coins first.
+type CoinControl struct {
+	Selection CoinSelection
+	Pinned    []*transaction.UTXOTxInput // Spent before any other coin
+	Locked    []*transaction.UTXOTxInput // Never spent
+}
+
+// ParseOutpoint parses the outpoint of a coin in the format txid:index, the
+// transaction id in the reversed hex as displayed.
+func ParseOutpoint(s string) (*transaction.UTXOTxInput, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return nil, errors.New("outpoint is not in the format txid:index")
+	}
+	tmp, err := HexStringToBytesReverse(s[:i])
+	if err != nil {
+		return nil, errors.New("invalid outpoint transaction id")
+	}
+	txid, err := Uint256ParseFromBytes(tmp)
+	if err != nil {
+		return nil, errors.New("invalid outpoint transaction id")
+	}
+	index, err := strconv.ParseUint(s[i+1:], 10, 16)
+	if err != nil {
+		return nil, errors.New("invalid outpoint index")
+	}
+	return &transaction.UTXOTxInput{
+		ReferTxID:          txid,
+		ReferTxOutputIndex: uint16(index),
+	}, nil
+}
+
+// FormatOutpoint returns the outpoint of the coin in the format parsed by
+// ParseOutpoint.
+func FormatOutpoint(input *transaction.UTXOTxInput) string {
+	return fmt.Sprintf("%s:%d", BytesToHexString(input.ReferTxID.ToArrayReverse()), input.ReferTxOutputIndex)
+}
+
+func containsOutpoint(list []*transaction.UTXOTxInput, input *transaction.UTXOTxInput) bool {
+	for _, in := range list {
+		if in.Equals(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// availableCoins returns the confirmed coins of the asset and address type,
+// only those of the program hash unless it is nil.
+func availableCoins(coins map[*transaction.UTXOTxInput]*account.Coin, addrtype account.AddressType,
+	assetID Uint256, programHash *Uint160) sortedCoins {
+	var coinList sortedCoins
+	for in, c := range coins {
+		if c.Height > ledger.DefaultLedger.Blockchain.GetBestHeight() {
+			continue
+		}
+		if c.AddressType != addrtype || c.Output.AssetID != assetID {
+			continue
+		}
+		if programHash != nil && c.Output.ProgramHash != *programHash {
+			continue
+		}
+		coinList = append(coinList, &sortedCoinsItem{
+			input: in,
+			coin:  c,
+		})
+	}
+	return coinList
+}
+
+// selectCoins chooses the coins adding up to at least the expected value
+func (control *CoinControl) selectCoins(coins sortedCoins, expected Fixed64) (sortedCoins, error) {
+	if control == nil {
+		control = &CoinControl{}
+	}
+	var selected, candidates sortedCoins
+	var total Fixed64
+	for _, pinned := range control.Pinned {
+		if containsOutpoint(control.Locked, pinned) {
+			return nil, fmt.Errorf("coin %s is both pinned and locked", FormatOutpoint(pinned))
+		}
+		found := false
+		for _, item := range coins {
+			if item.input.Equals(pinned) {
+				selected = append(selected, item)
+				total += item.coin.Output.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("pinned coin %s is not available", FormatOutpoint(pinned))
+		}
+	}
+	for _, item := range coins {
+		if !containsOutpoint(control.Pinned, item.input) && !containsOutpoint(control.Locked, item.input) {
+			candidates = append(candidates, item)
+		}
+	}
+	if total >= expected {
+		return selected, nil
+	}
+
+	if control.Selection == ExactMatch {
+		if match := exactMatch(candidates, expected-total); match != nil {
+			return append(selected, match...), nil
+		}
+	}
+	switch control.Selection {
+	case LargestFirst:
+		sort.Sort(sort.Reverse(candidates))
+	case OldestFirst:
+		sort.Stable(byHeight(candidates))
+	default:
+		sort.Sort(candidates)
+	}
+	for _, item := range candidates {
+		selected = append(selected, item)
+		total += item.coin.Output.Value
+		if total >= expected {
+			return selected, nil
+		}
+	}
+	return nil, errors.New("available token is not enough")
+}
+
+// exactMatch searches the coins adding up to the target by branch and bound,
+// the larger coins tried first. It returns nil if none is found.
+func exactMatch(coins sortedCoins, target Fixed64) sortedCoins {
+	sorted := make(sortedCoins, len(coins))
+	copy(sorted, coins)
+	sort.Sort(sort.Reverse(sorted))
+	// remaining[i] is the sum of the coins from i on
+	remaining := make([]Fixed64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].coin.Output.Value
+	}
+
+	tries := 0
+	picked := make([]bool, len(sorted))
+	var search func(i int, sum Fixed64) bool
+	search = func(i int, sum Fixed64) bool {
+		if sum == target {
+			return true
+		}
+		tries++
+		if i == len(sorted) || sum > target || sum+remaining[i] < target || tries > maxExactMatchTries {
+			return false
+		}
+		picked[i] = true
+		if search(i+1, sum+sorted[i].coin.Output.Value) {
+			return true
+		}
+		picked[i] = false
+		return search(i+1, sum)
+	}
+	if !search(0, 0) {
+		return nil
+	}
+	var match sortedCoins
+	for i, item := range sorted {
+		if picked[i] {
+			match = append(match, item)
+		}
+	}
+	return match
+}
+
+// byHeight sorts the coins by the height they are received at
+type byHeight sortedCoins
+
+func (sc byHeight) Len() int           { return len(sc) }
+func (sc byHeight) Swap(i, j int)      { sc[i], sc[j] = sc[j], sc[i] }
+func (sc byHeight) Less(i, j int) bool { return sc[i].coin.ReceivedHeight < sc[j].coin.ReceivedHeight }
+
+// spendCoins chooses the coins of the asset for the expected value and returns
+// the inputs, the outputs they spend and the changes left.
+func spendCoins(wallet account.Client, control *CoinControl, addrtype account.AddressType, assetID Uint256,
+	programHash *Uint160, expected Fixed64) ([]*transaction.UTXOTxInput, []*transaction.TxOutput, Fixed64, error) {
+	coins := availableCoins(wallet.GetCoins(), addrtype, assetID, programHash)
+	selected, err := control.selectCoins(coins, expected)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	input := []*transaction.UTXOTxInput{}
+	references := []*transaction.TxOutput{}
+	var total Fixed64
+	for _, item := range selected {
+		input = append(input, item.input)
+		references = append(references, item.coin.Output)
+		total += item.coin.Output.Value
+	}
+	return input, references, total - expected, nil
+}
@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"testing"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/core/transaction"
+)
+
+func testCoins(values ...Fixed64) sortedCoins {
+	var coins sortedCoins
+	for i, value := range values {
+		coins = append(coins, &sortedCoinsItem{
+			input: &transaction.UTXOTxInput{ReferTxOutputIndex: uint16(i)},
+			coin: &account.Coin{
+				Output: &transaction.TxOutput{Value: value},
+				// the later coins are the older ones
+				ReceivedHeight: uint32(len(values) - i),
+			},
+		})
+	}
+	return coins
+}
+
+func selectedIndexes(selected sortedCoins) []uint16 {
+	var indexes []uint16
+	for _, item := range selected {
+		indexes = append(indexes, item.input.ReferTxOutputIndex)
+	}
+	return indexes
+}
+
+func TestSelectCoins(t *testing.T) {
+	coins := testCoins(5, 20, 3, 12, 8)
+	tests := []struct {
+		control  *CoinControl
+		expected Fixed64
+		indexes  []uint16
+	}{
+		{nil, 10, []uint16{2, 0, 4}},
+		{&CoinControl{Selection: LargestFirst}, 10, []uint16{1}},
+		{&CoinControl{Selection: ExactMatch}, 25, []uint16{1, 0}},
+		{&CoinControl{Selection: ExactMatch}, 15, []uint16{3, 2}},
+		// no exact match spends the minor coins first
+		{&CoinControl{Selection: ExactMatch}, 47, []uint16{2, 0, 4, 3, 1}},
+		{&CoinControl{Selection: OldestFirst}, 10, []uint16{4, 3}},
+		{&CoinControl{Pinned: []*transaction.UTXOTxInput{{ReferTxOutputIndex: 3}}}, 14, []uint16{3, 2}},
+		{&CoinControl{Locked: []*transaction.UTXOTxInput{{ReferTxOutputIndex: 2}}}, 10, []uint16{0, 4}},
+	}
+	for i, test := range tests {
+		selected, err := test.control.selectCoins(coins, test.expected)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		indexes := selectedIndexes(selected)
+		if len(indexes) != len(test.indexes) {
+			t.Errorf("test %d: selected %v, expected %v", i, indexes, test.indexes)
+			continue
+		}
+		for j := range indexes {
+			if indexes[j] != test.indexes[j] {
+				t.Errorf("test %d: selected %v, expected %v", i, indexes, test.indexes)
+				break
+			}
+		}
+	}
+
+	if _, err := (&CoinControl{}).selectCoins(coins, 49); err == nil {
+		t.Error("spent more than the coins")
+	}
+	locked := &CoinControl{Locked: []*transaction.UTXOTxInput{{ReferTxOutputIndex: 1}}}
+	if _, err := locked.selectCoins(coins, 30); err == nil {
+		t.Error("spent a locked coin")
+	}
+	missing := &CoinControl{Pinned: []*transaction.UTXOTxInput{{ReferTxOutputIndex: 9}}}
+	if _, err := missing.selectCoins(coins, 1); err == nil {
+		t.Error("pinned a coin not available")
+	}
+}
+
+func TestParseOutpoint(t *testing.T) {
+	input := &transaction.UTXOTxInput{ReferTxID: Uint256{1, 2, 3}, ReferTxOutputIndex: 7}
+	parsed, err := ParseOutpoint(FormatOutpoint(input))
+	if err != nil || !parsed.Equals(input) {
+		t.Fatalf("outpoint %s is not parsed: %v", FormatOutpoint(input), err)
+	}
+	for _, s := range []string{"", "1234", "zz:1", FormatOutpoint(input) + "x"} {
+		if _, err := ParseOutpoint(s); err == nil {
+			t.Errorf("invalid outpoint %q parsed", s)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/common/config"
+	"DNA_POW/core/contract"
+	"DNA_POW/core/transaction"
+)
+
+// the size of a serialized UTXO input
+const utxoInputSize = 38
+
+// requiredFee returns the fee the transaction pool requires of a transaction
+// of the size.
+func requiredFee(size int) Fixed64 {
+	fee := Fixed64(config.Parameters.PowConfiguration.MinTxFee)
+	if byRate := Fixed64(config.Parameters.PowConfiguration.MinTxFeePerKB) * Fixed64(size) / 1000; byRate > fee {
+		fee = byRate
+	}
+	return fee
+}
+
+// makeConsolidate merges the coins to the address paying the fee, and returns
+// the signed transaction with the fee its size requires.
+func makeConsolidate(wallet account.Client, coins sortedCoins, assetID Uint256, address Uint160, fee Fixed64) (*transaction.Transaction, Fixed64, error) {
+	var total Fixed64
+	input := []*transaction.UTXOTxInput{}
+	for _, item := range coins {
+		input = append(input, item.input)
+		total += item.coin.Output.Value
+	}
+	if total <= fee {
+		return nil, 0, errors.New("the coins are not worth the fee to consolidate")
+	}
+	output := []*transaction.TxOutput{{
+		AssetID:     assetID,
+		Value:       total - fee,
+		ProgramHash: address,
+	}}
+	txn, err := transaction.NewTransferAssetTransaction(input, output)
+	if err != nil {
+		return nil, 0, err
+	}
+	txAttr := transaction.NewTxAttribute(transaction.Nonce, []byte(strconv.FormatInt(rand.Int63(), 10)))
+	txn.Attributes = []*transaction.TxAttribute{&txAttr}
+
+	ctx := contract.NewContractContext(txn)
+	if err := wallet.Sign(ctx); err != nil {
+		return nil, 0, err
+	}
+	txn.SetPrograms(ctx.GetPrograms())
+
+	var buffer bytes.Buffer
+	txn.Serialize(&buffer)
+	return txn, requiredFee(buffer.Len()), nil
+}
+
+// MakeConsolidateTransaction merges the single-sign coins of the asset worth
+// less than the dust value, all of them if it is zero, into one output to the
+// main account. The oldest coins are merged first, as many as fit under the
+// fee cap. It returns the transaction and the fee paid.
+func MakeConsolidateTransaction(wallet account.Client, assetID Uint256, dust Fixed64, feeCap Fixed64) (*transaction.Transaction, Fixed64, error) {
+	mainAccount, err := wallet.GetDefaultAccount()
+	if err != nil {
+		return nil, 0, err
+	}
+	if feeCap < requiredFee(0) {
+		return nil, 0, errors.New("the fee cap is below the minimum transaction fee")
+	}
+
+	var coins sortedCoins
+	for _, item := range availableCoins(wallet.GetCoins(), account.SingleSign, assetID, nil) {
+		if dust == 0 || item.coin.Output.Value < dust {
+			coins = append(coins, item)
+		}
+	}
+	sort.Sort(coins)
+	sort.Stable(byHeight(coins))
+
+	n := len(coins)
+	if n < 2 {
+		return nil, 0, errors.New("fewer than two coins to consolidate")
+	}
+	for n >= 2 {
+		fee := requiredFee(0)
+		txn, required, err := makeConsolidate(wallet, coins[:n], assetID, mainAccount.ProgramHash, fee)
+		if err != nil {
+			return nil, 0, err
+		}
+		if required <= fee {
+			return txn, fee, nil
+		}
+		if required <= feeCap {
+			// the fee doesn't change the size of the transaction
+			txn, _, err = makeConsolidate(wallet, coins[:n], assetID, mainAccount.ProgramHash, required)
+			if err != nil {
+				return nil, 0, err
+			}
+			return txn, required, nil
+		}
+		// drop the inputs the fee cap doesn't pay for
+		drop := 1
+		if rate := Fixed64(config.Parameters.PowConfiguration.MinTxFeePerKB); rate > 0 {
+			if d := int((required-feeCap)*1000/rate) / utxoInputSize; d > drop {
+				drop = d
+			}
+		}
+		n -= drop
+	}
+	return nil, 0, errors.New("no coins to consolidate under the fee cap")
+}
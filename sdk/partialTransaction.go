@@ -0,0 +1,510 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/common/serialization"
+	"DNA_POW/core/contract"
+	"DNA_POW/core/ledger"
+	"DNA_POW/core/signature"
+	"DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+)
+
+const PartialTransactionVersion byte = 2
+
+const (
+	// The most parameters of a program whose code is not a known contract
+	maxPartialParameters = 16
+	// The longest code or parameter of a program
+	maxPartialProgramBytes = 4096
+)
+
+// The leading bytes of a serialized partial transaction
+var partialTransactionMagic = []byte("DNAPTX")
+
+// PartialTransaction is a transaction passed between the signers, who don't
+// need the blockchain to sign it. Besides the unsigned transaction it carries
+// the transactions spent by its inputs, which the program hashes to sign for
+// are taken from, and the contract context with the signatures collected so
+// far.
+type PartialTransaction struct {
+	Transaction      *transaction.Transaction
+	PrevTransactions []*transaction.Transaction // The transactions spent by the UTXO inputs
+	References       []*transaction.TxOutput    // The output spent by each UTXO input
+	Context          *contract.ContractContext
+}
+
+// NewPartialTransaction creates the partial transaction of the unsigned
+// transaction and the transactions its inputs spend. The output spent by each
+// input is taken from the transaction of its hash, so the signers need not
+// trust the outputs they are given.
+func NewPartialTransaction(txn *transaction.Transaction, prevTxs []*transaction.Transaction) (*PartialTransaction, error) {
+	byHash := make(map[Uint256]*transaction.Transaction, len(prevTxs))
+	for _, prev := range prevTxs {
+		byHash[prev.Hash()] = prev
+	}
+	var spent []*transaction.Transaction
+	used := make(map[Uint256]bool)
+	references := make([]*transaction.TxOutput, 0, len(txn.UTXOInputs))
+	for _, input := range txn.UTXOInputs {
+		prev, ok := byHash[input.ReferTxID]
+		if !ok {
+			return nil, fmt.Errorf("the transaction %x spent by the input is missing", input.ReferTxID.ToArrayReverse())
+		}
+		if int(input.ReferTxOutputIndex) >= len(prev.Outputs) {
+			return nil, fmt.Errorf("the transaction %x has no output %d", input.ReferTxID.ToArrayReverse(), input.ReferTxOutputIndex)
+		}
+		references = append(references, prev.Outputs[input.ReferTxOutputIndex])
+		if !used[input.ReferTxID] {
+			used[input.ReferTxID] = true
+			spent = append(spent, prev)
+		}
+	}
+
+	uniq := make(map[Uint160]bool)
+	for _, output := range references {
+		uniq[output.ProgramHash] = true
+	}
+	for _, attribute := range txn.Attributes {
+		if attribute.Usage == transaction.Script {
+			hash, err := Uint160ParseFromBytes(attribute.Data)
+			if err != nil {
+				return nil, err
+			}
+			uniq[hash] = true
+		}
+	}
+	// the programs are in the order of transaction.GetProgramHashes
+	programHashes := make([]Uint160, 0, len(uniq))
+	for hash := range uniq {
+		programHashes = append(programHashes, hash)
+	}
+	sort.Slice(programHashes, func(i, j int) bool {
+		return programHashes[i].CompareTo(programHashes[j]) <= 0
+	})
+
+	return &PartialTransaction{
+		Transaction:      txn,
+		PrevTransactions: spent,
+		References:       references,
+		Context: &contract.ContractContext{
+			Data:            txn,
+			ProgramHashes:   programHashes,
+			Codes:           make([][]byte, len(programHashes)),
+			Parameters:      make([][][]byte, len(programHashes)),
+			MultiPubkeyPara: make([][]contract.PubkeyParameter, len(programHashes)),
+		},
+	}, nil
+}
+
+// MakePartialTransaction spends the coins of an address of the wallet, which
+// may be watch-only, to a partial transaction without signatures.
+func MakePartialTransaction(wallet account.Client, assetID Uint256, from string, fee string, batchOut ...BatchOut) (*PartialTransaction, error) {
+	txn, err := makeUnsignedTransfer(wallet, assetID, from, fee, batchOut...)
+	if err != nil {
+		return nil, err
+	}
+	var prevTxs []*transaction.Transaction
+	for _, input := range txn.UTXOInputs {
+		prev, _, err := ledger.DefaultLedger.Store.GetTransaction(input.ReferTxID)
+		if err != nil {
+			return nil, err
+		}
+		prevTxs = append(prevTxs, prev)
+	}
+	pt, err := NewPartialTransaction(txn, prevTxs)
+	if err != nil {
+		return nil, err
+	}
+	pt.setCodes(wallet)
+
+	return pt, nil
+}
+
+// walletContract returns the contract of the program hash known to the
+// wallet, which may be a watch-only one.
+func walletContract(wallet account.Client, programHash Uint160) *contract.Contract {
+	for _, c := range wallet.GetContracts() {
+		if c.ProgramHash == programHash {
+			return c
+		}
+	}
+	for _, w := range wallet.GetWatchOnly() {
+		if w.ProgramHash == programHash {
+			return w.Contract
+		}
+	}
+	return nil
+}
+
+// setCodes fills the codes of the programs the wallet knows the contracts of
+func (pt *PartialTransaction) setCodes(wallet account.Client) {
+	ctx := pt.Context
+	for i, hash := range ctx.ProgramHashes {
+		if ctx.Codes[i] != nil {
+			continue
+		}
+		if c := walletContract(wallet, hash); c != nil {
+			ctx.Codes[i] = c.Code
+		}
+	}
+}
+
+// signer returns the index of the public key of the multisig code the
+// signature is made by, -1 if none.
+func (pt *PartialTransaction) signer(pubKeys []*crypto.PubKey, sig []byte) int {
+	data := signature.GetHashData(pt.Transaction)
+	for i, pubKey := range pubKeys {
+		if crypto.Verify(*pubKey, data, sig) == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// multisigPubKeys returns the public keys of a multisig code
+func multisigPubKeys(code []byte) ([]*crypto.PubKey, error) {
+	i := 1
+	if code[0] == 1 {
+		i = 2
+	}
+	var pubKeys []*crypto.PubKey
+	for i+34 <= len(code) && code[i] == 33 {
+		pubKey, err := crypto.DecodePoint(code[i+1 : i+34])
+		if err != nil {
+			return nil, err
+		}
+		pubKeys = append(pubKeys, pubKey)
+		i += 34
+	}
+	return pubKeys, nil
+}
+
+// addSignature adds the signature to the program of the index unless its
+// signer has signed it already, and reports whether it is added.
+func (pt *PartialTransaction) addSignature(index int, c *contract.Contract, sig []byte) (bool, error) {
+	ctx := pt.Context
+	if ctx.Parameters[index] == nil {
+		ctx.Parameters[index] = make([][]byte, len(c.Parameters))
+	}
+	params := ctx.Parameters[index]
+	if c.IsStandard() {
+		if params[0] != nil {
+			return false, nil
+		}
+		pubKey, err := crypto.DecodePoint(c.Code[1:34])
+		if err != nil {
+			return false, err
+		}
+		if pt.signer([]*crypto.PubKey{pubKey}, sig) < 0 {
+			return false, errors.New("the signature is not made by the key of the contract")
+		}
+		params[0] = sig
+		return true, nil
+	}
+
+	pubKeys, err := multisigPubKeys(c.Code)
+	if err != nil {
+		return false, err
+	}
+	signer := pt.signer(pubKeys, sig)
+	if signer < 0 {
+		return false, errors.New("the signature is not made by a key of the contract")
+	}
+	for i, p := range params {
+		if p == nil {
+			params[i] = sig
+			return true, nil
+		}
+		if pt.signer(pubKeys, p) == signer {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// Sign adds the signatures of the accounts of the wallet and returns how many
+// are added. The wallet may fill the codes of the programs unknown so far.
+func (pt *PartialTransaction) Sign(wallet account.Client) (int, error) {
+	pt.setCodes(wallet)
+	ctx := pt.Context
+	count := 0
+	for i, hash := range ctx.ProgramHashes {
+		if ctx.Codes[i] == nil {
+			continue
+		}
+		c, err := account.NewScriptContract(ctx.Codes[i])
+		if err != nil {
+			return count, err
+		}
+		if c.ProgramHash != hash {
+			return count, fmt.Errorf("the code doesn't match the program hash %x", hash.ToArrayReverse())
+		}
+
+		signers := []Uint160{hash}
+		if c.IsMultiSigContract() {
+			signers = transaction.ParseMultisigTransactionCode(c.Code)
+		}
+		for _, signer := range signers {
+			acct := wallet.GetAccountByProgramHash(signer)
+			if acct == nil {
+				continue
+			}
+			sig, err := signature.SignBySigner(pt.Transaction, acct)
+			if err != nil {
+				return count, err
+			}
+			added, err := pt.addSignature(i, c, sig)
+			if err != nil {
+				return count, err
+			}
+			if added {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// Combine adds the codes and the signatures of the other partial transaction
+// of the same transaction.
+func (pt *PartialTransaction) Combine(other *PartialTransaction) error {
+	if pt.Transaction.Hash() != other.Transaction.Hash() {
+		return errors.New("the partial transactions are of different transactions")
+	}
+	ctx := pt.Context
+	for i, hash := range ctx.ProgramHashes {
+		j := other.Context.GetIndex(hash)
+		if j < 0 {
+			return errors.New("the partial transactions have different programs")
+		}
+		if ctx.Codes[i] == nil {
+			ctx.Codes[i] = other.Context.Codes[j]
+		}
+		if ctx.Codes[i] == nil {
+			continue
+		}
+		c, err := account.NewScriptContract(ctx.Codes[i])
+		if err != nil {
+			return err
+		}
+		for _, sig := range other.Context.Parameters[j] {
+			if sig == nil {
+				continue
+			}
+			if _, err := pt.addSignature(i, c, sig); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Missing returns the number of signatures the transaction still needs, the
+// programs without code count one.
+func (pt *PartialTransaction) Missing() int {
+	missing := 0
+	for i, params := range pt.Context.Parameters {
+		if pt.Context.Codes[i] == nil || params == nil {
+			missing++
+			if c, err := account.NewScriptContract(pt.Context.Codes[i]); err == nil {
+				missing += len(c.Parameters) - 1
+			}
+			continue
+		}
+		for _, p := range params {
+			if p == nil {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// Finalize returns the transaction with the programs of the signatures when
+// all are collected.
+func (pt *PartialTransaction) Finalize() (*transaction.Transaction, error) {
+	if missing := pt.Missing(); missing > 0 {
+		return nil, fmt.Errorf("%d signatures are missing", missing)
+	}
+	pt.Transaction.SetPrograms(pt.Context.GetPrograms())
+
+	return pt.Transaction, nil
+}
+
+func (pt *PartialTransaction) Serialize(w io.Writer) error {
+	w.Write(partialTransactionMagic)
+	w.Write([]byte{PartialTransactionVersion})
+	if err := pt.Transaction.SerializeUnsigned(w); err != nil {
+		return err
+	}
+	// the transactions spent are hashed without their programs
+	if err := serialization.WriteVarUint(w, uint64(len(pt.PrevTransactions))); err != nil {
+		return err
+	}
+	for _, prev := range pt.PrevTransactions {
+		if err := prev.SerializeUnsigned(w); err != nil {
+			return err
+		}
+	}
+
+	ctx := pt.Context
+	if err := serialization.WriteVarUint(w, uint64(len(ctx.ProgramHashes))); err != nil {
+		return err
+	}
+	for i, hash := range ctx.ProgramHashes {
+		if _, err := hash.Serialize(w); err != nil {
+			return err
+		}
+		if err := serialization.WriteVarBytes(w, ctx.Codes[i]); err != nil {
+			return err
+		}
+		if err := serialization.WriteVarUint(w, uint64(len(ctx.Parameters[i]))); err != nil {
+			return err
+		}
+		for _, p := range ctx.Parameters[i] {
+			// an empty parameter is a signature still missing
+			if err := serialization.WriteVarBytes(w, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (pt *PartialTransaction) Deserialize(r io.Reader) error {
+	header := make([]byte, len(partialTransactionMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:len(partialTransactionMagic)], partialTransactionMagic) {
+		return errors.New("not a partial transaction")
+	}
+	if version := header[len(header)-1]; version != PartialTransactionVersion {
+		return fmt.Errorf("unsupported partial transaction version %d", version)
+	}
+
+	txn := new(transaction.Transaction)
+	if err := txn.DeserializeUnsigned(r); err != nil {
+		return err
+	}
+	count, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	// each input spends one transaction at most
+	if count > uint64(len(txn.UTXOInputs)) {
+		return errors.New("more spent transactions than inputs")
+	}
+	prevTxs := make([]*transaction.Transaction, 0, count)
+	for i := uint64(0); i < count; i++ {
+		prev := new(transaction.Transaction)
+		if err := prev.DeserializeUnsigned(r); err != nil {
+			return err
+		}
+		prevTxs = append(prevTxs, prev)
+	}
+	p, err := NewPartialTransaction(txn, prevTxs)
+	if err != nil {
+		return err
+	}
+
+	ctx := p.Context
+	count, err = serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count != uint64(len(ctx.ProgramHashes)) {
+		return errors.New("the programs don't match the references")
+	}
+	for i := range ctx.ProgramHashes {
+		var hash Uint160
+		if err := hash.Deserialize(r); err != nil {
+			return err
+		}
+		if hash != ctx.ProgramHashes[i] {
+			return errors.New("the programs don't match the references")
+		}
+		code, err := readProgramBytes(r)
+		if err != nil {
+			return err
+		}
+		if len(code) > 0 {
+			ctx.Codes[i] = code
+		}
+		n, err := serialization.ReadVarUint(r, 0)
+		if err != nil {
+			return err
+		}
+		// a program has no more parameters than its contract
+		max := uint64(maxPartialParameters)
+		if c, err := account.NewScriptContract(ctx.Codes[i]); err == nil {
+			max = uint64(len(c.Parameters))
+		}
+		if n > max {
+			return errors.New("too many parameters of the program")
+		}
+		if n == 0 {
+			continue
+		}
+		ctx.Parameters[i] = make([][]byte, n)
+		for j := range ctx.Parameters[i] {
+			param, err := readProgramBytes(r)
+			if err != nil {
+				return err
+			}
+			if len(param) > 0 {
+				ctx.Parameters[i][j] = param
+			}
+		}
+	}
+
+	*pt = *p
+	return nil
+}
+
+// readProgramBytes reads the variable length bytes of a program, which are no
+// longer than maxPartialProgramBytes.
+func readProgramBytes(r io.Reader) ([]byte, error) {
+	n, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxPartialProgramBytes {
+		return nil, errors.New("program data too long")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ToHexString returns the serialized partial transaction in hex
+func (pt *PartialTransaction) ToHexString() (string, error) {
+	var buffer bytes.Buffer
+	if err := pt.Serialize(&buffer); err != nil {
+		return "", err
+	}
+	return BytesToHexString(buffer.Bytes()), nil
+}
+
+// PartialTransactionFromHexString parses a partial transaction in hex
+func PartialTransactionFromHexString(s string) (*PartialTransaction, error) {
+	data, err := HexStringToBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	pt := new(PartialTransaction)
+	if err := pt.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
@@ -0,0 +1,205 @@
+package sdk
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"DNA_POW/account"
+	. "DNA_POW/common"
+	"DNA_POW/common/log"
+	ct "DNA_POW/core/contract"
+	"DNA_POW/core/transaction"
+	"DNA_POW/crypto"
+)
+
+func TestMain(m *testing.M) {
+	log.Init()
+	os.Exit(m.Run())
+}
+
+func TestPartialTransaction(t *testing.T) {
+	crypto.SetAlg("P256R1")
+	dir, err := ioutil.TempDir("", "partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Three signers of a 2 of 3 multisig address, each with its own wallet
+	var wallets []*account.ClientImpl
+	var keys []*crypto.PubKey
+	for i := 0; i < 3; i++ {
+		wallet, err := account.Create(path.Join(dir, fmt.Sprintf("wallet%d.dat", i)), []byte("password"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		main, _ := wallet.GetDefaultAccount()
+		wallets = append(wallets, wallet)
+		keys = append(keys, main.PublicKey)
+	}
+	multisig, err := ct.CreateMultiSigContract(Uint160{}, 2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev, txn := testTransfer(multisig.ProgramHash)
+	pt, err := NewPartialTransaction(txn, []*transaction.Transaction{prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt.Context.Codes[0] = multisig.Code
+	if pt.Missing() != 2 {
+		t.Fatalf("unexpected %d missing signatures", pt.Missing())
+	}
+	data, err := pt.ToHexString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each signer signs its own copy without the blockchain
+	var signed []*PartialTransaction
+	for _, wallet := range wallets[:2] {
+		part, err := PartialTransactionFromHexString(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Transaction.Hash() != txn.Hash() || part.References[0].Value != 11 {
+			t.Fatal("partial transaction is not deserialized")
+		}
+		if count, err := part.Sign(wallet); err != nil || count != 1 {
+			t.Fatalf("%d signatures added: %v", count, err)
+		}
+		if count, _ := part.Sign(wallet); count != 0 {
+			t.Error("signed twice by the same key")
+		}
+		signed = append(signed, part)
+	}
+	if _, err := signed[0].Finalize(); err == nil {
+		t.Error("finalized without enough signatures")
+	}
+	if err := signed[0].Combine(signed[0]); err != nil || signed[0].Missing() != 1 {
+		t.Error("combined the signature of the same key twice")
+	}
+
+	if err := signed[0].Combine(signed[1]); err != nil {
+		t.Fatal(err)
+	}
+	final, err := signed[0].Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(final.Programs) != 1 || len(final.Programs[0].Code) == 0 || len(final.Programs[0].Parameter) == 0 {
+		t.Error("transaction is not finalized with the program")
+	}
+}
+
+// testTransfer returns a transaction paying 11 to the program hash and the
+// transaction spending it.
+func testTransfer(programHash Uint160) (*transaction.Transaction, *transaction.Transaction) {
+	prev, _ := transaction.NewTransferAssetTransaction(nil,
+		[]*transaction.TxOutput{{Value: 11, ProgramHash: programHash}},
+	)
+	txn, _ := transaction.NewTransferAssetTransaction(
+		[]*transaction.UTXOTxInput{{ReferTxID: prev.Hash(), ReferTxOutputIndex: 0}},
+		[]*transaction.TxOutput{{Value: 10, ProgramHash: programHash}},
+	)
+	return prev, txn
+}
+
+func TestPartialTransactionReferences(t *testing.T) {
+	prev, txn := testTransfer(Uint160{1, 2, 3})
+	if _, err := NewPartialTransaction(txn, nil); err == nil {
+		t.Error("created without the transaction spent")
+	}
+	other, _ := testTransfer(Uint160{4, 5, 6})
+	if _, err := NewPartialTransaction(txn, []*transaction.Transaction{other}); err == nil {
+		t.Error("created with another transaction than the one spent")
+	}
+
+	pt, err := NewPartialTransaction(txn, []*transaction.Transaction{prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The output of the transaction spent is raised on the way to the signer
+	prev.Outputs[0].Value = 1000
+	data, err := pt.ToHexString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PartialTransactionFromHexString(data); err == nil {
+		t.Error("deserialized with a tampered transaction spent")
+	}
+}
+
+func TestPartialTransactionBounds(t *testing.T) {
+	prev, txn := testTransfer(Uint160{1, 2, 3})
+	pt, err := NewPartialTransaction(txn, []*transaction.Transaction{prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := pt.Serialize(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	huge := []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0x40}
+
+	// The parameter count of the program, the last byte, is huge
+	if err := new(PartialTransaction).Deserialize(bytes.NewReader(append(data[:len(data)-1:len(data)-1], huge...))); err == nil {
+		t.Error("deserialized a huge parameter count")
+	}
+	// More parameters than the contract takes
+	pt.Context.Parameters[0] = make([][]byte, maxPartialParameters+1)
+	if data, err := pt.ToHexString(); err != nil {
+		t.Fatal(err)
+	} else if _, err := PartialTransactionFromHexString(data); err == nil {
+		t.Error("deserialized more parameters than a program takes")
+	}
+
+	// The count of the transactions spent is huge
+	buffer.Reset()
+	buffer.Write(partialTransactionMagic)
+	buffer.WriteByte(PartialTransactionVersion)
+	if err := txn.SerializeUnsigned(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	buffer.Write(huge)
+	if err := new(PartialTransaction).Deserialize(&buffer); err == nil {
+		t.Error("deserialized a huge count of transactions spent")
+	}
+}
+
+func TestPartialTransactionInvalidSignature(t *testing.T) {
+	crypto.SetAlg("P256R1")
+	_, pubKey, err := crypto.GenKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ct.CreateSignatureContract(&pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, txn := testTransfer(c.ProgramHash)
+	pt, err := NewPartialTransaction(txn, []*transaction.Transaction{prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt.Context.Codes[0] = c.Code
+	other, err := NewPartialTransaction(txn, []*transaction.Transaction{prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Context.Parameters[0] = [][]byte{bytes.Repeat([]byte{1}, 64)}
+
+	// The signature of a single signature program is verified as well
+	if err := pt.Combine(other); err == nil {
+		t.Error("combined a signature not made by the key of the contract")
+	}
+	if pt.Missing() != 1 {
+		t.Errorf("unexpected %d missing signatures", pt.Missing())
+	}
+}
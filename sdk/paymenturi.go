@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	. "DNA_POW/common"
+)
+
+// PaymentURIScheme is the scheme of the payment URIs, as in
+// dna:<address>?asset=<asset id>&amount=<value>&label=<label>&id=<request id>
+const PaymentURIScheme = "dna"
+
+// PaymentURI is a request to pay an address. The asset ID and the amount are
+// zero when the payer chooses them, the ID identifies the payment request of
+// the payee.
+type PaymentURI struct {
+	Address string
+	AssetID Uint256
+	Amount  Fixed64
+	Label   string
+	ID      string
+}
+
+// ParsePaymentURI parses a payment URI
+func ParsePaymentURI(s string) (*PaymentURI, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Scheme, PaymentURIScheme) {
+		return nil, errors.New("not a payment URI")
+	}
+	if _, err := ToScriptHash(u.Opaque); err != nil {
+		return nil, errors.New("invalid address in payment URI")
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, err
+	}
+	uri := &PaymentURI{
+		Address: u.Opaque,
+		Label:   query.Get("label"),
+		ID:      query.Get("id"),
+	}
+	if asset := query.Get("asset"); asset != "" {
+		b, err := HexStringToBytesReverse(asset)
+		if err != nil {
+			return nil, errors.New("invalid asset ID in payment URI")
+		}
+		if uri.AssetID, err = Uint256ParseFromBytes(b); err != nil {
+			return nil, errors.New("invalid asset ID in payment URI")
+		}
+	}
+	if amount := query.Get("amount"); amount != "" {
+		if uri.Amount, err = StringToFixed64(amount); err != nil || uri.Amount <= 0 {
+			return nil, errors.New("invalid amount in payment URI")
+		}
+	}
+	return uri, nil
+}
+
+// String returns the URI, without the fields not given
+func (uri *PaymentURI) String() string {
+	query := url.Values{}
+	if uri.AssetID != (Uint256{}) {
+		query.Set("asset", BytesToHexString(uri.AssetID.ToArrayReverse()))
+	}
+	if uri.Amount > 0 {
+		query.Set("amount", uri.Amount.String())
+	}
+	if uri.Label != "" {
+		query.Set("label", uri.Label)
+	}
+	if uri.ID != "" {
+		query.Set("id", uri.ID)
+	}
+	u := url.URL{Scheme: PaymentURIScheme, Opaque: uri.Address, RawQuery: query.Encode()}
+	return u.String()
+}
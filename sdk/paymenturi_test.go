@@ -0,0 +1,44 @@
+package sdk
+
+import (
+	"testing"
+
+	. "DNA_POW/common"
+)
+
+func TestPaymentURI(t *testing.T) {
+	programHash := Uint160{1, 2, 3}
+	address, _ := programHash.ToAddress()
+	uri := &PaymentURI{
+		Address: address,
+		AssetID: Uint256{4, 5, 6},
+		Amount:  150000000,
+		Label:   "invoice #7 & co",
+		ID:      "0123456789abcdef",
+	}
+	parsed, err := ParsePaymentURI(uri.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != *uri {
+		t.Fatalf("parsed %+v from %s", parsed, uri)
+	}
+
+	bare, err := ParsePaymentURI(PaymentURIScheme + ":" + address)
+	if err != nil || bare.Address != address || bare.Amount != 0 || bare.AssetID != (Uint256{}) {
+		t.Errorf("unexpected payment URI without fields %+v: %v", bare, err)
+	}
+	for _, s := range []string{
+		"",
+		address,
+		"bitcoin:" + address,
+		PaymentURIScheme + ":notanaddress",
+		PaymentURIScheme + ":" + address + "?amount=-1",
+		PaymentURIScheme + ":" + address + "?amount=abc",
+		PaymentURIScheme + ":" + address + "?asset=zz",
+	} {
+		if _, err := ParsePaymentURI(s); err == nil {
+			t.Errorf("invalid payment URI %q parsed", s)
+		}
+	}
+}
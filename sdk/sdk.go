@@ -4,13 +4,11 @@ import (
 	"DNA_POW/account"
 	. "DNA_POW/common"
 	"DNA_POW/core/contract"
-	"DNA_POW/core/ledger"
 	"DNA_POW/core/signature"
 	"DNA_POW/core/transaction"
 	"errors"
 	"fmt"
 	"math/rand"
-	"sort"
 	"strconv"
 )
 
@@ -37,24 +35,13 @@ func (sc sortedCoins) Less(i, j int) bool {
 	}
 }
 
-func sortAvailableCoinsByValue(coins map[*transaction.UTXOTxInput]*account.Coin, addrtype account.AddressType) sortedCoins {
-	var coinList sortedCoins
-	for in, c := range coins {
-		if c.Height <= ledger.DefaultLedger.Blockchain.GetBestHeight() {
-			if c.AddressType == addrtype {
-				tmp := &sortedCoinsItem{
-					input: in,
-					coin:  c,
-				}
-				coinList = append(coinList, tmp)
-			}
-		}
-	}
-	sort.Sort(coinList)
-	return coinList
+func MakeTransferTransaction(wallet account.Client, assetID Uint256, fee string, batchOut ...BatchOut) (*transaction.Transaction, error) {
+	return MakeTransferTransactionWithCoinControl(wallet, assetID, fee, nil, batchOut...)
 }
 
-func MakeTransferTransaction(wallet account.Client, assetID Uint256, fee string, batchOut ...BatchOut) (*transaction.Transaction, error) {
+// MakeTransferTransactionWithCoinControl is MakeTransferTransaction spending
+// the coins chosen by the coin control.
+func MakeTransferTransactionWithCoinControl(wallet account.Client, assetID Uint256, fee string, control *CoinControl, batchOut ...BatchOut) (*transaction.Transaction, error) {
 	// get main account which is used to receive changes
 	mainAccount, err := wallet.GetDefaultAccount()
 	if err != nil {
@@ -63,7 +50,6 @@ func MakeTransferTransaction(wallet account.Client, assetID Uint256, fee string,
 
 	// construct transaction outputs
 	var expected Fixed64
-	input := []*transaction.UTXOTxInput{}
 	output := []*transaction.TxOutput{}
 	txnfee, err := StringToFixed64(fee)
 	if err != nil || txnfee <= 0 {
@@ -89,31 +75,17 @@ func MakeTransferTransaction(wallet account.Client, assetID Uint256, fee string,
 	}
 
 	// construct transaction inputs and changes
-	coins := wallet.GetCoins()
-	sorted := sortAvailableCoinsByValue(coins, account.SingleSign)
-	for _, coinItem := range sorted {
-		if coinItem.coin.Output.AssetID == assetID {
-			input = append(input, coinItem.input)
-			if coinItem.coin.Output.Value > expected {
-				changes := &transaction.TxOutput{
-					AssetID:     assetID,
-					Value:       coinItem.coin.Output.Value - expected,
-					ProgramHash: mainAccount.ProgramHash,
-				}
-				// if any, the changes output of transaction will be the last one
-				output = append(output, changes)
-				expected = 0
-				break
-			} else if coinItem.coin.Output.Value == expected {
-				expected = 0
-				break
-			} else if coinItem.coin.Output.Value < expected {
-				expected = expected - coinItem.coin.Output.Value
-			}
-		}
+	input, _, changes, err := spendCoins(wallet, control, account.SingleSign, assetID, nil, expected)
+	if err != nil {
+		return nil, err
 	}
-	if expected > 0 {
-		return nil, errors.New("available token is not enough")
+	if changes > 0 {
+		// if any, the changes output of transaction will be the last one
+		output = append(output, &transaction.TxOutput{
+			AssetID:     assetID,
+			Value:       changes,
+			ProgramHash: mainAccount.ProgramHash,
+		})
 	}
 
 	// construct transaction
@@ -146,7 +118,6 @@ func MakeMultisigTransferTransaction(wallet account.Client, assetID Uint256, fro
 	}
 
 	var expected Fixed64
-	input := []*transaction.UTXOTxInput{}
 	output := []*transaction.TxOutput{}
 	txnfee, err := StringToFixed64(fee)
 	if err != nil || txnfee <= 0 {
@@ -174,33 +145,17 @@ func MakeMultisigTransferTransaction(wallet account.Client, assetID Uint256, fro
 	}
 	fmt.Printf("expected = %v\n", expected)
 	// construct transaction inputs and changes
-	coins := wallet.GetCoins()
-	sorted := sortAvailableCoinsByValue(coins, account.MultiSign)
-	for _, coinItem := range sorted {
-		if coinItem.coin.Output.AssetID == assetID && coinItem.coin.Output.ProgramHash == spendAddress {
-			input = append(input, coinItem.input)
-			fmt.Printf("coinItem.coin.Output.Value = %v ProgramHash = %x\n", coinItem.coin.Output.Value, spendAddress.ToArrayReverse())
-			if coinItem.coin.Output.Value > expected {
-				changes := &transaction.TxOutput{
-					AssetID:     assetID,
-					Value:       coinItem.coin.Output.Value - expected,
-					ProgramHash: spendAddress,
-				}
-				// if any, the changes output of transaction will be the last one
-				output = append(output, changes)
-				expected = 0
-				break
-			} else if coinItem.coin.Output.Value == expected {
-				expected = 0
-				break
-			} else if coinItem.coin.Output.Value < expected {
-				expected = expected - coinItem.coin.Output.Value
-				fmt.Printf("expected - coinItem.coin.Output.Value = %v\n", expected)
-			}
-		}
+	input, _, changes, err := spendCoins(wallet, nil, account.MultiSign, assetID, &spendAddress, expected)
+	if err != nil {
+		return nil, err
 	}
-	if expected > 0 {
-		return nil, errors.New("available token is not enough")
+	if changes > 0 {
+		// if any, the changes output of transaction will be the last one
+		output = append(output, &transaction.TxOutput{
+			AssetID:     assetID,
+			Value:       changes,
+			ProgramHash: spendAddress,
+		})
 	}
 
 	// construct transaction
@@ -226,6 +181,101 @@ func MakeMultisigTransferTransaction(wallet account.Client, assetID Uint256, fro
 	return txn, nil
 }
 
+// MakeUnsignedTransferTransaction spends the coins of a wallet address, a
+// watch-only one usually, and returns the transaction without programs to be
+// signed offline by the holder of the keys. The changes go back to the address.
+func MakeUnsignedTransferTransaction(wallet account.Client, assetID Uint256, from string, fee string, batchOut ...BatchOut) (*transaction.Transaction, error) {
+	txn, err := makeUnsignedTransfer(wallet, assetID, from, fee, batchOut...)
+	return txn, err
+}
+
+// walletAddressType returns the type of the coins of the wallet address
+func walletAddressType(wallet account.Client, programHash Uint160) (account.AddressType, error) {
+	for _, w := range wallet.GetWatchOnly() {
+		if w.ProgramHash == programHash {
+			return account.WatchOnly, nil
+		}
+	}
+	for _, c := range wallet.GetContracts() {
+		if c.ProgramHash == programHash {
+			if c.IsMultiSigContract() {
+				return account.MultiSign, nil
+			}
+			return account.SingleSign, nil
+		}
+	}
+	return 0, errors.New("sender address is not in the wallet")
+}
+
+// makeUnsignedTransfer spends the coins of the wallet address to the
+// transaction without programs. The changes go back to the address.
+func makeUnsignedTransfer(wallet account.Client, assetID Uint256, from string, fee string, batchOut ...BatchOut) (*transaction.Transaction, error) {
+	outputNum := len(batchOut)
+	if outputNum == 0 {
+		return nil, errors.New("nil outputs")
+	}
+
+	spendAddress, err := ToScriptHash(from)
+	if err != nil {
+		return nil, errors.New("invalid sender address")
+	}
+	addressType, err := walletAddressType(wallet, spendAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var expected Fixed64
+	output := []*transaction.TxOutput{}
+	txnfee, err := StringToFixed64(fee)
+	if err != nil || txnfee <= 0 {
+		return nil, errors.New("invalid transation fee")
+	}
+	expected += txnfee
+	// construct transaction outputs
+	for _, o := range batchOut {
+		outputValue, err := StringToFixed64(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		expected += outputValue
+		address, err := ToScriptHash(o.Address)
+		if err != nil {
+			return nil, errors.New("invalid receiver address")
+		}
+		tmp := &transaction.TxOutput{
+			AssetID:     assetID,
+			Value:       outputValue,
+			ProgramHash: address,
+		}
+		output = append(output, tmp)
+	}
+
+	// construct transaction inputs and changes
+	input, _, changes, err := spendCoins(wallet, nil, addressType, assetID, &spendAddress, expected)
+	if err != nil {
+		return nil, err
+	}
+	if changes > 0 {
+		// if any, the changes output of transaction will be the last one
+		output = append(output, &transaction.TxOutput{
+			AssetID:     assetID,
+			Value:       changes,
+			ProgramHash: spendAddress,
+		})
+	}
+
+	// construct transaction
+	txn, err := transaction.NewTransferAssetTransaction(input, output)
+	if err != nil {
+		return nil, err
+	}
+	txAttr := transaction.NewTxAttribute(transaction.Nonce, []byte(strconv.FormatInt(rand.Int63(), 10)))
+	txn.Attributes = make([]*transaction.TxAttribute, 0)
+	txn.Attributes = append(txn.Attributes, &txAttr)
+
+	return txn, nil
+}
+
 func signTransaction(signer *account.Account, tx *transaction.Transaction) error {
 	signature, err := signature.SignBySigner(tx, signer)
 	if err != nil {